@@ -0,0 +1,84 @@
+package deej
+
+import (
+	"context"
+	"time"
+
+	"github.com/mitchellh/go-ps"
+	"go.uber.org/zap"
+)
+
+// ActionExecutor performs everything action steps do outside deej: launching apps, simulating input,
+// beeping and waiting, but also looking up, killing and focusing processes and windows. ButtonHandler
+// only sequences steps and calls through this, so every step type can be exercised with a fake executor
+// instead of the OS
+type ActionExecutor interface {
+	Execute(ctx context.Context, step *ActionStep, buttonID int, actionType string, key string, bh *ButtonHandler) error
+	Keystroke(ctx context.Context, step *ActionStep, logger *zap.SugaredLogger) error
+	Typing(ctx context.Context, step *ActionStep, logger *zap.SugaredLogger) error
+	Beep(ctx context.Context, step *ActionStep, logger *zap.SugaredLogger) error
+	Sleep(ctx context.Context, duration time.Duration) error
+
+	// FindProcesses returns the running processes with the given name, see findRunningProcesses
+	FindProcesses(name string) ([]ps.Process, error)
+	KillProcess(pid int, force bool, logger *zap.SugaredLogger) error
+
+	// Window focus, for if_running: focus, keystroke/typing targets and restore_focus
+	FocusProcessWindow(pid int, logger *zap.SugaredLogger) bool
+	FocusTargetWindow(pids []int, title string, logger *zap.SugaredLogger) bool
+	ForegroundWindow() (uintptr, bool)
+	RestoreForegroundWindow(window uintptr, logger *zap.SugaredLogger) bool
+}
+
+// platformActionExecutor is the default ActionExecutor, backed by the platform implementations
+type platformActionExecutor struct{}
+
+func (platformActionExecutor) Execute(ctx context.Context, step *ActionStep, buttonID int, actionType string, key string, bh *ButtonHandler) error {
+	return executeActionPlatform(ctx, step, buttonID, actionType, key, bh)
+}
+
+func (platformActionExecutor) Keystroke(ctx context.Context, step *ActionStep, logger *zap.SugaredLogger) error {
+	return keystrokeActionImpl(ctx, step, logger)
+}
+
+func (platformActionExecutor) Typing(ctx context.Context, step *ActionStep, logger *zap.SugaredLogger) error {
+	return typingActionImpl(ctx, step, logger)
+}
+
+func (platformActionExecutor) Beep(ctx context.Context, step *ActionStep, logger *zap.SugaredLogger) error {
+	return beepActionImpl(ctx, step, logger)
+}
+
+// Sleep waits for duration, returning context.Canceled if the action is cancelled first
+func (platformActionExecutor) Sleep(ctx context.Context, duration time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return context.Canceled
+	case <-time.After(duration):
+		return nil
+	}
+}
+
+func (platformActionExecutor) FindProcesses(name string) ([]ps.Process, error) {
+	return findRunningProcesses(name)
+}
+
+func (platformActionExecutor) KillProcess(pid int, force bool, logger *zap.SugaredLogger) error {
+	return killProcessImpl(pid, force, logger)
+}
+
+func (platformActionExecutor) FocusProcessWindow(pid int, logger *zap.SugaredLogger) bool {
+	return focusProcessWindowImpl(pid, logger)
+}
+
+func (platformActionExecutor) FocusTargetWindow(pids []int, title string, logger *zap.SugaredLogger) bool {
+	return focusTargetWindowImpl(pids, title, logger)
+}
+
+func (platformActionExecutor) ForegroundWindow() (uintptr, bool) {
+	return foregroundWindowImpl()
+}
+
+func (platformActionExecutor) RestoreForegroundWindow(window uintptr, logger *zap.SugaredLogger) bool {
+	return restoreForegroundWindowImpl(window, logger)
+}
@@ -0,0 +1,465 @@
+package deej
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/go-ps"
+	"go.uber.org/zap"
+)
+
+// fakeProcess is a ps.Process that only has a pid and a name
+type fakeProcess struct {
+	pid  int
+	name string
+}
+
+func (p fakeProcess) Pid() int           { return p.pid }
+func (p fakeProcess) PPid() int          { return 0 }
+func (p fakeProcess) Executable() string { return p.name }
+
+// fakeExecutor records what steps asked the OS to do instead of doing it
+type fakeExecutor struct {
+	processes  []ps.Process
+	listings   [][]ps.Process // if set, what successive process lookups see instead (the last one repeats)
+	lookups    int
+	focusable  map[string]bool // window titles or process names FocusTargetWindow succeeds for
+	focusPIDs  []int           // the pids FocusTargetWindow was last given
+	foreground uintptr         // the window ForegroundWindow reports, 0 for none
+	calls      []string
+}
+
+func (f *fakeExecutor) record(call string) { f.calls = append(f.calls, call) }
+
+func (f *fakeExecutor) Execute(ctx context.Context, step *ActionStep, buttonID int, actionType string, key string, bh *ButtonHandler) error {
+	f.record("execute " + step.App)
+	return nil
+}
+
+func (f *fakeExecutor) Keystroke(ctx context.Context, step *ActionStep, logger *zap.SugaredLogger) error {
+	f.record("keystroke " + step.Keys)
+	return nil
+}
+
+func (f *fakeExecutor) Typing(ctx context.Context, step *ActionStep, logger *zap.SugaredLogger) error {
+	f.record("typing " + step.Text)
+	return nil
+}
+
+func (f *fakeExecutor) Beep(ctx context.Context, step *ActionStep, logger *zap.SugaredLogger) error {
+	f.record("beep")
+	return nil
+}
+
+func (f *fakeExecutor) Sleep(ctx context.Context, duration time.Duration) error {
+	f.record("sleep " + duration.String())
+	return nil
+}
+
+func (f *fakeExecutor) FindProcesses(name string) ([]ps.Process, error) {
+	f.lookups++
+
+	processes := f.processes
+	if len(f.listings) > 0 {
+		processes = f.listings[0]
+		if len(f.listings) > 1 {
+			f.listings = f.listings[1:]
+		}
+	}
+
+	matches := []ps.Process{}
+	for _, process := range processes {
+		if strings.EqualFold(process.Executable(), name) {
+			matches = append(matches, process)
+		}
+	}
+	return matches, nil
+}
+
+func (f *fakeExecutor) KillProcess(pid int, force bool, logger *zap.SugaredLogger) error {
+	f.record("kill " + strconv.Itoa(pid))
+	return nil
+}
+
+func (f *fakeExecutor) FocusProcessWindow(pid int, logger *zap.SugaredLogger) bool {
+	f.record("focus pid " + strconv.Itoa(pid))
+	return true
+}
+
+func (f *fakeExecutor) FocusTargetWindow(pids []int, title string, logger *zap.SugaredLogger) bool {
+	f.focusPIDs = pids
+	f.record("focus " + title)
+	return f.focusable[title]
+}
+
+func (f *fakeExecutor) ForegroundWindow() (uintptr, bool) {
+	return f.foreground, f.foreground != 0
+}
+
+func (f *fakeExecutor) RestoreForegroundWindow(window uintptr, logger *zap.SugaredLogger) bool {
+	f.record("restore " + strconv.Itoa(int(window)))
+	return true
+}
+
+func TestExecuteActionThroughExecutor(t *testing.T) {
+	tests := []struct {
+		name      string
+		processes []ps.Process
+		focusable map[string]bool
+		steps     []ActionStep
+		wantCalls []string
+		wantErr   bool
+	}{
+		{
+			name: "steps are dispatched in order",
+			steps: []ActionStep{
+				{Type: ActionTypeExecute, App: "notepad.exe"},
+				{Type: ActionTypeDelay, Ms: 250},
+				{Type: ActionTypeKeystroke, Keys: "ctrl+n"},
+				{Type: ActionTypeTyping, Text: "hello"},
+				{Type: ActionTypeBeep},
+			},
+			wantCalls: []string{"execute notepad.exe", "sleep 250ms", "keystroke ctrl+n", "typing hello", "beep"},
+		},
+		{
+			name:      "steps after a failed one don't run",
+			steps:     []ActionStep{{Type: ActionTypeBeep}, {Type: ActionTypeDelay}, {Type: ActionTypeBeep}},
+			wantCalls: []string{"beep"},
+			wantErr:   true,
+		},
+		{
+			name:      "kill every match but deej itself",
+			processes: []ps.Process{fakeProcess{100, "game.exe"}, fakeProcess{101, "game.exe"}, fakeProcess{os.Getpid(), "game.exe"}, fakeProcess{102, "other.exe"}},
+			steps:     []ActionStep{{Type: ActionTypeKill, ProcessName: "game.exe"}},
+			wantCalls: []string{"kill 100", "kill 101"},
+		},
+		{
+			name:      "protected process is refused",
+			processes: []ps.Process{fakeProcess{4, "explorer.exe"}},
+			steps:     []ActionStep{{Type: ActionTypeKill, ProcessName: "explorer"}},
+			wantErr:   true,
+		},
+		{
+			name:      "keystroke goes to its target",
+			focusable: map[string]bool{"notepad.exe": true},
+			steps:     []ActionStep{{Type: ActionTypeKeystroke, Keys: "ctrl+s", Target: "notepad.exe"}},
+			wantCalls: []string{"focus notepad.exe", "keystroke ctrl+s"},
+		},
+		{
+			name:      "no keystroke when the target can't be focused",
+			steps:     []ActionStep{{Type: ActionTypeKeystroke, Keys: "ctrl+s", Target: "notepad.exe"}},
+			wantCalls: []string{"focus notepad.exe"},
+			wantErr:   true,
+		},
+		{
+			name:      "if_running focus doesn't launch again",
+			processes: []ps.Process{fakeProcess{200, "spotify.exe"}},
+			steps:     []ActionStep{{Type: ActionTypeExecute, App: "spotify.exe", IfRunning: IfRunningFocus}},
+			wantCalls: []string{"focus pid 200"},
+		},
+		{
+			name:      "wait_process sees a running process",
+			processes: []ps.Process{fakeProcess{300, "obs64.exe"}},
+			steps:     []ActionStep{{Type: ActionTypeWaitProcess, ProcessName: "obs64.exe", Mode: WaitProcessModeStart}, {Type: ActionTypeBeep}},
+			wantCalls: []string{"beep"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &fakeExecutor{processes: tt.processes, focusable: tt.focusable}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+
+			err := bh.executeAction(context.Background(), tt.steps, 1, ButtonActionSingle, "1_single", []string{"1_single"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error: got %v, want error %v", err, tt.wantErr)
+			}
+
+			if strings.Join(executor.calls, "; ") != strings.Join(tt.wantCalls, "; ") {
+				t.Errorf("calls: got %q, want %q", executor.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWaitProcess(t *testing.T) {
+	obs := []ps.Process{fakeProcess{300, "obs64.exe"}}
+
+	tests := []struct {
+		name        string
+		mode        string
+		timeoutMs   int
+		listings    [][]ps.Process
+		wantLookups int
+		wantErr     bool
+	}{
+		{"start, already running", WaitProcessModeStart, 0, [][]ps.Process{obs}, 1, false},
+		{"start, appears later", WaitProcessModeStart, 0, [][]ps.Process{nil, nil, obs}, 3, false},
+		{"exit, already gone", WaitProcessModeExit, 0, [][]ps.Process{nil}, 1, false},
+		{"exit, quits later", WaitProcessModeExit, 0, [][]ps.Process{obs, nil}, 2, false},
+		{"start, times out", WaitProcessModeStart, 300, [][]ps.Process{nil}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &fakeExecutor{listings: tt.listings}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+
+			step := ActionStep{Type: ActionTypeWaitProcess, ProcessName: "obs64.exe", Mode: tt.mode, Timeout: tt.timeoutMs}
+			err := bh.executeWaitProcess(context.Background(), &step)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error: got %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantLookups > 0 && executor.lookups != tt.wantLookups {
+				t.Errorf("lookups: got %d, want %d", executor.lookups, tt.wantLookups)
+			}
+		})
+	}
+
+	// cancelling the action stops the wait
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bh := newTestButtonHandler()
+	step := ActionStep{Type: ActionTypeWaitProcess, ProcessName: "obs64.exe", Mode: WaitProcessModeStart}
+	if err := bh.executeWaitProcess(ctx, &step); err != context.Canceled {
+		t.Errorf("cancelled wait: got %v, want context.Canceled", err)
+	}
+}
+
+func TestExecuteIfRunning(t *testing.T) {
+	spotify := []ps.Process{fakeProcess{200, "spotify.exe"}}
+
+	tests := []struct {
+		name      string
+		ifRunning string
+		processes []ps.Process
+		wantCalls []string
+	}{
+		{"launch by default", "", spotify, []string{"execute spotify.exe"}},
+		{"launch when running", IfRunningLaunch, spotify, []string{"execute spotify.exe"}},
+		{"focus when running", IfRunningFocus, spotify, []string{"focus pid 200"}},
+		{"focus launches when not running", IfRunningFocus, nil, []string{"execute spotify.exe"}},
+		{"skip when running", IfRunningSkip, spotify, nil},
+		{"skip launches when not running", IfRunningSkip, nil, []string{"execute spotify.exe"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &fakeExecutor{processes: tt.processes}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+
+			steps := []ActionStep{{Type: ActionTypeExecute, App: "spotify.exe", IfRunning: tt.ifRunning}}
+			if err := bh.executeAction(context.Background(), steps, 1, ButtonActionSingle, "1_single", []string{"1_single"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if strings.Join(executor.calls, "; ") != strings.Join(tt.wantCalls, "; ") {
+				t.Errorf("calls: got %q, want %q", executor.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestFocusStepTarget(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    string
+		processes []ps.Process
+		focusable map[string]bool
+		wantPIDs  []int
+		wantErr   bool
+	}{
+		{"no target", "", nil, nil, nil, false},
+		{"running process", "notepad.exe", []ps.Process{fakeProcess{10, "notepad.exe"}, fakeProcess{11, "notepad.exe"}}, map[string]bool{"notepad.exe": true}, []int{10, 11}, false},
+		{"window title only", "Untitled - Notepad", nil, map[string]bool{"Untitled - Notepad": true}, []int{}, false},
+		{"target not found", "notepad.exe", nil, nil, []int{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &fakeExecutor{processes: tt.processes, focusable: tt.focusable}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+
+			step := ActionStep{Type: ActionTypeTyping, Text: "hello", Target: tt.target}
+			err := bh.focusStepTarget(&step)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error: got %v, want error %v", err, tt.wantErr)
+			}
+
+			if fmt.Sprint(executor.focusPIDs) != fmt.Sprint(tt.wantPIDs) {
+				t.Errorf("focused pids: got %v, want %v", executor.focusPIDs, tt.wantPIDs)
+			}
+		})
+	}
+}
+
+func TestFindRunningProcesses(t *testing.T) {
+	previous := processLister
+	defer func() { processLister = previous }()
+
+	processLister = func() ([]ps.Process, error) {
+		return []ps.Process{
+			fakeProcess{1, "Notepad.exe"},
+			fakeProcess{2, "notepad"},
+			fakeProcess{3, "notepad++.exe"},
+			fakeProcess{4, "obs64.exe"},
+		}, nil
+	}
+
+	tests := []struct {
+		name string
+		want []int
+	}{
+		{"notepad.exe", []int{1, 2}},
+		{"NOTEPAD", []int{1, 2}},
+		{"notepad++", []int{3}},
+		{"obs", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processes, err := findRunningProcesses(tt.name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := []int{}
+			for _, process := range processes {
+				got = append(got, process.Pid())
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got pids %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteSettle(t *testing.T) {
+	tests := []struct {
+		name      string
+		step      ActionStep
+		processes []ps.Process
+		wantCalls []string
+	}{
+		{"no settle", ActionStep{Type: ActionTypeExecute, App: "game.exe"}, nil, []string{"execute game.exe"}},
+		{"settles after launch", ActionStep{Type: ActionTypeExecute, App: "game.exe", SettleMs: 1500}, nil, []string{"execute game.exe", "sleep 1.5s"}},
+		{"no settle when nothing was launched", ActionStep{Type: ActionTypeExecute, App: "game.exe", SettleMs: 1500, IfRunning: IfRunningSkip},
+			[]ps.Process{fakeProcess{50, "game.exe"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &fakeExecutor{processes: tt.processes}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+
+			if err := bh.executeAction(context.Background(), []ActionStep{tt.step}, 1, ButtonActionSingle, "1_single", []string{"1_single"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if strings.Join(executor.calls, "; ") != strings.Join(tt.wantCalls, "; ") {
+				t.Errorf("calls: got %q, want %q", executor.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDisabledActions(t *testing.T) {
+	steps := []ActionStep{
+		{Type: ActionTypeExecute, App: "cmd.exe"},
+		{Type: ActionTypeKeystroke, Keys: "ctrl+s"},
+		{Type: ActionTypeExecute, App: "calc.exe"},
+		{Type: ActionTypeBeep},
+	}
+
+	tests := []struct {
+		name         string
+		yaml         string
+		wantCalls    []string
+		wantNotified int
+	}{
+		{"nothing disabled", "", []string{"execute cmd.exe", "keystroke ctrl+s", "execute calc.exe", "beep"}, 0},
+		{"execute disabled", "disabled_actions: [execute]\n", []string{"keystroke ctrl+s", "beep"}, 1},
+		{"matched case-insensitively", "disabled_actions: [' Keystroke ', Beep]\n", []string{"execute cmd.exe", "execute calc.exe"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &fakeExecutor{}
+			notifier := &recordingNotifier{}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+			bh.notifier = notifier
+			bh.config = newTestConfig(t, tt.yaml).ButtonsMapping.ToButtonsMapping()
+
+			if err := bh.executeAction(context.Background(), steps, 1, ButtonActionSingle, "1_single", []string{"1_single"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if strings.Join(executor.calls, "; ") != strings.Join(tt.wantCalls, "; ") {
+				t.Errorf("calls: got %q, want %q", executor.calls, tt.wantCalls)
+			}
+			// one notification per run, however many steps were skipped
+			if len(notifier.Titles()) != tt.wantNotified {
+				t.Errorf("got %d notifications, want %d", len(notifier.Titles()), tt.wantNotified)
+			}
+		})
+	}
+}
+
+func TestTypingTextFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return path
+	}
+
+	signature := writeFile("signature.txt", "Best regards,\nJane")
+	windowsPath := writeFile("path.txt", `C:\temp\new`)
+	tooLarge := writeFile("large.txt", strings.Repeat("x", typingTextFileMaxBytes+1))
+
+	tests := []struct {
+		name      string
+		step      ActionStep
+		wantCalls []string
+		wantErr   bool
+	}{
+		{"file text is typed", ActionStep{Type: ActionTypeTyping, TextFile: signature}, []string{"typing Best regards,\nJane"}, false},
+		{"backslashes are protected", ActionStep{Type: ActionTypeTyping, TextFile: windowsPath}, []string{`typing C:\\temp\\new`}, false},
+		{"escapes processed when asked", ActionStep{Type: ActionTypeTyping, TextFile: windowsPath, Escapes: true}, []string{`typing C:\temp\new`}, false},
+		{"file replaces inline text", ActionStep{Type: ActionTypeTyping, Text: "inline", TextFile: signature}, []string{"typing Best regards,\nJane"}, false},
+		{"missing file", ActionStep{Type: ActionTypeTyping, TextFile: filepath.Join(dir, "missing.txt")}, nil, true},
+		{"file over the size cap", ActionStep{Type: ActionTypeTyping, TextFile: tooLarge}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &fakeExecutor{}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+
+			err := bh.executeAction(context.Background(), []ActionStep{tt.step}, 1, ButtonActionSingle, "1_single", []string{"1_single"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error: got %v, want error %v", err, tt.wantErr)
+			}
+
+			if strings.Join(executor.calls, "; ") != strings.Join(tt.wantCalls, "; ") {
+				t.Errorf("calls: got %q, want %q", executor.calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -4,17 +4,51 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/mitchellh/go-ps"
 	"go.uber.org/zap"
 )
 
 const (
 	defaultWaitTimeout = 30 * time.Second
+
+	// How often wait_process checks the process list
+	waitProcessPollInterval = 250 * time.Millisecond
+
+	// Largest text_file a typing step will read - typing is slow, so anything bigger is a mistake
+	typingTextFileMaxBytes = 64 * 1024
 )
 
+// processLister returns the running processes. It's a variable so the process source can be replaced
+var processLister = ps.Processes
+
+// protectedProcesses can't be targeted by kill steps - killing them crashes or logs out the session.
+// Names are lowercase, without .exe
+var protectedProcesses = map[string]bool{
+	// Windows
+	"system": true, "smss": true, "csrss": true, "wininit": true, "winlogon": true, "services": true,
+	"lsass": true, "svchost": true, "dwm": true, "explorer": true, "fontdrvhost": true,
+	// Linux
+	"init": true, "systemd": true, "dbus-daemon": true, "xorg": true, "xwayland": true,
+	"gnome-shell": true, "kwin_x11": true, "kwin_wayland": true, "pipewire": true, "pulseaudio": true,
+	"wireplumber": true, "sshd": true,
+	// ourselves
+	"deej": true,
+}
+
+// protectedProcess reports whether name is a process kill steps refuse to terminate
+func protectedProcess(name string) bool {
+	return protectedProcesses[strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".exe")]
+}
+
 // ActionError represents an error that occurred during action execution
 type ActionError struct {
 	Type    string
@@ -55,6 +89,19 @@ type ButtonHandler struct {
 	trackedProcesses map[string]*exec.Cmd   // Linux: tracked exec.Cmd processes (protected by processMutex)
 	trackedHandles   map[string]interface{} // Windows: tracked syscall.Handle (stored as interface{} for build tag compatibility, protected by processMutex)
 	processMutex     sync.RWMutex           // Protects trackedProcesses and trackedHandles
+	actionsWG        sync.WaitGroup         // Tracks running action goroutines so shutdown can wait for them
+	shuttingDown     atomic.Bool            // Set by Shutdown; no new actions are started afterwards (written under lifecycleMutex)
+	lifecycleMutex   sync.Mutex             // Orders actionsWG.Add in startAction against Shutdown setting shuttingDown before it waits
+	templateValues   actionTemplateValues   // Runtime values for {{...}} templates in execute steps
+	armedActions     map[string]time.Time   // First press of confirm actions, keyed like runningActions (protected by armedMutex)
+	armedMutex       sync.Mutex             // Protects armedActions
+	connectActionRan atomic.Bool            // Set once on_connect has run, unless it runs on every connect
+	executor         ActionExecutor         // Performs the platform side of steps (platformActionExecutor outside of tests)
+	writeToDevice    func([]byte) error     // Sends long press progress back to the device
+	holds            map[int]*buttonHold    // Long presses being timed, keyed by button (protected by holdsMutex)
+	holdReporting    map[int]bool           // Buttons that have sent down/up events (protected by holdsMutex)
+	holdsMutex       sync.Mutex             // Protects holds and holdReporting
+	runningCount     atomic.Int32           // Actions running right now, for max_concurrent_actions
 }
 
 // NewButtonHandler creates a new ButtonHandler instance
@@ -68,6 +115,12 @@ func NewButtonHandler(d *Deej, logger *zap.SugaredLogger) (*ButtonHandler, error
 		runningActions:   make(map[string]context.CancelFunc),
 		trackedProcesses: make(map[string]*exec.Cmd),
 		trackedHandles:   make(map[string]interface{}),
+		templateValues:   d,
+		armedActions:     make(map[string]time.Time),
+		executor:         platformActionExecutor{},
+		writeToDevice:    d.WriteToDevice,
+		holds:            make(map[int]*buttonHold),
+		holdReporting:    make(map[int]bool),
 	}
 
 	logger.Debug("ButtonHandler created")
@@ -87,6 +140,24 @@ func (bh *ButtonHandler) UpdateConfig(config *buttonsMap) {
 
 	// Convert to public ButtonsMapping
 	bh.config = config.ToButtonsMapping()
+
+	// Tell the user about missing tools now, rather than when a button fails mid-session
+	if missing := missingActionDependencies(bh.config, exec.LookPath); len(missing) > 0 {
+		bh.logger.Warnw("Button actions need tools that aren't installed", "missing", missing)
+		bh.notifier.Notify("Button actions may not work",
+			fmt.Sprintf("Please install: %s", strings.Join(missing, ", ")))
+	}
+}
+
+// disabledActions returns the step types disabled_actions turns off
+func (bh *ButtonHandler) disabledActions() map[string]bool {
+	bh.configMutex.RLock()
+	defer bh.configMutex.RUnlock()
+
+	if bh.config == nil {
+		return nil
+	}
+	return bh.config.DisabledActions
 }
 
 // CancelAllActions cancels all currently running button actions and terminates tracked processes
@@ -150,8 +221,53 @@ func (bh *ButtonHandler) CancelAllActions() {
 	}
 }
 
+// Shutdown cancels all running actions and waits up to grace for their goroutines to unwind.
+// Processes tracked while waiting (e.g. spawned by a step that was mid-flight) are terminated too.
+// Returns false if some actions were still running when grace expired
+func (bh *ButtonHandler) Shutdown(grace time.Duration) bool {
+	// once this is set no action adds to actionsWG, so the Wait below can't race with an Add
+	bh.lifecycleMutex.Lock()
+	bh.shuttingDown.Store(true)
+	bh.lifecycleMutex.Unlock()
+
+	bh.CancelAllActions()
+
+	done := make(chan struct{})
+	go func() {
+		bh.actionsWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		bh.logger.Debug("All button actions finished")
+		return true
+	case <-time.After(grace):
+		// sweep once more in case a step tracked a process after the first cancellation
+		bh.CancelAllActions()
+		bh.logger.Warnw("Button actions did not finish within grace period", "grace", grace)
+		return false
+	}
+}
+
 // HandleButtonPress handles a button press event
 func (bh *ButtonHandler) HandleButtonPress(buttonID int, actionType string) error {
+	if bh.shuttingDown.Load() {
+		bh.logger.Debugw("Ignoring button press during shutdown", "button", buttonID, "action", actionType)
+		return nil
+	}
+
+	// the hold is timed by deej instead, see button_hold.go
+	if actionType == ButtonActionLong && bh.deviceLongPressIgnored(buttonID) {
+		bh.logger.Debugw("Ignoring device long press, timed by hold_ms", "button", buttonID)
+		return nil
+	}
+
+	return bh.pressButton(buttonID, actionType)
+}
+
+// pressButton runs the action configured for a button and action type
+func (bh *ButtonHandler) pressButton(buttonID int, actionType string) error {
 	bh.configMutex.RLock()
 	config := bh.config
 	bh.configMutex.RUnlock()
@@ -198,6 +314,7 @@ func (bh *ButtonHandler) HandleButtonPress(buttonID int, actionType string) erro
 	// Copy actionConfig data to avoid race conditions
 	// We copy the data before releasing the mutex and launching the goroutine
 	exclusive := actionConfig.Exclusive
+	silent := actionConfig.Silent
 	steps := make([]ActionStep, len(actionConfig.Steps))
 	copy(steps, actionConfig.Steps)
 
@@ -220,6 +337,66 @@ func (bh *ButtonHandler) HandleButtonPress(buttonID int, actionType string) erro
 		}
 	}
 
+	// Macros shouldn't fire into a presentation
+	if bh.focusAssistPaused(config) {
+		bh.logger.Infow("Focus Assist is on, ignoring button press", "button", buttonID, "action", actionType)
+		if config.FocusAssistNotify {
+			bh.notifier.Notify("Button action paused",
+				fmt.Sprintf("Focus Assist is on, so button %d (%s) didn't run its action.", buttonID, actionType))
+		}
+		return nil
+	}
+
+	// Destructive actions can require a second press to confirm
+	if actionConfig.Confirm && !bh.confirmPress(key, actionConfig.ConfirmMs) {
+		bh.logger.Infow("Action armed, waiting for confirmation", "button", buttonID, "action", actionType)
+		bh.notifier.Notify("Press again to confirm",
+			fmt.Sprintf("Press button %d (%s) again to run its action.", buttonID, actionType))
+		return nil
+	}
+
+	// Mashing buttons shouldn't pile up actions without end
+	if !bh.reserveActionSlot(config.MaxConcurrent) {
+		bh.logger.Infow("Too many actions running, ignoring button press",
+			"button", buttonID, "action", actionType, "running", bh.runningCount.Load(), "max_concurrent_actions", config.MaxConcurrent)
+		return nil
+	}
+
+	bh.startAction(key, buttonID, actionType, silent, actionConfig.RestoreFocus, steps)
+
+	return nil
+}
+
+// reserveActionSlot counts one more running action, unless max of them (0 = no limit) are running already.
+// The check and the count happen in one step, so presses arriving together can't both slip under the limit
+func (bh *ButtonHandler) reserveActionSlot(max int) bool {
+	for {
+		running := bh.runningCount.Load()
+		if max > 0 && int(running) >= max {
+			return false
+		}
+
+		if bh.runningCount.CompareAndSwap(running, running+1) {
+			return true
+		}
+	}
+}
+
+// startAction runs steps in the background, tracked under key so they can be cancelled. With restoreFocus,
+// the window that was in the foreground before the steps ran is focused again once they're done.
+// The caller has reserved a slot for it with reserveActionSlot, which is released when the steps end
+func (bh *ButtonHandler) startAction(key string, buttonID int, actionType string, silent bool, restoreFocus bool, steps []ActionStep) {
+	// a press that got past the shutdown checks in the meantime must not start anything once Shutdown waits
+	bh.lifecycleMutex.Lock()
+	if bh.shuttingDown.Load() {
+		bh.lifecycleMutex.Unlock()
+		bh.runningCount.Add(-1)
+		bh.logger.Debugw("Not starting action during shutdown", "button", buttonID, "action", actionType)
+		return
+	}
+	bh.actionsWG.Add(1)
+	bh.lifecycleMutex.Unlock()
+
 	// Create context for this action
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -230,6 +407,9 @@ func (bh *ButtonHandler) HandleButtonPress(buttonID int, actionType string) erro
 
 	// Execute action in goroutine to avoid blocking the main event handler
 	go func() {
+		defer bh.actionsWG.Done()
+		defer bh.runningCount.Add(-1)
+
 		// Recover from panics to prevent goroutine crash and application termination
 		defer func() {
 			if r := recover(); r != nil {
@@ -245,12 +425,24 @@ func (bh *ButtonHandler) HandleButtonPress(buttonID int, actionType string) erro
 			cancel()
 		}()
 
-		err := bh.executeAction(ctx, steps, buttonID, actionType, key)
+		if restoreFocus {
+			if window, ok := bh.executor.ForegroundWindow(); ok {
+				defer bh.restoreForegroundWindow(window, buttonID, actionType)
+			} else {
+				bh.logger.Debugw("No foreground window to restore after the action", "button", buttonID, "action", actionType)
+			}
+		}
+
+		err := bh.executeAction(ctx, steps, buttonID, actionType, key, []string{key})
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				bh.logger.Debugw("Action cancelled", "button", buttonID, "action", actionType)
 			} else {
 				bh.logger.Warnw("Action execution failed", "button", buttonID, "action", actionType, "error", err)
+				if silent {
+					return
+				}
+
 				// Notify user about the error
 				var title, message string
 				if actionErr, ok := err.(*ActionError); ok && actionErr.Step != nil {
@@ -273,12 +465,84 @@ func (bh *ButtonHandler) HandleButtonPress(buttonID int, actionType string) erro
 			bh.logger.Debugw("Action completed successfully", "button", buttonID, "action", actionType)
 		}
 	}()
+}
+
+// HandleConnect runs the on_connect action when a device connects. Unless every_connect is set it
+// only runs for the first connection, so reconnects after a dropout don't repeat it
+func (bh *ButtonHandler) HandleConnect(transport string) error {
+	if bh.shuttingDown.Load() {
+		return nil
+	}
+
+	bh.configMutex.RLock()
+	config := bh.config
+	bh.configMutex.RUnlock()
+
+	if config == nil || config.OnConnect == nil || len(config.OnConnect.Steps) == 0 {
+		return nil
+	}
+
+	if !config.OnConnectEvery && bh.connectActionRan.Swap(true) {
+		bh.logger.Debugw("on_connect action already ran, skipping", "transport", transport)
+		return nil
+	}
+
+	key := ActionOnConnect
+	if config.OnConnect.Exclusive {
+		bh.actionsMutex.RLock()
+		_, running := bh.runningActions[key]
+		bh.actionsMutex.RUnlock()
+
+		if running {
+			bh.logger.Debugw("on_connect action already running (exclusive)", "transport", transport)
+			return nil
+		}
+	}
+
+	steps := make([]ActionStep, len(config.OnConnect.Steps))
+	copy(steps, config.OnConnect.Steps)
+
+	bh.logger.Infow("Starting on_connect action", "transport", transport, "steps_count", len(steps), "steps", steps)
+	// on_connect isn't a button press, max_concurrent_actions doesn't hold it back
+	bh.reserveActionSlot(0)
+	bh.startAction(key, -1, ActionOnConnect, config.OnConnect.Silent, config.OnConnect.RestoreFocus, steps)
 
 	return nil
 }
 
-// executeAction executes a sequence of action steps
-func (bh *ButtonHandler) executeAction(ctx context.Context, steps []ActionStep, buttonID int, actionType string, key string) error {
+// confirmPress arms a confirm action on its first press and reports true when a second press
+// comes in within the confirmation window, disarming it again
+func (bh *ButtonHandler) confirmPress(key string, windowMs int) bool {
+	if windowMs <= 0 {
+		windowMs = defaultConfirmWindowMs
+	}
+
+	bh.armedMutex.Lock()
+	defer bh.armedMutex.Unlock()
+
+	now := time.Now()
+	if armedAt, ok := bh.armedActions[key]; ok && now.Sub(armedAt) <= time.Duration(windowMs)*time.Millisecond {
+		delete(bh.armedActions, key)
+		return true
+	}
+
+	bh.armedActions[key] = now
+	return false
+}
+
+// executeAction executes a sequence of action steps. callStack holds the "buttonID_actionType" of the
+// action being run and of every action that called it, for the cycle check of call steps
+func (bh *ButtonHandler) executeAction(ctx context.Context, steps []ActionStep, buttonID int, actionType string, key string, callStack []string) error {
+	disabled := bh.disabledActions()
+	notifiedDisabled := false
+
+	// per-step timing, logged once the action is over so slow steps stand out
+	started := time.Now()
+	timings := make([]stepTiming, 0, len(steps))
+	defer func() {
+		bh.logStepTimings(buttonID, actionType, started, timings)
+	}()
+
 	for stepIdx, step := range steps {
 		// Check for cancellation
 		select {
@@ -287,26 +551,63 @@ func (bh *ButtonHandler) executeAction(ctx context.Context, steps []ActionStep,
 		default:
 		}
 
+		if disabled[step.Type] {
+			bh.logger.Warnw("Skipping disabled action step", "button", buttonID, "action", actionType, "step", stepIdx, "type", step.Type)
+			if !notifiedDisabled {
+				bh.notifier.Notify("Button action step skipped",
+					fmt.Sprintf("%s steps are disabled by disabled_actions", step.Type))
+				notifiedDisabled = true
+			}
+			continue
+		}
+
 		bh.logger.Debugw("Executing step", "button", buttonID, "action", actionType, "step", stepIdx, "type", step.Type)
 
+		stepStarted := time.Now()
 		var err error
 		switch step.Type {
 		case ActionTypeExecute:
-			err = executeActionPlatform(ctx, &step, buttonID, actionType, key, bh)
-			// Note: Window readiness is verified using SendMessageTimeout in executeActionPlatform
-			// No additional delay needed here
+			bh.expandExecuteTemplates(&step)
+
+			var handled bool
+			handled, err = bh.handleAlreadyRunning(&step)
+			if !handled && err == nil {
+				err = bh.executor.Execute(ctx, &step, buttonID, actionType, key, bh)
+			}
+			// Note: Window readiness is verified using SendMessageTimeout in executeActionPlatform.
+			// settle_ms adds a fixed pause on top of that for apps that report ready too early
+			if !handled && err == nil {
+				err = bh.executeSettle(ctx, &step)
+			}
 		case ActionTypeDelay:
 			err = bh.executeDelay(ctx, &step)
 		case ActionTypeKeystroke:
-			err = keystrokeActionImpl(ctx, &step, bh.logger)
+			if err = bh.focusStepTarget(&step); err == nil {
+				err = bh.executor.Keystroke(ctx, &step, bh.logger)
+			}
 		case ActionTypeTyping:
 			// Window readiness is verified using SendMessageTimeout in typingActionImpl
 			// No fixed delay needed here - the platform-specific implementation handles it
-			err = typingActionImpl(ctx, &step, bh.logger)
+			if err = bh.loadStepTextFile(&step); err == nil {
+				bh.limitTypingText(&step, buttonID, actionType)
+				if err = bh.focusStepTarget(&step); err == nil {
+					err = bh.executor.Typing(ctx, &step, bh.logger)
+				}
+			}
+		case ActionTypeWaitProcess:
+			err = bh.executeWaitProcess(ctx, &step)
+		case ActionTypeBeep:
+			err = bh.executor.Beep(ctx, &step, bh.logger)
+		case ActionTypeKill:
+			err = bh.executeKill(&step)
+		case ActionTypeCall:
+			err = bh.executeCall(ctx, &step, key, callStack)
 		default:
 			err = fmt.Errorf("unknown step type: %s", step.Type)
 		}
 
+		timings = append(timings, stepTiming{index: stepIdx, stepType: step.Type, start: stepStarted, duration: time.Since(stepStarted), failed: err != nil})
+
 		if err != nil {
 			return fmt.Errorf("step %d (%s) failed: %w", stepIdx, step.Type, err)
 		}
@@ -315,6 +616,99 @@ func (bh *ButtonHandler) executeAction(ctx context.Context, steps []ActionStep,
 	return nil
 }
 
+// executeCall runs another button's action inline, as it's configured right now. Processes it starts
+// are tracked under the calling action's key, so cancelling the caller cancels them too
+func (bh *ButtonHandler) executeCall(ctx context.Context, step *ActionStep, key string, callStack []string) error {
+	ref := fmt.Sprintf("%d_%s", step.Button, step.Action)
+
+	if slices.Contains(callStack, ref) {
+		return fmt.Errorf("call cycle: %s -> %s", strings.Join(callStack, " -> "), ref)
+	}
+	if len(callStack) > maxActionCallDepth {
+		return fmt.Errorf("calls nested deeper than %d", maxActionCallDepth)
+	}
+
+	bh.configMutex.RLock()
+	config := bh.config
+	bh.configMutex.RUnlock()
+
+	var actionConfig *ButtonActionConfig
+	if config != nil {
+		if buttonConfig, ok := config.Buttons[step.Button]; ok {
+			switch step.Action {
+			case ButtonActionSingle:
+				actionConfig = buttonConfig.Single
+			case ButtonActionDouble:
+				actionConfig = buttonConfig.Double
+			case ButtonActionLong:
+				actionConfig = buttonConfig.Long
+			}
+		}
+	}
+	if actionConfig == nil {
+		return fmt.Errorf("button %d has no %s action", step.Button, step.Action)
+	}
+
+	steps := make([]ActionStep, len(actionConfig.Steps))
+	copy(steps, actionConfig.Steps)
+
+	bh.logger.Debugw("Calling action", "button", step.Button, "action", step.Action, "steps_count", len(steps))
+
+	// a fresh slice each time, so sibling calls don't share a backing array
+	nested := append(slices.Clone(callStack), ref)
+
+	return bh.executeAction(ctx, steps, step.Button, step.Action, key, nested)
+}
+
+// stepTiming records how long a single step of an action took
+type stepTiming struct {
+	index    int
+	stepType string
+	start    time.Time
+	duration time.Duration
+	failed   bool
+}
+
+// logStepTimings logs the timing of every step that ran, e.g. "1:execute 2.31s (+500ms)", the part in
+// brackets being when the step started. Handy for finding the step that makes a macro slow
+func (bh *ButtonHandler) logStepTimings(buttonID int, actionType string, started time.Time, timings []stepTiming) {
+	if len(timings) == 0 {
+		return
+	}
+
+	entries := make([]string, 0, len(timings))
+	for _, timing := range timings {
+		entry := fmt.Sprintf("%d:%s %s (+%s)", timing.index, timing.stepType,
+			timing.duration.Round(time.Millisecond), timing.start.Sub(started).Round(time.Millisecond))
+		if timing.failed {
+			entry += " failed"
+		}
+		entries = append(entries, entry)
+	}
+
+	bh.logger.Infow("Action step timings",
+		"button", buttonID,
+		"action", actionType,
+		"total", time.Since(started).Round(time.Millisecond),
+		"steps", entries)
+}
+
+// expandExecuteTemplates fills in {{...}} templates in an execute step's app and args.
+// The step is a copy, but its args slice is shared with the config, so a new one is built
+func (bh *ButtonHandler) expandExecuteTemplates(step *ActionStep) {
+	if bh.templateValues == nil {
+		return
+	}
+
+	step.App = expandActionTemplates(step.App, bh.templateValues, bh.logger)
+
+	args := make([]string, len(step.Args))
+	for i, arg := range step.Args {
+		args[i] = expandActionTemplates(arg, bh.templateValues, bh.logger)
+	}
+	step.Args = args
+}
+
 // executeDelay executes a delay step
 func (bh *ButtonHandler) executeDelay(ctx context.Context, step *ActionStep) error {
 	if step.Ms <= 0 {
@@ -323,12 +717,280 @@ func (bh *ButtonHandler) executeDelay(ctx context.Context, step *ActionStep) err
 
 	bh.logger.Debugw("Delaying", "ms", step.Ms)
 
-	select {
-	case <-ctx.Done():
-		return context.Canceled
-	case <-time.After(time.Duration(step.Ms) * time.Millisecond):
+	return bh.executor.Sleep(ctx, time.Duration(step.Ms)*time.Millisecond)
+}
+
+// executeSettle pauses for an execute step's settle_ms after the app was launched
+func (bh *ButtonHandler) executeSettle(ctx context.Context, step *ActionStep) error {
+	if step.SettleMs <= 0 {
 		return nil
 	}
+
+	bh.logger.Debugw("Letting launched app settle", "app", step.App, "ms", step.SettleMs)
+
+	return bh.executor.Sleep(ctx, time.Duration(step.SettleMs)*time.Millisecond)
+}
+
+// executeWaitProcess blocks until the named process starts or exits, depending on the step's mode
+func (bh *ButtonHandler) executeWaitProcess(ctx context.Context, step *ActionStep) error {
+	wantRunning := step.Mode == WaitProcessModeStart
+
+	bh.logger.Debugw("Waiting for process", "process", step.ProcessName, "mode", step.Mode, "timeout_ms", step.Timeout)
+
+	var timeout <-chan time.Time
+	if step.Timeout > 0 {
+		timer := time.NewTimer(time.Duration(step.Timeout) * time.Millisecond)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	ticker := time.NewTicker(waitProcessPollInterval)
+	defer ticker.Stop()
+
+	for {
+		processes, err := bh.executor.FindProcesses(step.ProcessName)
+		if err != nil {
+			return &ActionError{
+				Type:    ErrorExecutionFailed,
+				Message: fmt.Sprintf("failed to list processes: %v", err),
+				Step:    step,
+				Err:     err,
+			}
+		}
+
+		if running := len(processes) > 0; running == wantRunning {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return context.Canceled
+		case <-timeout:
+			return &ActionError{
+				Type:    ErrorTimeout,
+				Message: fmt.Sprintf("process %s did not %s within %dms", step.ProcessName, step.Mode, step.Timeout),
+				Step:    step,
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// executeKill terminates every running process with the step's name. Nothing running is not an error
+func (bh *ButtonHandler) executeKill(step *ActionStep) error {
+	// Validate refuses these too, but this also covers configs applied some other way
+	if protectedProcess(step.ProcessName) {
+		return &ActionError{
+			Type:    ErrorPermissionDenied,
+			Message: fmt.Sprintf("%s is a system process and can't be killed", step.ProcessName),
+			Step:    step,
+		}
+	}
+
+	processes, err := bh.executor.FindProcesses(step.ProcessName)
+	if err != nil {
+		return &ActionError{
+			Type:    ErrorExecutionFailed,
+			Message: fmt.Sprintf("failed to list processes: %v", err),
+			Step:    step,
+			Err:     err,
+		}
+	}
+
+	if len(processes) == 0 {
+		bh.logger.Debugw("No process to kill", "process", step.ProcessName)
+		return nil
+	}
+
+	var failed []error
+	for _, process := range processes {
+		// never take deej down with it, whatever the binary is called
+		if process.Pid() == os.Getpid() {
+			continue
+		}
+
+		bh.logger.Infow("Killing process", "process", step.ProcessName, "pid", process.Pid(), "force", step.Force)
+
+		if err := bh.executor.KillProcess(process.Pid(), step.Force, bh.logger); err != nil {
+			bh.logger.Warnw("Failed to kill process", "process", step.ProcessName, "pid", process.Pid(), "error", err)
+			failed = append(failed, err)
+		}
+	}
+
+	if len(failed) > 0 {
+		return &ActionError{
+			Type:    ErrorExecutionFailed,
+			Message: fmt.Sprintf("failed to kill %d of %d %s processes", len(failed), len(processes), step.ProcessName),
+			Step:    step,
+			Err:     errors.Join(failed...),
+		}
+	}
+
+	return nil
+}
+
+// handleAlreadyRunning applies an execute step's if_running mode. It returns true if the app was
+// already running and got focused or skipped, meaning there's nothing left to launch
+func (bh *ButtonHandler) handleAlreadyRunning(step *ActionStep) (bool, error) {
+	if step.IfRunning == "" || step.IfRunning == IfRunningLaunch {
+		return false, nil
+	}
+
+	name := filepath.Base(step.App)
+	processes, err := bh.executor.FindProcesses(name)
+	if err != nil {
+		return false, &ActionError{
+			Type:    ErrorExecutionFailed,
+			Message: fmt.Sprintf("failed to list processes: %v", err),
+			Step:    step,
+			Err:     err,
+		}
+	}
+
+	if len(processes) == 0 {
+		return false, nil
+	}
+
+	if step.IfRunning == IfRunningSkip {
+		bh.logger.Debugw("Application already running, skipping launch", "app", step.App)
+		return true, nil
+	}
+
+	// several processes may share the name (helpers, tabs) - focus the first one that owns a window
+	for _, process := range processes {
+		if bh.executor.FocusProcessWindow(process.Pid(), bh.logger) {
+			bh.logger.Debugw("Application already running, focused existing window", "app", step.App, "pid", process.Pid())
+			return true, nil
+		}
+	}
+
+	bh.logger.Infow("Application already running but has no window to focus, not launching another instance", "app", step.App)
+	return true, nil
+}
+
+// loadStepTextFile reads a typing step's text_file into its text. The file is read on every run,
+// so edits take effect without a config reload
+func (bh *ButtonHandler) loadStepTextFile(step *ActionStep) error {
+	if step.TextFile == "" {
+		return nil
+	}
+
+	info, err := os.Stat(step.TextFile)
+	if err != nil {
+		return &ActionError{
+			Type:    ErrorExecutionFailed,
+			Message: fmt.Sprintf("can't read text_file %s", step.TextFile),
+			Step:    step,
+			Err:     err,
+		}
+	}
+
+	if info.Size() > typingTextFileMaxBytes {
+		return &ActionError{
+			Type:    ErrorExecutionFailed,
+			Message: fmt.Sprintf("text_file %s is %d bytes, the limit is %d", step.TextFile, info.Size(), typingTextFileMaxBytes),
+			Step:    step,
+		}
+	}
+
+	content, err := os.ReadFile(step.TextFile)
+	if err != nil {
+		return &ActionError{
+			Type:    ErrorExecutionFailed,
+			Message: fmt.Sprintf("can't read text_file %s", step.TextFile),
+			Step:    step,
+			Err:     err,
+		}
+	}
+
+	text := string(content)
+	if !step.Escapes {
+		// The typing implementations always process escapes, so protect backslashes to type the file as-is
+		text = strings.ReplaceAll(text, "\\", "\\\\")
+	}
+
+	bh.logger.Debugw("Loaded typing text from file", "file", step.TextFile, "bytes", len(content))
+	step.Text = text
+	return nil
+}
+
+// restoreForegroundWindow focuses the window restore_focus saved before the action ran
+func (bh *ButtonHandler) restoreForegroundWindow(window uintptr, buttonID int, actionType string) {
+	if !bh.executor.RestoreForegroundWindow(window, bh.logger) {
+		bh.logger.Debugw("Couldn't restore the foreground window after the action", "button", buttonID, "action", actionType)
+		return
+	}
+
+	bh.logger.Debugw("Restored the foreground window after the action", "button", buttonID, "action", actionType)
+}
+
+// limitTypingText cuts a typing step's text off at max_typing_length, so a config typo or the wrong
+// text_file can't type a wall of text into whatever window has focus
+func (bh *ButtonHandler) limitTypingText(step *ActionStep, buttonID int, actionType string) {
+	bh.configMutex.RLock()
+	maxLength := default_MaxTypingLength
+	if bh.config != nil && bh.config.MaxTypingLength > 0 {
+		maxLength = bh.config.MaxTypingLength
+	}
+	bh.configMutex.RUnlock()
+
+	runes := []rune(step.Text)
+	if len(runes) <= maxLength {
+		return
+	}
+
+	bh.logger.Warnw("Typing text exceeds max_typing_length, cutting it off",
+		"button", buttonID, "action", actionType, "length", len(runes), "max_typing_length", maxLength)
+	step.Text = string(runes[:maxLength])
+}
+
+// focusStepTarget focuses the window named by a keystroke/typing step's target, if it has one.
+// The target is tried as a process name first, then as a window title. Input must never land
+// in the wrong app, so failing to focus the target is an error
+func (bh *ButtonHandler) focusStepTarget(step *ActionStep) error {
+	if step.Target == "" {
+		return nil
+	}
+
+	processes, err := bh.executor.FindProcesses(step.Target)
+	if err != nil {
+		bh.logger.Debugw("Failed to list processes for target, trying window title only", "target", step.Target, "error", err)
+	}
+
+	pids := make([]int, 0, len(processes))
+	for _, process := range processes {
+		pids = append(pids, process.Pid())
+	}
+
+	if !bh.executor.FocusTargetWindow(pids, step.Target, bh.logger) {
+		return &ActionError{
+			Type:    ErrorExecutionFailed,
+			Message: fmt.Sprintf("target window %s not found or could not be focused", step.Target),
+			Step:    step,
+		}
+	}
+
+	bh.logger.Debugw("Focused target window", "target", step.Target)
+	return nil
+}
+
+// findRunningProcesses returns the running processes with the given name.
+// Names are matched case-insensitively and the .exe suffix is optional
+func findRunningProcesses(name string) ([]ps.Process, error) {
+	processes, err := processLister()
+	if err != nil {
+		return nil, err
+	}
+
+	target := strings.TrimSuffix(strings.ToLower(name), ".exe")
+	matches := []ps.Process{}
+	for _, process := range processes {
+		if strings.TrimSuffix(strings.ToLower(process.Executable()), ".exe") == target {
+			matches = append(matches, process)
+		}
+	}
+
+	return matches, nil
 }
 
 // trackProcess tracks a Linux process (exec.Cmd) for forced termination on cancel_on_reload
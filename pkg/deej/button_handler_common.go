@@ -1,28 +1,128 @@
-package deej
-
-import "strings"
-
-// processEscapeSequences processes escape sequences in text
-// Converts \n, \t, \r, \\ to actual characters
-// This function is shared between Windows and Linux implementations
-func processEscapeSequences(text string) string {
-	// Process escape sequences:
-	// \n = newline
-	// \t = tab
-	// \r = carriage return
-	// \\ = backslash
-
-	// First handle \\ to avoid double processing
-	// Use a temporary marker that won't appear in normal text
-	result := strings.ReplaceAll(text, "\\\\", "\x00")
-
-	// Convert escape sequences
-	result = strings.ReplaceAll(result, "\\n", "\n")
-	result = strings.ReplaceAll(result, "\\r", "\r")
-	result = strings.ReplaceAll(result, "\\t", "\t")
-
-	// Restore backslashes
-	result = strings.ReplaceAll(result, "\x00", "\\")
-
-	return result
-}
+package deej
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// actionTemplatePattern matches {{name}} and {{name:arg}} templates in execute steps
+var actionTemplatePattern = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*(?::\s*([^}]*?)\s*)?\}\}`)
+
+// actionTemplateValues supplies the runtime values execute step templates expand to
+type actionTemplateValues interface {
+	SliderPercent(idx int) (int, bool)
+	ForegroundWindowTitle() (string, error)
+}
+
+// expandActionTemplates replaces the templates in s with their current values:
+//
+//	{{slider:N}} - last volume percent of slider N (empty if it hasn't reported yet)
+//	{{fg_title}} - title of the focused window
+//
+// Unknown templates are left as they are, so text without templates passes through unchanged
+func expandActionTemplates(s string, values actionTemplateValues, logger *zap.SugaredLogger) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+
+	return actionTemplatePattern.ReplaceAllStringFunc(s, func(match string) string {
+		parts := actionTemplatePattern.FindStringSubmatch(match)
+		name, arg := parts[1], parts[2]
+
+		switch name {
+		case "slider":
+			idx, err := strconv.Atoi(arg)
+			if err != nil {
+				logger.Warnw("Invalid slider template", "template", match)
+				return match
+			}
+			percent, ok := values.SliderPercent(idx)
+			if !ok {
+				logger.Debugw("No value for slider template yet", "slider", idx)
+				return ""
+			}
+			return strconv.Itoa(percent)
+
+		case "fg_title":
+			title, err := values.ForegroundWindowTitle()
+			if err != nil {
+				logger.Debugw("Failed to get foreground window title for template", "error", err)
+				return ""
+			}
+			return title
+
+		default:
+			return match
+		}
+	})
+}
+
+// processEscapeSequences processes escape sequences in text
+// Converts \n, \t, \r, \\ to actual characters
+// This function is shared between Windows and Linux implementations
+func processEscapeSequences(text string) string {
+	// Process escape sequences:
+	// \n = newline
+	// \t = tab
+	// \r = carriage return
+	// \\ = backslash
+
+	// First handle \\ to avoid double processing
+	// Use a temporary marker that won't appear in normal text
+	result := strings.ReplaceAll(text, "\\\\", "\x00")
+
+	// Convert escape sequences
+	result = strings.ReplaceAll(result, "\\n", "\n")
+	result = strings.ReplaceAll(result, "\\r", "\r")
+	result = strings.ReplaceAll(result, "\\t", "\t")
+
+	// Restore backslashes
+	result = strings.ReplaceAll(result, "\x00", "\\")
+
+	return result
+}
+
+// missingActionDependencies returns the external tools needed by the configured action steps
+// (see actionToolDependencies) that lookPath can't find, sorted and without duplicates
+func missingActionDependencies(mapping *ButtonsMapping, lookPath func(file string) (string, error)) []string {
+	if mapping == nil {
+		return nil
+	}
+
+	missing := make(map[string]bool)
+	for _, buttonConfig := range mapping.Buttons {
+		if buttonConfig == nil {
+			continue
+		}
+
+		for _, actionConfig := range []*ButtonActionConfig{buttonConfig.Single, buttonConfig.Double, buttonConfig.Long} {
+			if actionConfig == nil {
+				continue
+			}
+
+			for _, step := range actionConfig.Steps {
+				for _, tool := range actionToolDependencies[step.Type] {
+					if _, checked := missing[tool]; checked {
+						continue
+					}
+					_, err := lookPath(tool)
+					missing[tool] = err != nil
+				}
+			}
+		}
+	}
+
+	result := []string{}
+	for tool, isMissing := range missing {
+		if isMissing {
+			result = append(result, tool)
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
@@ -0,0 +1,59 @@
+package deej
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// fakeTemplateValues serves fixed slider percents and window title to action templates
+type fakeTemplateValues struct {
+	sliders map[int]int
+	title   string
+}
+
+func (f fakeTemplateValues) SliderPercent(idx int) (int, bool) {
+	percent, ok := f.sliders[idx]
+	return percent, ok
+}
+
+func (f fakeTemplateValues) ForegroundWindowTitle() (string, error) {
+	if f.title == "" {
+		return "", errors.New("no foreground window")
+	}
+	return f.title, nil
+}
+
+func TestExpandActionTemplates(t *testing.T) {
+	values := fakeTemplateValues{sliders: map[int]int{0: 0, 2: 75}, title: "Spotify Premium"}
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"--volume", "--volume"},
+		{"--volume={{slider:2}}", "--volume=75"},
+		{"{{ slider : 2 }}%", "75%"},
+		{"{{slider:0}}", "0"},
+		{"{{slider:5}}", ""},
+		{"{{slider:x}}", "{{slider:x}}"},
+		{"now playing: {{fg_title}}", "now playing: Spotify Premium"},
+		{"{{slider:2}} {{fg_title}}", "75 Spotify Premium"},
+		{"{{unknown}}", "{{unknown}}"},
+		{"{single braces}", "{single braces}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := expandActionTemplates(tt.in, values, zap.NewNop().Sugar()); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// no focused window expands to nothing rather than failing the step
+	if got := expandActionTemplates("[{{fg_title}}]", fakeTemplateValues{}, zap.NewNop().Sugar()); got != "[]" {
+		t.Errorf("without a foreground window: got %q, want %q", got, "[]")
+	}
+}
@@ -7,7 +7,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -15,6 +18,12 @@ import (
 	"go.uber.org/zap"
 )
 
+// actionToolDependencies lists the external tools each step type needs on Linux
+var actionToolDependencies = map[string][]string{
+	ActionTypeKeystroke: {"xdotool"},
+	ActionTypeTyping:    {"xdotool"},
+}
+
 // keystrokeActionImpl implements keystroke simulation for Linux
 func keystrokeActionImpl(ctx context.Context, step *ActionStep, logger *zap.SugaredLogger) error {
 	if step.Keys == "" {
@@ -43,7 +52,15 @@ func keystrokeActionImpl(ctx context.Context, step *ActionStep, logger *zap.Suga
 	// xdotool key ctrl+alt+t
 	xdotoolKeys := buildXdotoolKeyString(keys)
 
-	cmd := exec.CommandContext(ctx, "xdotool", "key", xdotoolKeys)
+	args := []string{"key"}
+	if step.Scancode {
+		// xdotool always sends keysyms through XTest, which games see as real key presses;
+		// clearing held modifiers keeps them from leaking into the combination
+		args = append(args, "--clearmodifiers")
+	}
+	args = append(args, xdotoolKeys)
+
+	cmd := exec.CommandContext(ctx, "xdotool", args...)
 
 	if err := cmd.Run(); err != nil {
 		// Check if it's a permission error
@@ -89,6 +106,49 @@ func buildXdotoolKeyString(keys []string) string {
 	return strings.Join(parts, "+")
 }
 
+// beepSoundFile is the freedesktop bell sound played with paplay when no tone is requested
+const beepSoundFile = "/usr/share/sounds/freedesktop/stereo/bell.oga"
+
+// beepActionImpl plays a tone with beep, or the bell sound with paplay, falling back to the terminal bell
+func beepActionImpl(ctx context.Context, step *ActionStep, logger *zap.SugaredLogger) error {
+	argv := beepCommand(step, exec.LookPath)
+	if argv == nil {
+		logger.Debugw("No beep tool found, ringing terminal bell")
+		_, err := os.Stdout.WriteString("\a")
+		return err
+	}
+
+	logger.Debugw("Playing beep", "command", argv)
+
+	if err := exec.CommandContext(ctx, argv[0], argv[1:]...).Run(); err != nil {
+		return fmt.Errorf("failed to play beep: %w", err)
+	}
+
+	return nil
+}
+
+// beepCommand picks the command used to play a beep step, or nil if no tool is available.
+// beep can play the requested tone; paplay ignores frequency and plays the system bell
+func beepCommand(step *ActionStep, lookPath func(string) (string, error)) []string {
+	if _, err := lookPath("beep"); err == nil {
+		if step.Frequency == 0 {
+			return []string{"beep"}
+		}
+
+		duration := step.Ms
+		if duration == 0 {
+			duration = beepDefaultDurationMs
+		}
+		return []string{"beep", "-f", strconv.Itoa(step.Frequency), "-l", strconv.Itoa(duration)}
+	}
+
+	if _, err := lookPath("paplay"); err == nil {
+		return []string{"paplay", beepSoundFile}
+	}
+
+	return nil
+}
+
 // typingActionImpl implements text typing simulation for Linux
 func typingActionImpl(ctx context.Context, step *ActionStep, logger *zap.SugaredLogger) error {
 	if step.Text == "" {
@@ -144,6 +204,92 @@ func typingActionImpl(ctx context.Context, step *ActionStep, logger *zap.Sugared
 	return nil
 }
 
+// focusProcessWindowImpl activates a visible window of the given process using xdotool
+func focusProcessWindowImpl(pid int, logger *zap.SugaredLogger) bool {
+	cmd := exec.Command("xdotool", "search", "--onlyvisible", "--pid", strconv.Itoa(pid), "windowactivate")
+	if err := cmd.Run(); err != nil {
+		logger.Debugw("No window focused for process", "pid", pid, "error", err)
+		return false
+	}
+
+	return true
+}
+
+// focusTargetWindowImpl activates a window of one of the given processes, or else the first visible window
+// whose title matches title, and reports whether it became the active window.
+// We activate rather than using xdotool's --window, since many applications ignore the synthetic events it sends
+func focusTargetWindowImpl(pids []int, title string, logger *zap.SugaredLogger) bool {
+	var windowID string
+	for _, pid := range pids {
+		if windowID = xdotoolSearch("--pid", strconv.Itoa(pid)); windowID != "" {
+			break
+		}
+	}
+
+	if windowID == "" && title != "" {
+		windowID = xdotoolSearch("--name", regexp.QuoteMeta(title))
+	}
+
+	if windowID == "" {
+		logger.Debugw("No window found for target", "target", title)
+		return false
+	}
+
+	if err := exec.Command("xdotool", "windowactivate", "--sync", windowID).Run(); err != nil {
+		logger.Debugw("Failed to activate target window", "window", windowID, "error", err)
+		return false
+	}
+
+	active, err := exec.Command("xdotool", "getactivewindow").Output()
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(string(active)) == windowID
+}
+
+// foregroundWindowImpl returns the id of the active window, for restore_focus
+func foregroundWindowImpl() (uintptr, bool) {
+	output, err := exec.Command("xdotool", "getactivewindow").Output()
+	if err != nil {
+		return 0, false
+	}
+
+	windowID, err := strconv.ParseUint(strings.TrimSpace(string(output)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return uintptr(windowID), true
+}
+
+// restoreForegroundWindowImpl activates a window saved by foregroundWindowImpl again.
+// It fails if the window was closed in the meantime
+func restoreForegroundWindowImpl(window uintptr, logger *zap.SugaredLogger) bool {
+	windowID := strconv.FormatUint(uint64(window), 10)
+	if err := exec.Command("xdotool", "windowactivate", "--sync", windowID).Run(); err != nil {
+		logger.Debugw("Failed to activate saved window", "window", windowID, "error", err)
+		return false
+	}
+
+	return true
+}
+
+// xdotoolSearch returns the id of the first visible window matching the search, or "" if there's none
+func xdotoolSearch(args ...string) string {
+	output, err := exec.Command("xdotool", append([]string{"search", "--onlyvisible"}, args...)...).Output()
+	if err != nil {
+		return ""
+	}
+
+	ids := strings.Fields(string(output))
+	if len(ids) == 0 {
+		return ""
+	}
+
+	return ids[0]
+}
+
 // executeActionPlatform executes an application using exec.CommandContext on Linux
 func executeActionPlatform(ctx context.Context, step *ActionStep, buttonID int, actionType string, key string, bh *ButtonHandler) error {
 	if step.Wait {
@@ -253,6 +399,20 @@ func setHideWindow(cmd *exec.Cmd) {
 	// No-op on Linux
 }
 
+// killProcessImpl sends SIGTERM to a process, or SIGKILL when force is set
+func killProcessImpl(pid int, force bool, logger *zap.SugaredLogger) error {
+	signal := syscall.SIGTERM
+	if force {
+		signal = syscall.SIGKILL
+	}
+
+	if err := syscall.Kill(pid, signal); err != nil {
+		return fmt.Errorf("send %s to pid %d: %w", signal, pid, err)
+	}
+
+	return nil
+}
+
 // terminateProcessHandleImpl terminates a process handle (Linux implementation - no-op)
 func terminateProcessHandleImpl(hProcess interface{}) error {
 	// On Linux, we don't use process handles, so this is a no-op
@@ -0,0 +1,73 @@
+//go:build linux
+// +build linux
+
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMissingActionDependencies(t *testing.T) {
+	keystroke := &ButtonActionConfig{Steps: []ActionStep{{Type: ActionTypeKeystroke, Keys: "ctrl+s"}}}
+	execute := &ButtonActionConfig{Steps: []ActionStep{{Type: ActionTypeExecute, App: "firefox"}}}
+
+	tests := []struct {
+		name      string
+		mapping   *ButtonsMapping
+		installed map[string]bool
+		want      string
+	}{
+		{"no config", nil, nil, "[]"},
+		{"xdotool missing", &ButtonsMapping{Buttons: map[int]*ButtonConfig{1: {Single: keystroke}}}, nil, "[xdotool]"},
+		{"xdotool installed", &ButtonsMapping{Buttons: map[int]*ButtonConfig{1: {Single: keystroke}}}, map[string]bool{"xdotool": true}, "[]"},
+		{"no step needs a tool", &ButtonsMapping{Buttons: map[int]*ButtonConfig{1: {Single: execute, Long: execute}}}, nil, "[]"},
+		{"reported once", &ButtonsMapping{Buttons: map[int]*ButtonConfig{1: {Single: keystroke}, 2: {Double: keystroke}}}, nil, "[xdotool]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookPath := func(file string) (string, error) {
+				if tt.installed[file] {
+					return "/usr/bin/" + file, nil
+				}
+				return "", errors.New("not found")
+			}
+
+			if got := fmt.Sprint(missingActionDependencies(tt.mapping, lookPath)); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBeepCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		step      ActionStep
+		installed map[string]bool
+		want      []string
+	}{
+		{"system sound with beep", ActionStep{Type: ActionTypeBeep}, map[string]bool{"beep": true, "paplay": true}, []string{"beep"}},
+		{"tone with beep", ActionStep{Type: ActionTypeBeep, Frequency: 880, Ms: 200}, map[string]bool{"beep": true}, []string{"beep", "-f", "880", "-l", "200"}},
+		{"tone with default length", ActionStep{Type: ActionTypeBeep, Frequency: 440}, map[string]bool{"beep": true}, []string{"beep", "-f", "440", "-l", "150"}},
+		{"paplay ignores the tone", ActionStep{Type: ActionTypeBeep, Frequency: 880}, map[string]bool{"paplay": true}, []string{"paplay", beepSoundFile}},
+		{"nothing installed", ActionStep{Type: ActionTypeBeep}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookPath := func(file string) (string, error) {
+				if tt.installed[file] {
+					return "/usr/bin/" + file, nil
+				}
+				return "", errors.New("not found")
+			}
+
+			if got := beepCommand(&tt.step, lookPath); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,515 @@
+package deej
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newTestButtonHandler builds a ButtonHandler that isn't wired to a device or a running deej,
+// and whose steps never reach the OS
+func newTestButtonHandler() *ButtonHandler {
+	return &ButtonHandler{
+		executor:         &fakeExecutor{},
+		logger:           zap.NewNop().Sugar(),
+		notifier:         testNotifier{},
+		runningActions:   make(map[string]context.CancelFunc),
+		trackedProcesses: make(map[string]*exec.Cmd),
+		trackedHandles:   make(map[string]interface{}),
+		armedActions:     make(map[string]time.Time),
+		holds:            make(map[int]*buttonHold),
+		holdReporting:    make(map[int]bool),
+	}
+}
+
+func TestReserveActionSlot(t *testing.T) {
+	tests := []struct {
+		name    string
+		max     int
+		presses int
+		want    int
+	}{
+		{"under the limit", 4, 3, 3},
+		{"at the limit", 4, 4, 4},
+		{"over the limit", 4, 50, 4},
+		{"no limit", 0, 50, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bh := &ButtonHandler{}
+
+			// every press arrives at once, as when buttons are mashed
+			var reserved atomic.Int32
+			var start, wg sync.WaitGroup
+			start.Add(1)
+			for i := 0; i < tt.presses; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					start.Wait()
+					if bh.reserveActionSlot(tt.max) {
+						reserved.Add(1)
+					}
+				}()
+			}
+			start.Done()
+			wg.Wait()
+
+			if int(reserved.Load()) != tt.want || int(bh.runningCount.Load()) != tt.want {
+				t.Errorf("reserved %d, running %d, want %d", reserved.Load(), bh.runningCount.Load(), tt.want)
+			}
+		})
+	}
+}
+
+func TestStartActionAfterShutdown(t *testing.T) {
+	bh := newTestButtonHandler()
+	if !bh.Shutdown(time.Second) {
+		t.Fatal("Shutdown timed out with nothing running")
+	}
+
+	bh.reserveActionSlot(0)
+	bh.startAction("1_single", 1, ButtonActionSingle, true, false, nil)
+
+	if running := bh.runningCount.Load(); running != 0 {
+		t.Errorf("running count after refused start: got %d, want 0", running)
+	}
+	if len(bh.runningActions) != 0 {
+		t.Errorf("refused action is tracked: %v", bh.runningActions)
+	}
+}
+
+func TestShutdownWhileStartingActions(t *testing.T) {
+	// actions starting while Shutdown waits must neither panic the WaitGroup nor outlive the wait
+	for i := 0; i < 50; i++ {
+		bh := newTestButtonHandler()
+
+		var wg sync.WaitGroup
+		for j := 0; j < 10; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				bh.reserveActionSlot(0)
+				bh.startAction("1_single", 1, ButtonActionSingle, true, false, nil)
+			}()
+		}
+
+		if !bh.Shutdown(time.Second) {
+			t.Fatal("Shutdown timed out")
+		}
+		wg.Wait()
+
+		if running := bh.runningCount.Load(); running != 0 {
+			t.Fatalf("running count after shutdown: got %d, want 0", running)
+		}
+	}
+}
+
+// blockingExecutor's execute and delay steps run until their action is cancelled, and report what they saw
+type blockingExecutor struct {
+	fakeExecutor
+	started  chan struct{}
+	canceled chan error
+}
+
+func (b *blockingExecutor) block(ctx context.Context) error {
+	b.started <- struct{}{}
+	<-ctx.Done()
+	b.canceled <- ctx.Err()
+	return ctx.Err()
+}
+
+func (b *blockingExecutor) Execute(ctx context.Context, step *ActionStep, buttonID int, actionType string, key string, bh *ButtonHandler) error {
+	return b.block(ctx)
+}
+
+func (b *blockingExecutor) Sleep(ctx context.Context, duration time.Duration) error {
+	return b.block(ctx)
+}
+
+func TestShutdownCancelsRunningAction(t *testing.T) {
+	const grace = time.Second
+
+	tests := []struct {
+		name string
+		step string
+	}{
+		{"execute step", "{type: execute, app: notepad.exe}"},
+		{"delay step", "{type: delay, ms: 60000}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &blockingExecutor{started: make(chan struct{}, 1), canceled: make(chan error, 1)}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+			bh.config = newTestConfig(t, singleActionYAML(tt.step)).ButtonsMapping.ToButtonsMapping()
+
+			if err := bh.HandleButtonPress(0, ButtonActionSingle); err != nil {
+				t.Fatalf("HandleButtonPress: %v", err)
+			}
+
+			select {
+			case <-executor.started:
+			case <-time.After(time.Second):
+				t.Fatal("step never started")
+			}
+			if running := bh.runningCount.Load(); running != 1 {
+				t.Fatalf("running count while the step runs: got %d, want 1", running)
+			}
+
+			started := time.Now()
+			if !bh.Shutdown(grace) {
+				t.Fatal("Shutdown timed out waiting for the cancelled action")
+			}
+			if elapsed := time.Since(started); elapsed >= grace {
+				t.Errorf("Shutdown took %v, want less than the %v grace period", elapsed, grace)
+			}
+
+			select {
+			case err := <-executor.canceled:
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("step saw %v, want context.Canceled", err)
+				}
+			default:
+				t.Error("step didn't see the cancellation")
+			}
+
+			if running := bh.runningCount.Load(); running != 0 {
+				t.Errorf("running count after shutdown: got %d, want 0", running)
+			}
+		})
+	}
+}
+
+func TestSilentActionFailure(t *testing.T) {
+	tests := []struct {
+		name         string
+		silent       bool
+		wantNotified int
+	}{
+		{"notifies by default", false, 1},
+		{"silent only logs", true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.WarnLevel)
+			notifier := &recordingNotifier{}
+			bh := newTestButtonHandler()
+			bh.logger = zap.New(core).Sugar()
+			bh.notifier = notifier
+
+			// the target window doesn't exist, so the step fails
+			steps := []ActionStep{{Type: ActionTypeKeystroke, Keys: "ctrl+s", Target: "missing.exe"}}
+			bh.reserveActionSlot(0)
+			bh.startAction("1_single", 1, ButtonActionSingle, tt.silent, false, steps)
+			bh.actionsWG.Wait()
+
+			if failures := logs.FilterMessage("Action execution failed").Len(); failures != 1 {
+				t.Errorf("got %d failure logs, want 1", failures)
+			}
+			if len(notifier.Titles()) != tt.wantNotified {
+				t.Errorf("got notifications %q, want %d", notifier.Titles(), tt.wantNotified)
+			}
+		})
+	}
+}
+
+func TestConfirmAction(t *testing.T) {
+	const window = 50 * time.Millisecond
+
+	tests := []struct {
+		name         string
+		confirm      bool
+		gaps         []time.Duration // pause before each press after the first
+		wantRuns     int
+		wantNotified int
+	}{
+		{"single press only arms", true, nil, 0, 1},
+		{"second press within window runs", true, []time.Duration{0}, 1, 1},
+		{"second press after window re-arms", true, []time.Duration{2 * window}, 0, 2},
+		{"third press arms again", true, []time.Duration{0, 0}, 1, 2},
+		{"without confirm every press runs", false, []time.Duration{0}, 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yaml := fmt.Sprintf("button_actions:\n  1:\n    single:\n      exclusive: false\n      confirm: %v\n      confirm_ms: %d\n      steps:\n        - type: beep\n",
+				tt.confirm, window.Milliseconds())
+
+			executor := &fakeExecutor{}
+			notifier := &recordingNotifier{}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+			bh.notifier = notifier
+			bh.config = newTestConfig(t, yaml).ButtonsMapping.ToButtonsMapping()
+
+			press := func() {
+				if err := bh.HandleButtonPress(1, ButtonActionSingle); err != nil {
+					t.Fatalf("HandleButtonPress: %v", err)
+				}
+				bh.actionsWG.Wait()
+			}
+
+			press()
+			for _, gap := range tt.gaps {
+				time.Sleep(gap)
+				press()
+			}
+
+			if len(executor.calls) != tt.wantRuns {
+				t.Errorf("action ran %d times (%q), want %d", len(executor.calls), executor.calls, tt.wantRuns)
+			}
+			if len(notifier.Titles()) != tt.wantNotified {
+				t.Errorf("got notifications %q, want %d", notifier.Titles(), tt.wantNotified)
+			}
+		})
+	}
+}
+
+func TestHandleConnect(t *testing.T) {
+	const onConnect = "on_connect:\n  steps:\n    - type: beep\n"
+
+	tests := []struct {
+		name     string
+		yaml     string
+		connects int
+		wantRuns int
+	}{
+		{"runs on the first connect", onConnect, 1, 1},
+		{"reconnects don't repeat it", onConnect, 3, 1},
+		{"every_connect repeats it", onConnect + "  every_connect: true\n", 3, 3},
+		{"nothing configured", "", 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &fakeExecutor{}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+			bh.config = newTestConfig(t, tt.yaml).ButtonsMapping.ToButtonsMapping()
+
+			for i := 0; i < tt.connects; i++ {
+				if err := bh.HandleConnect("serial"); err != nil {
+					t.Fatalf("HandleConnect: %v", err)
+				}
+				bh.actionsWG.Wait()
+			}
+
+			if len(executor.calls) != tt.wantRuns {
+				t.Errorf("on_connect ran %d times (%q), want %d", len(executor.calls), executor.calls, tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestStepTimings(t *testing.T) {
+	tests := []struct {
+		name        string
+		steps       []ActionStep
+		wantEntries []string // prefix of each step's timing entry
+		wantFailed  bool     // whether the last entry is marked as failed
+	}{
+		{
+			name:        "every step is timed",
+			steps:       []ActionStep{{Type: ActionTypeBeep}, {Type: ActionTypeDelay, Ms: 20}, {Type: ActionTypeBeep}},
+			wantEntries: []string{"0:beep ", "1:delay ", "2:beep "},
+		},
+		{
+			name:        "timing stops at the failed step",
+			steps:       []ActionStep{{Type: ActionTypeBeep}, {Type: "bogus"}, {Type: ActionTypeBeep}},
+			wantEntries: []string{"0:beep ", "1:bogus "},
+			wantFailed:  true,
+		},
+		{
+			name:  "no steps, no timings",
+			steps: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.InfoLevel)
+			bh := newTestButtonHandler()
+			bh.logger = zap.New(core).Sugar()
+
+			err := bh.executeAction(context.Background(), tt.steps, 1, ButtonActionSingle, "1_single", nil)
+			if (err != nil) != tt.wantFailed {
+				t.Fatalf("executeAction: got %v, want error %v", err, tt.wantFailed)
+			}
+
+			timingLogs := logs.FilterMessage("Action step timings").All()
+			if len(tt.wantEntries) == 0 {
+				if len(timingLogs) != 0 {
+					t.Fatalf("got %d timing logs, want none", len(timingLogs))
+				}
+				return
+			}
+			if len(timingLogs) != 1 {
+				t.Fatalf("got %d timing logs, want 1", len(timingLogs))
+			}
+
+			entries, _ := timingLogs[0].ContextMap()["steps"].([]interface{})
+			if len(entries) != len(tt.wantEntries) {
+				t.Fatalf("got timing entries %v, want %d", entries, len(tt.wantEntries))
+			}
+			for i, want := range tt.wantEntries {
+				entry := fmt.Sprint(entries[i])
+				if !strings.HasPrefix(entry, want) {
+					t.Errorf("entry %d = %q, want prefix %q", i, entry, want)
+				}
+				if failed := strings.HasSuffix(entry, " failed"); failed != (tt.wantFailed && i == len(tt.wantEntries)-1) {
+					t.Errorf("entry %d = %q, failed marker %v", i, entry, failed)
+				}
+			}
+		})
+	}
+}
+
+func TestCallStep(t *testing.T) {
+	action := func(button int, steps ...string) string {
+		return fmt.Sprintf("  %d:\n    single:\n      steps:\n        - %s\n", button, strings.Join(steps, "\n        - "))
+	}
+	call := func(button int) string {
+		return fmt.Sprintf("{type: call, button: %d, action: single}", button)
+	}
+
+	tests := []struct {
+		name      string
+		buttons   string
+		wantCalls []string
+		wantErr   bool
+	}{
+		{
+			name:      "runs the other button's steps inline",
+			buttons:   action(1, "{type: keystroke, keys: a}", call(2), "{type: keystroke, keys: c}") + action(2, "{type: keystroke, keys: b}"),
+			wantCalls: []string{"keystroke a", "keystroke b", "keystroke c"},
+		},
+		{
+			name:      "nested calls",
+			buttons:   action(1, call(2)) + action(2, call(3), "{type: keystroke, keys: b}") + action(3, "{type: keystroke, keys: c}"),
+			wantCalls: []string{"keystroke c", "keystroke b"},
+		},
+		{
+			name:      "same action called twice",
+			buttons:   action(1, call(2), call(2)) + action(2, "{type: beep}"),
+			wantCalls: []string{"beep", "beep"},
+		},
+		{
+			name:      "cycle is stopped",
+			buttons:   action(1, "{type: beep}", call(2)) + action(2, call(1)),
+			wantCalls: []string{"beep"},
+			wantErr:   true,
+		},
+		{
+			name:    "missing action",
+			buttons: action(1, call(5)),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &fakeExecutor{}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+			bh.config = newTestConfig(t, "button_actions:\n"+tt.buttons).ButtonsMapping.ToButtonsMapping()
+
+			steps := bh.config.Buttons[1].Single.Steps
+			err := bh.executeAction(context.Background(), steps, 1, ButtonActionSingle, "1_single", []string{"1_single"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("executeAction: got %v, want error %v", err, tt.wantErr)
+			}
+
+			if strings.Join(executor.calls, "; ") != strings.Join(tt.wantCalls, "; ") {
+				t.Errorf("calls: got %q, want %q", executor.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestMaxTypingLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     string
+		text      string
+		wantTyped string
+		wantWarns int
+	}{
+		{"under the limit", "max_typing_length: 5\n", "abc", "abc", 0},
+		{"at the limit", "max_typing_length: 5\n", "abcde", "abcde", 0},
+		{"over the limit is cut off", "max_typing_length: 5\n", "abcdefgh", "abcde", 1},
+		{"counts characters, not bytes", "max_typing_length: 3\n", "äöüß", "äöü", 1},
+		{"generous default", "", strings.Repeat("x", 200), strings.Repeat("x", 200), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.WarnLevel)
+			executor := &fakeExecutor{}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+			bh.logger = zap.New(core).Sugar()
+			bh.config = newTestConfig(t, tt.limit+singleActionYAML(fmt.Sprintf("{type: typing, text: %q}", tt.text))).ButtonsMapping.ToButtonsMapping()
+
+			if err := bh.executeAction(context.Background(), bh.config.Buttons[0].Single.Steps, 0, ButtonActionSingle, "0_single", nil); err != nil {
+				t.Fatalf("executeAction: %v", err)
+			}
+
+			if got := strings.Join(executor.calls, "; "); got != "typing "+tt.wantTyped {
+				t.Errorf("calls: got %q, want %q", got, "typing "+tt.wantTyped)
+			}
+			if got := logs.FilterMessageSnippet("max_typing_length").Len(); got != tt.wantWarns {
+				t.Errorf("got %d warnings, want %d", got, tt.wantWarns)
+			}
+		})
+	}
+}
+
+func TestRestoreFocus(t *testing.T) {
+	action := func(restoreFocus bool, steps string) string {
+		return fmt.Sprintf("button_actions:\n  1:\n    single:\n      restore_focus: %v\n      steps:\n        - %s\n", restoreFocus, steps)
+	}
+
+	tests := []struct {
+		name       string
+		yaml       string
+		foreground uintptr
+		wantCalls  []string
+	}{
+		{"restored after the steps", action(true, "{type: keystroke, keys: ctrl+c}"), 42, []string{"keystroke ctrl+c", "restore 42"}},
+		{"restored after a failed step", action(true, "{type: keystroke, keys: ctrl+c, target: notepad.exe}"), 42, []string{"focus notepad.exe", "restore 42"}},
+		{"off by default", action(false, "{type: keystroke, keys: ctrl+c}"), 42, []string{"keystroke ctrl+c"}},
+		{"no window to restore", action(true, "{type: keystroke, keys: ctrl+c}"), 0, []string{"keystroke ctrl+c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &fakeExecutor{foreground: tt.foreground}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+			bh.config = newTestConfig(t, tt.yaml).ButtonsMapping.ToButtonsMapping()
+
+			if err := bh.HandleButtonPress(1, ButtonActionSingle); err != nil {
+				t.Fatalf("HandleButtonPress: %v", err)
+			}
+			bh.actionsWG.Wait()
+
+			if strings.Join(executor.calls, "; ") != strings.Join(tt.wantCalls, "; ") {
+				t.Errorf("calls: got %q, want %q", executor.calls, tt.wantCalls)
+			}
+		})
+	}
+}
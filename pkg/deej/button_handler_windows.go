@@ -42,8 +42,14 @@ var (
 	procGetCurrentThreadId       = modkernel32.NewProc("GetCurrentThreadId")
 	procSendMessageTimeout       = moduser32.NewProc("SendMessageTimeoutW")
 	procSetErrorMode             = modkernel32.NewProc("SetErrorMode")
+	procBeep                     = modkernel32.NewProc("Beep")
+	procGetAsyncKeyState         = moduser32.NewProc("GetAsyncKeyState")
+	procIsWindow                 = moduser32.NewProc("IsWindow")
 )
 
+// actionToolDependencies lists the external tools each step type needs - everything is built in on Windows
+var actionToolDependencies = map[string][]string{}
+
 const (
 	KEYEVENTF_KEYUP          = 0x0002
 	KEYEVENTF_UNICODE        = 0x0004
@@ -90,6 +96,16 @@ func keystrokeActionImpl(ctx context.Context, step *ActionStep, logger *zap.Suga
 		return fmt.Errorf("invalid key combination: %s", step.Keys)
 	}
 
+	if step.Scancode {
+		return sendScanCodeKeys(keys, logger)
+	}
+
+	// Remember which modifiers the user is physically holding, so we don't release them afterwards
+	var held map[uintptr]bool
+	if step.PreserveModifiers {
+		held = snapshotModifiers()
+	}
+
 	// Press modifiers first
 	for i := 0; i < len(keys)-1; i++ {
 		k := strings.TrimSpace(strings.ToLower(keys[i]))
@@ -114,7 +130,7 @@ func keystrokeActionImpl(ctx context.Context, step *ActionStep, logger *zap.Suga
 	for i := len(keys) - 2; i >= 0; i-- {
 		k := strings.TrimSpace(strings.ToLower(keys[i]))
 		vk := getVirtualKeyCode(k)
-		if vk != 0 {
+		if vk != 0 && !held[uintptr(vk)] {
 			// Release modifier
 			procKeybdEvent.Call(uintptr(vk), 0, KEYEVENTF_KEYUP, 0)
 		}
@@ -123,6 +139,122 @@ func keystrokeActionImpl(ctx context.Context, step *ActionStep, logger *zap.Suga
 	return nil
 }
 
+// scanCode is a set 1 keyboard scan code; extended keys are sent with the E0 prefix
+type scanCode struct {
+	code     uint16
+	extended bool
+}
+
+// scanCodes maps key names to scan codes for keystroke steps with scancode: true.
+// Games reading DirectInput only see these, not the virtual keys keybd_event sends
+var scanCodes = map[string]scanCode{
+	"ctrl": {0x1D, false}, "control": {0x1D, false},
+	"alt":   {0x38, false},
+	"shift": {0x2A, false},
+	"win":   {0x5B, true}, "windows": {0x5B, true}, "meta": {0x5B, true},
+	"esc": {0x01, false}, "escape": {0x01, false},
+	"1": {0x02, false}, "2": {0x03, false}, "3": {0x04, false}, "4": {0x05, false}, "5": {0x06, false},
+	"6": {0x07, false}, "7": {0x08, false}, "8": {0x09, false}, "9": {0x0A, false}, "0": {0x0B, false},
+	"-": {0x0C, false}, "=": {0x0D, false},
+	"backspace": {0x0E, false},
+	"tab":       {0x0F, false},
+	"q":         {0x10, false}, "w": {0x11, false}, "e": {0x12, false}, "r": {0x13, false}, "t": {0x14, false},
+	"y": {0x15, false}, "u": {0x16, false}, "i": {0x17, false}, "o": {0x18, false}, "p": {0x19, false},
+	"[": {0x1A, false}, "]": {0x1B, false},
+	"enter": {0x1C, false}, "return": {0x1C, false},
+	"a": {0x1E, false}, "s": {0x1F, false}, "d": {0x20, false}, "f": {0x21, false}, "g": {0x22, false},
+	"h": {0x23, false}, "j": {0x24, false}, "k": {0x25, false}, "l": {0x26, false},
+	";": {0x27, false}, "'": {0x28, false}, "`": {0x29, false}, "\\": {0x2B, false},
+	"z": {0x2C, false}, "x": {0x2D, false}, "c": {0x2E, false}, "v": {0x2F, false}, "b": {0x30, false},
+	"n": {0x31, false}, "m": {0x32, false},
+	",": {0x33, false}, ".": {0x34, false}, "/": {0x35, false},
+	"space":    {0x39, false},
+	"capslock": {0x3A, false}, "caps": {0x3A, false},
+	"f1": {0x3B, false}, "f2": {0x3C, false}, "f3": {0x3D, false}, "f4": {0x3E, false}, "f5": {0x3F, false},
+	"f6": {0x40, false}, "f7": {0x41, false}, "f8": {0x42, false}, "f9": {0x43, false}, "f10": {0x44, false},
+	"f11": {0x57, false}, "f12": {0x58, false},
+	"numlock": {0x45, false}, "num": {0x45, false},
+	"scrolllock": {0x46, false}, "scroll": {0x46, false},
+	"numpad7": {0x47, false}, "np7": {0x47, false},
+	"numpad8": {0x48, false}, "np8": {0x48, false},
+	"numpad9": {0x49, false}, "np9": {0x49, false},
+	"numpad4": {0x4B, false}, "np4": {0x4B, false},
+	"numpad5": {0x4C, false}, "np5": {0x4C, false},
+	"numpad6": {0x4D, false}, "np6": {0x4D, false},
+	"numpad1": {0x4F, false}, "np1": {0x4F, false},
+	"numpad2": {0x50, false}, "np2": {0x50, false},
+	"numpad3": {0x51, false}, "np3": {0x51, false},
+	"numpad0": {0x52, false}, "np0": {0x52, false},
+	"numpadmultiply": {0x37, false}, "numpad*": {0x37, false}, "np*": {0x37, false}, "npmultiply": {0x37, false},
+	"numpadsubtract": {0x4A, false}, "numpad-": {0x4A, false}, "np-": {0x4A, false}, "npsubtract": {0x4A, false},
+	"numpadadd": {0x4E, false}, "numpad+": {0x4E, false}, "np+": {0x4E, false}, "npadd": {0x4E, false},
+	"numpaddecimal": {0x53, false}, "numpad.": {0x53, false}, "np.": {0x53, false}, "npdecimal": {0x53, false},
+	"numpaddivide": {0x35, true}, "numpad/": {0x35, true}, "np/": {0x35, true}, "npdivide": {0x35, true},
+	"numpadenter": {0x1C, true}, "npenter": {0x1C, true},
+	"insert": {0x52, true}, "ins": {0x52, true},
+	"delete": {0x53, true}, "del": {0x53, true},
+	"home":   {0x47, true},
+	"end":    {0x4F, true},
+	"pageup": {0x49, true}, "pgup": {0x49, true},
+	"pagedown": {0x51, true}, "pgdn": {0x51, true},
+	"up":    {0x48, true},
+	"down":  {0x50, true},
+	"left":  {0x4B, true},
+	"right": {0x4D, true},
+	"menu":  {0x5D, true}, "contextmenu": {0x5D, true}, "apps": {0x5D, true},
+}
+
+// sendScanCodeKeys presses a key combination using scan codes via SendInput.
+// Every key is resolved up front so an unknown name doesn't leave modifiers held down
+func sendScanCodeKeys(keys []string, logger *zap.SugaredLogger) error {
+	codes := make([]scanCode, 0, len(keys))
+	for _, k := range keys {
+		code, ok := scanCodeForKey(k)
+		if !ok {
+			return fmt.Errorf("no scan code for key: %s", k)
+		}
+		codes = append(codes, code)
+	}
+
+	logger.Debugw("Sending keystroke as scan codes", "keys", keys)
+
+	// Press in order, release in reverse order
+	for _, code := range codes {
+		sendScanCode(code, false)
+	}
+	for i := len(codes) - 1; i >= 0; i-- {
+		sendScanCode(codes[i], true)
+	}
+
+	return nil
+}
+
+// scanCodeForKey looks up a key name from a keystroke step, ignoring case and surrounding spaces
+func scanCodeForKey(key string) (scanCode, bool) {
+	code, ok := scanCodes[strings.ToLower(strings.TrimSpace(key))]
+	return code, ok
+}
+
+// sendScanCode sends a single scan code press or release
+func sendScanCode(code scanCode, release bool) {
+	flags := uint32(win.KEYEVENTF_SCANCODE)
+	if code.extended {
+		flags |= win.KEYEVENTF_EXTENDEDKEY
+	}
+	if release {
+		flags |= win.KEYEVENTF_KEYUP
+	}
+
+	input := win.KEYBD_INPUT{
+		Type: win.INPUT_KEYBOARD,
+		Ki: win.KEYBDINPUT{
+			WScan:   code.code,
+			DwFlags: flags,
+		},
+	}
+	win.SendInput(1, unsafe.Pointer(&input), int32(unsafe.Sizeof(input)))
+}
+
 // getVirtualKeyCode returns Windows virtual key code for a key name
 func getVirtualKeyCode(keyName string) uintptr {
 	keyLower := strings.ToLower(keyName)
@@ -286,6 +418,31 @@ func getVirtualKeyCode(keyName string) uintptr {
 	}
 }
 
+// beepActionImpl plays the default system sound, or a tone of the step's frequency via Beep
+func beepActionImpl(ctx context.Context, step *ActionStep, logger *zap.SugaredLogger) error {
+	if step.Frequency == 0 {
+		logger.Debugw("Playing system beep")
+		if !win.MessageBeep(win.MB_OK) {
+			return fmt.Errorf("MessageBeep failed")
+		}
+		return nil
+	}
+
+	duration := step.Ms
+	if duration == 0 {
+		duration = beepDefaultDurationMs
+	}
+
+	logger.Debugw("Playing beep", "frequency", step.Frequency, "ms", duration)
+
+	// Beep blocks for the whole tone, which is capped by validation
+	if ret, _, err := procBeep.Call(uintptr(step.Frequency), uintptr(duration)); ret == 0 {
+		return fmt.Errorf("Beep failed: %w", err)
+	}
+
+	return nil
+}
+
 // typingActionImpl implements text typing simulation for Windows using keybd_event with KEYEVENTF_UNICODE
 func typingActionImpl(ctx context.Context, step *ActionStep, logger *zap.SugaredLogger) error {
 	// Get current foreground window for debugging and ensure it's focused
@@ -370,6 +527,12 @@ func typingActionImpl(ctx context.Context, step *ActionStep, logger *zap.Sugared
 		logger.Warnw("No foreground window found, typing may not work correctly")
 	}
 
+	// Remember which modifiers the user is physically holding, so we don't release them afterwards
+	var held map[uintptr]bool
+	if step.PreserveModifiers {
+		held = snapshotModifiers()
+	}
+
 	// Ensure we detach thread input when done
 	defer func() {
 		if inputAttached && targetThreadID != currentThreadID {
@@ -450,20 +613,61 @@ func typingActionImpl(ctx context.Context, step *ActionStep, logger *zap.Sugared
 		}
 	}
 
-	// Ensure all modifier keys are released at the end
+	// Ensure modifier keys are released at the end (except ones the user was holding)
 	// This prevents issues where modifiers might be stuck
-	modifiers := []uintptr{0x10, 0x11, 0x12, 0x5B, 0x5C} // VK_SHIFT, VK_CONTROL, VK_MENU, VK_LWIN, VK_RWIN
-
-	for _, vk := range modifiers {
-		procKeybdEvent.Call(vk, 0, KEYEVENTF_KEYUP, 0)
-		time.Sleep(5 * time.Millisecond) // Small delay between each modifier
-	}
+	releaseModifiers(held, logger)
 
 	time.Sleep(10 * time.Millisecond) // Small delay after releasing modifiers
 
 	return nil
 }
 
+// modifierKeys are the modifier virtual keys released after typing: VK_SHIFT, VK_CONTROL, VK_MENU, VK_LWIN, VK_RWIN
+var modifierKeys = []uintptr{0x10, 0x11, 0x12, 0x5B, 0x5C}
+
+// snapshotModifiers returns the modifier keys that are currently held down on the real keyboard
+func snapshotModifiers() map[uintptr]bool {
+	return heldModifiers(func(vk uintptr) uintptr {
+		state, _, _ := procGetAsyncKeyState.Call(vk)
+		return state
+	})
+}
+
+// heldModifiers returns the modifier keys whose GetAsyncKeyState-style state says they're down
+func heldModifiers(keyState func(vk uintptr) uintptr) map[uintptr]bool {
+	held := make(map[uintptr]bool)
+	for _, vk := range modifierKeys {
+		// The most significant bit of GetAsyncKeyState's result is set while the key is down
+		if keyState(vk)&0x8000 != 0 {
+			held[vk] = true
+		}
+	}
+	return held
+}
+
+// modifiersToRelease returns the modifier keys deej may release, leaving out the ones in held
+func modifiersToRelease(held map[uintptr]bool) []uintptr {
+	release := make([]uintptr, 0, len(modifierKeys))
+	for _, vk := range modifierKeys {
+		if !held[vk] {
+			release = append(release, vk)
+		}
+	}
+	return release
+}
+
+// releaseModifiers sends key-up events for all modifier keys except the ones in held
+func releaseModifiers(held map[uintptr]bool, logger *zap.SugaredLogger) {
+	if len(held) > 0 {
+		logger.Debugw("Leaving modifiers held by the user pressed", "count", len(held))
+	}
+
+	for _, vk := range modifiersToRelease(held) {
+		procKeybdEvent.Call(vk, 0, KEYEVENTF_KEYUP, 0)
+		time.Sleep(5 * time.Millisecond) // Small delay between each modifier
+	}
+}
+
 // sendVirtualKey sends a virtual key press and release
 func sendVirtualKey(vk uintptr) {
 	// Press
@@ -532,6 +736,72 @@ func findWindowByPID(pid int, titleFilter string, logger *zap.SugaredLogger) win
 	return foundWindow
 }
 
+// focusProcessWindowImpl brings the main window of the given process to the foreground
+func focusProcessWindowImpl(pid int, logger *zap.SugaredLogger) bool {
+	return setWindowFocus(findWindowByPID(pid, "", logger), logger)
+}
+
+// focusTargetWindowImpl focuses a window of one of the given processes, or else the first visible window
+// whose title contains title, and reports whether it actually became the foreground window
+func focusTargetWindowImpl(pids []int, title string, logger *zap.SugaredLogger) bool {
+	var hwnd win.HWND
+	for _, pid := range pids {
+		if hwnd = findWindowByPID(pid, "", logger); hwnd != 0 {
+			break
+		}
+	}
+
+	if hwnd == 0 {
+		hwnd = findWindowByTitle(title)
+	}
+
+	if !setWindowFocus(hwnd, logger) {
+		return false
+	}
+
+	fgHwnd, _, _ := procGetForegroundWindow.Call()
+	return win.HWND(fgHwnd) == hwnd
+}
+
+// foregroundWindowImpl returns the current foreground window, for restore_focus
+func foregroundWindowImpl() (uintptr, bool) {
+	fgHwnd, _, _ := procGetForegroundWindow.Call()
+	return fgHwnd, fgHwnd != 0
+}
+
+// restoreForegroundWindowImpl brings a window saved by foregroundWindowImpl back to the foreground.
+// It fails if the window was closed in the meantime
+func restoreForegroundWindowImpl(window uintptr, logger *zap.SugaredLogger) bool {
+	if exists, _, _ := procIsWindow.Call(window); exists == 0 {
+		return false
+	}
+
+	return setWindowFocus(win.HWND(window), logger)
+}
+
+// findWindowByTitle finds the first visible top-level window whose title contains title (case-insensitive)
+func findWindowByTitle(title string) win.HWND {
+	if title == "" {
+		return 0
+	}
+
+	var foundWindow win.HWND
+	titleLower := strings.ToLower(title)
+
+	enumProc := syscall.NewCallback(func(hwnd win.HWND, lParam uintptr) uintptr {
+		if win.IsWindowVisible(hwnd) && strings.Contains(strings.ToLower(getWindowTitle(hwnd)), titleLower) {
+			foundWindow = hwnd
+			return 0 // Stop enumeration
+		}
+		return 1 // Continue enumeration
+	})
+
+	procEnumWindows := moduser32.NewProc("EnumWindows")
+	procEnumWindows.Call(uintptr(enumProc), 0)
+
+	return foundWindow
+}
+
 // getWindowTitle retrieves the title of a window
 func getWindowTitle(hwnd win.HWND) string {
 	moduser32 := syscall.NewLazyDLL("user32.dll")
@@ -987,6 +1257,26 @@ func terminateProcess(hProcess syscall.Handle) error {
 	return nil
 }
 
+// killProcessImpl asks a process to close by sending WM_CLOSE to its main window, or terminates it
+// right away when force is set (or it has no window to close)
+func killProcessImpl(pid int, force bool, logger *zap.SugaredLogger) error {
+	if !force {
+		if hwnd := findWindowByPID(pid, "", logger); hwnd != 0 {
+			win.PostMessage(hwnd, win.WM_CLOSE, 0, 0)
+			return nil
+		}
+		logger.Debugw("Process has no window to close, terminating it", "pid", pid)
+	}
+
+	hProcess, err := syscall.OpenProcess(syscall.PROCESS_TERMINATE, false, uint32(pid))
+	if err != nil {
+		return fmt.Errorf("open process %d: %w", pid, err)
+	}
+	defer closeHandle(hProcess)
+
+	return terminateProcess(hProcess)
+}
+
 // terminateProcessHandleImpl terminates a process handle (Windows implementation)
 func terminateProcessHandleImpl(hProcess interface{}) error {
 	if handle, ok := hProcess.(syscall.Handle); ok {
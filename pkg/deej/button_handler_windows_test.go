@@ -0,0 +1,70 @@
+//go:build windows
+// +build windows
+
+package deej
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestScanCodeForKey(t *testing.T) {
+	tests := []struct {
+		key    string
+		want   scanCode
+		wantOk bool
+	}{
+		{"a", scanCode{0x1E, false}, true},
+		{" A ", scanCode{0x1E, false}, true},
+		{"ctrl", scanCode{0x1D, false}, true},
+		{"Control", scanCode{0x1D, false}, true},
+		{"f12", scanCode{0x58, false}, true},
+		{"enter", scanCode{0x1C, false}, true},
+		{"numpadenter", scanCode{0x1C, true}, true},
+		{"up", scanCode{0x48, true}, true},
+		{"np8", scanCode{0x48, false}, true},
+		{"win", scanCode{0x5B, true}, true},
+		{"volumeup", scanCode{}, false},
+		{"", scanCode{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := scanCodeForKey(tt.key)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("got %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestModifierBookkeeping(t *testing.T) {
+	const (
+		vkShift   = 0x10
+		vkControl = 0x11
+		vkMenu    = 0x12
+		vkLWin    = 0x5B
+		vkRWin    = 0x5C
+	)
+
+	tests := []struct {
+		name        string
+		down        map[uintptr]uintptr // GetAsyncKeyState results, 0 for anything missing
+		wantRelease []uintptr
+	}{
+		{"nothing held", nil, []uintptr{vkShift, vkControl, vkMenu, vkLWin, vkRWin}},
+		{"user holds shift", map[uintptr]uintptr{vkShift: 0x8000}, []uintptr{vkControl, vkMenu, vkLWin, vkRWin}},
+		{"user holds ctrl and win", map[uintptr]uintptr{vkControl: 0x8001, vkLWin: 0x8000}, []uintptr{vkShift, vkMenu, vkRWin}},
+		{"pressed since last call isn't held", map[uintptr]uintptr{vkShift: 0x0001}, []uintptr{vkShift, vkControl, vkMenu, vkLWin, vkRWin}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			held := heldModifiers(func(vk uintptr) uintptr { return tt.down[vk] })
+
+			if got := modifiersToRelease(held); fmt.Sprint(got) != fmt.Sprint(tt.wantRelease) {
+				t.Errorf("released %v, want %v", got, tt.wantRelease)
+			}
+		})
+	}
+}
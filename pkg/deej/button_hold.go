@@ -0,0 +1,137 @@
+package deej
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Raw button events, for firmware that reports presses and releases besides its gestures ("3_down", "3_up")
+const (
+	ButtonEventDown = "down"
+	ButtonEventUp   = "up"
+)
+
+// How many progress commands a timed long press sends before its action fires
+const holdProgressSteps = 10
+
+// buttonHold is a long press deej is timing for a button that's being held
+type buttonHold struct {
+	released chan struct{} // closed when the button goes up
+}
+
+// holdTimedAction returns a button's long action if deej times it from down/up events (hold_ms is set)
+func (bh *ButtonHandler) holdTimedAction(buttonID int) (*ButtonActionConfig, bool) {
+	bh.configMutex.RLock()
+	defer bh.configMutex.RUnlock()
+
+	if bh.config == nil {
+		return nil, false
+	}
+
+	buttonConfig, ok := bh.config.Buttons[buttonID]
+	if !ok || buttonConfig.Long == nil || buttonConfig.Long.HoldMs <= 0 {
+		return nil, false
+	}
+
+	return buttonConfig.Long, true
+}
+
+// deviceLongPressIgnored reports whether the device's own long press event for a button is left out,
+// because deej times that button's long press itself. Only once the button actually reported a down
+// event, so firmware that can't report them keeps working as before
+func (bh *ButtonHandler) deviceLongPressIgnored(buttonID int) bool {
+	if _, ok := bh.holdTimedAction(buttonID); !ok {
+		return false
+	}
+
+	bh.holdsMutex.Lock()
+	defer bh.holdsMutex.Unlock()
+
+	return bh.holdReporting[buttonID]
+}
+
+// HandleButtonHold tracks a button going down or up. For long actions with hold_ms, holding the button
+// sends the progress command to the device as the hold goes on, and runs the action once it completes.
+// Releasing the button early cancels it and sends progress_clear
+func (bh *ButtonHandler) HandleButtonHold(buttonID int, down bool) error {
+	if bh.shuttingDown.Load() {
+		return nil
+	}
+
+	longAction, ok := bh.holdTimedAction(buttonID)
+	if !ok {
+		bh.logger.Debugw("Button has no timed long press, ignoring hold event", "button", buttonID, "down", down)
+		return nil
+	}
+
+	bh.holdsMutex.Lock()
+	defer bh.holdsMutex.Unlock()
+
+	bh.holdReporting[buttonID] = true
+
+	hold, holding := bh.holds[buttonID]
+
+	if !down {
+		if holding {
+			delete(bh.holds, buttonID)
+			close(hold.released)
+		}
+		return nil
+	}
+
+	// a repeated down event while held, the hold is already running
+	if holding {
+		return nil
+	}
+
+	hold = &buttonHold{released: make(chan struct{})}
+	bh.holds[buttonID] = hold
+
+	go bh.runHold(buttonID, hold, longAction.HoldMs, longAction.Progress, longAction.ProgressClear)
+
+	return nil
+}
+
+// runHold sends progress in holdProgressSteps steps over holdMs and runs the long action at 100%.
+// The progress is cleared when the button is released, whether the action ran or not
+func (bh *ButtonHandler) runHold(buttonID int, hold *buttonHold, holdMs int, progress string, progressClear string) {
+	ticker := time.NewTicker(time.Duration(holdMs) * time.Millisecond / holdProgressSteps)
+	defer ticker.Stop()
+
+	for step := 1; step <= holdProgressSteps; step++ {
+		select {
+		case <-hold.released:
+			bh.logger.Debugw("Button released before the long press completed", "button", buttonID, "progress", (step-1)*100/holdProgressSteps)
+			bh.sendHoldProgress(progressClear, buttonID, 0)
+			return
+		case <-ticker.C:
+		}
+
+		bh.sendHoldProgress(progress, buttonID, step*100/holdProgressSteps)
+	}
+
+	if err := bh.pressButton(buttonID, ButtonActionLong); err != nil {
+		bh.logger.Warnw("Failed to run timed long press", "button", buttonID, "error", err)
+	}
+
+	<-hold.released
+	bh.sendHoldProgress(progressClear, buttonID, 0)
+}
+
+// sendHoldProgress writes a progress or progress_clear command to the device, with {{button}}
+// and {{percent}} filled in
+func (bh *ButtonHandler) sendHoldProgress(command string, buttonID int, percent int) {
+	if command == "" || bh.writeToDevice == nil {
+		return
+	}
+
+	command = strings.NewReplacer(
+		"{{button}}", strconv.Itoa(buttonID),
+		"{{percent}}", strconv.Itoa(percent),
+	).Replace(command)
+
+	if err := bh.writeToDevice([]byte(command)); err != nil {
+		bh.logger.Debugw("Failed to send long press progress to device", "button", buttonID, "percent", percent, "error", err)
+	}
+}
@@ -0,0 +1,115 @@
+package deej
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// deviceWrites collects what a ButtonHandler writes back to the device, from any goroutine
+type deviceWrites struct {
+	mu      sync.Mutex
+	written []string
+}
+
+func (w *deviceWrites) write(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.written = append(w.written, string(data))
+	return nil
+}
+
+func (w *deviceWrites) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return strings.Join(w.written, " ")
+}
+
+// waitFor polls until the writes end with suffix, or fails the test after a second
+func (w *deviceWrites) waitFor(t *testing.T, suffix string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.HasSuffix(w.String(), suffix) {
+		if time.Now().After(deadline) {
+			t.Fatalf("device writes %q never ended with %q", w.String(), suffix)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestButtonHold(t *testing.T) {
+	const yaml = `button_actions:
+  1:
+    long:
+      hold_ms: 100
+      progress: 'p{{button}}:{{percent}}'
+      progress_clear: 'clear{{button}}'
+      steps:
+        - {type: beep}
+`
+
+	allProgress := "p1:10 p1:20 p1:30 p1:40 p1:50 p1:60 p1:70 p1:80 p1:90 p1:100"
+
+	tests := []struct {
+		name        string
+		holdFor     time.Duration // 0 holds until the progress is complete
+		wantWritten string        // what the writes start with
+		wantRuns    int
+	}{
+		{"held to completion", 0, allProgress + " clear1", 1},
+		{"released early", 35 * time.Millisecond, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &fakeExecutor{}
+			writes := &deviceWrites{}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+			bh.writeToDevice = writes.write
+			bh.config = newTestConfig(t, yaml).ButtonsMapping.ToButtonsMapping()
+
+			if err := bh.HandleButtonHold(1, true); err != nil {
+				t.Fatalf("HandleButtonHold down: %v", err)
+			}
+			// a repeated down event while held doesn't start another hold
+			if err := bh.HandleButtonHold(1, true); err != nil {
+				t.Fatalf("HandleButtonHold down: %v", err)
+			}
+
+			if tt.holdFor > 0 {
+				time.Sleep(tt.holdFor)
+			} else {
+				writes.waitFor(t, "p1:100")
+			}
+
+			if err := bh.HandleButtonHold(1, false); err != nil {
+				t.Fatalf("HandleButtonHold up: %v", err)
+			}
+			writes.waitFor(t, "clear1")
+
+			// long enough for a hold that wasn't cancelled to finish
+			time.Sleep(150 * time.Millisecond)
+			bh.actionsWG.Wait()
+
+			if got := writes.String(); !strings.HasPrefix(got, tt.wantWritten) || strings.Count(got, "clear1") != 1 {
+				t.Errorf("device writes: got %q, want %q... with a single clear", got, tt.wantWritten)
+			}
+			if tt.holdFor > 0 && strings.Contains(writes.String(), "p1:100") {
+				t.Errorf("device writes %q reached 100%% after an early release", writes.String())
+			}
+			if len(executor.calls) != tt.wantRuns {
+				t.Errorf("action ran %d times, want %d", len(executor.calls), tt.wantRuns)
+			}
+
+			// the device reports down events, so its own long press gesture is left to deej
+			if !bh.deviceLongPressIgnored(1) {
+				t.Error("device long press isn't ignored for a button timed by hold_ms")
+			}
+		})
+	}
+}
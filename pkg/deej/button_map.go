@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
@@ -16,18 +19,67 @@ const (
 	ButtonActionLong   = "long"
 )
 
+// ActionOnConnect names the top-level on_connect action, which runs when a device connects
+const ActionOnConnect = "on_connect"
+
+// How long a confirm action stays armed after its first press
+const defaultConfirmWindowMs = 3000
+
+// inherit_mode values: where an action's own steps go relative to the inherited ones
+const (
+	InheritModeAppend  = "append"
+	InheritModePrepend = "prepend"
+)
+
 // Action step types
 const (
-	ActionTypeExecute   = "execute"
-	ActionTypeDelay     = "delay"
-	ActionTypeKeystroke = "keystroke"
-	ActionTypeTyping    = "typing"
+	ActionTypeExecute     = "execute"
+	ActionTypeDelay       = "delay"
+	ActionTypeKeystroke   = "keystroke"
+	ActionTypeTyping      = "typing"
+	ActionTypeWaitProcess = "wait_process"
+	ActionTypeBeep        = "beep"
+	ActionTypeKill        = "kill"
+	ActionTypeCall        = "call"
+)
+
+// execute if_running modes
+const (
+	IfRunningLaunch = "launch"
+	IfRunningFocus  = "focus"
+	IfRunningSkip   = "skip"
+)
+
+// wait_process modes
+const (
+	WaitProcessModeStart = "start"
+	WaitProcessModeExit  = "exit"
+)
+
+// How deeply call steps may nest, so a long chain of calls can't run away
+const maxActionCallDepth = 8
+
+// beep step limits - the frequency range is what the Windows Beep API accepts
+const (
+	beepMinFrequency      = 37
+	beepMaxFrequency      = 32767
+	beepMaxDurationMs     = 5000
+	beepDefaultDurationMs = 150
 )
 
 // ButtonActionConfig represents configuration for a single action type (single/double/long)
 type ButtonActionConfig struct {
-	Exclusive bool         `json:"exclusive"` // Default: true
-	Steps     []ActionStep `json:"steps"`
+	Exclusive     bool         `json:"exclusive"`                // Default: true
+	Silent        bool         `json:"silent,omitempty"`         // Don't notify the user when the action fails (still logged)
+	Inherit       string       `json:"inherit,omitempty"`        // Action type (single/double/long) of the same button whose steps are reused
+	InheritMode   string       `json:"inherit_mode,omitempty"`   // append (own steps run after the inherited ones, default) or prepend
+	Confirm       bool         `json:"confirm,omitempty"`        // Require a second press within ConfirmMs before running
+	ConfirmMs     int          `json:"confirm_ms,omitempty"`     // Confirmation window in milliseconds (default: 3000)
+	RestoreFocus  bool         `json:"restore_focus,omitempty"`  // Focus the window that was in the foreground again once the steps are done
+	HoldMs        int          `json:"hold_ms,omitempty"`        // Long only: deej times the hold from down/up events, firing after this many milliseconds
+	Progress      string       `json:"progress,omitempty"`       // Long only, with hold_ms: device command sent as the hold progresses
+	ProgressClear string       `json:"progress_clear,omitempty"` // Long only, with hold_ms: device command sent when the button is released
+	Steps         []ActionStep `json:"steps"`
 }
 
 // WaitWnd represents window waiting configuration for execute action
@@ -39,16 +91,31 @@ type WaitWnd struct {
 
 // ActionStep represents a single step in an action sequence
 type ActionStep struct {
-	Type        string   `json:"type"` // execute, delay, keystroke, typing
-	App         string   `json:"app,omitempty"`
-	Args        []string `json:"args,omitempty"`
-	Wait        bool     `json:"wait,omitempty"`         // For execute: wait for completion
-	WaitTimeout int      `json:"wait_timeout,omitempty"` // For execute: timeout in milliseconds (0 = infinite, default: 0)
-	WaitWnd     *WaitWnd `json:"wait_wnd,omitempty"`     // For execute: wait for window (only with wait: false)
-	Ms          int      `json:"ms,omitempty"`           // For delay: duration in milliseconds
-	Keys        string   `json:"keys,omitempty"`         // For keystroke: key combination
-	Text        string   `json:"text,omitempty"`         // For typing: text to type
-	CharDelay   int      `json:"char_delay,omitempty"`   // For typing: delay between characters in milliseconds (optional)
+	Type              string   `json:"type"` // execute, delay, keystroke, typing, wait_process, beep, kill, call (see knownActionType)
+	App               string   `json:"app,omitempty"`
+	Args              []string `json:"args,omitempty"`
+	Wait              bool     `json:"wait,omitempty"`               // For execute: wait for completion
+	WaitTimeout       int      `json:"wait_timeout,omitempty"`       // For execute: timeout in milliseconds (0 = infinite, default: 0)
+	WaitWnd           *WaitWnd `json:"wait_wnd,omitempty"`           // For execute: wait for window (only with wait: false)
+	IfRunning         string   `json:"if_running,omitempty"`         // For execute: launch, focus or skip when the app is already running (default: launch)
+	SettleMs          int      `json:"settle_ms,omitempty"`          // For execute: extra pause after launch (and after wait_wnd) in milliseconds (optional)
+	Target            string   `json:"target,omitempty"`             // For keystroke/typing: process name or window title to focus first (optional)
+	Scancode          bool     `json:"scancode,omitempty"`           // For keystroke: send hardware scan codes instead of virtual keys (optional)
+	PreserveModifiers bool     `json:"preserve_modifiers,omitempty"` // For keystroke/typing: don't release modifiers the user is holding (Windows, optional)
+	Ms                int      `json:"ms,omitempty"`                 // For delay: duration in milliseconds. For beep: tone length (optional)
+	Duration          string   `json:"duration,omitempty"`           // For delay: duration like "1.5s" or "250ms", takes precedence over ms (optional)
+	Keys              string   `json:"keys,omitempty"`               // For keystroke: key combination
+	Text              string   `json:"text,omitempty"`               // For typing: text to type
+	CharDelay         int      `json:"char_delay,omitempty"`         // For typing: delay between characters in milliseconds (optional)
+	TextFile          string   `json:"text_file,omitempty"`          // For typing: file to read the text from at execution time, instead of text
+	Escapes           bool     `json:"escapes,omitempty"`            // For typing with text_file: process \n, \t, \r and \\ in the file (default: false)
+	ProcessName       string   `json:"process,omitempty"`            // For wait_process/kill: process name to wait for or terminate
+	Mode              string   `json:"mode,omitempty"`               // For wait_process: start or exit
+	Timeout           int      `json:"timeout,omitempty"`            // For wait_process: timeout in milliseconds (0 = infinite, default: 0)
+	Frequency         int      `json:"frequency,omitempty"`          // For beep: tone frequency in Hz (optional, default: system sound)
+	Force             bool     `json:"force,omitempty"`              // For kill: terminate immediately instead of asking the app to close (optional)
+	Button            int      `json:"button,omitempty"`             // For call: button whose action is run
+	Action            string   `json:"action,omitempty"`             // For call: which of that button's actions (single/double/long)
 }
 
 // ButtonConfig represents configuration for a single button
@@ -60,16 +127,30 @@ type ButtonConfig struct {
 
 // ButtonsMapping represents the complete button actions configuration
 type ButtonsMapping struct {
-	CancelOnReload bool                  `json:"cancel_on_reload"` // Default: false
-	Buttons        map[int]*ButtonConfig `json:"buttons"`
-	logger         *zap.SugaredLogger
+	CancelOnReload    bool                  `json:"cancel_on_reload"` // Default: false
+	DisabledActions   map[string]bool       `json:"disabled_actions"` // Step types that are never run, from the top-level disabled_actions
+	Buttons           map[int]*ButtonConfig `json:"buttons"`
+	OnConnect         *ButtonActionConfig   `json:"on_connect,omitempty"`   // Runs when a device connects, from the top-level on_connect
+	OnConnectEvery    bool                  `json:"every_connect"`          // Run on_connect on every reconnect, not just the first connection
+	MaxTypingLength   int                   `json:"max_typing_length"`      // Typing steps are cut off after this many characters, from the top-level max_typing_length
+	MaxConcurrent     int                   `json:"max_concurrent_actions"` // Button presses are ignored while this many actions run (0 = no limit), from the top-level max_concurrent_actions
+	FocusAssistPause  bool                  `json:"pause_in_focus_assist"`  // Button presses are ignored while Windows Focus Assist is on, from the top-level pause_in_focus_assist
+	FocusAssistNotify bool                  `json:"focus_assist_notify"`    // Show a notification for presses ignored because of Focus Assist
+	logger            *zap.SugaredLogger
 }
 
 // buttonsMap is the internal implementation
 type buttonsMap struct {
-	CancelOnReload bool
-	Buttons        map[int]*ButtonConfig
-	logger         *zap.SugaredLogger
+	CancelOnReload    bool
+	DisabledActions   map[string]bool
+	Buttons           map[int]*ButtonConfig
+	OnConnect         *ButtonActionConfig
+	OnConnectEvery    bool
+	MaxTypingLength   int
+	MaxConcurrent     int
+	FocusAssistPause  bool
+	FocusAssistNotify bool
+	logger            *zap.SugaredLogger
 }
 
 // get returns the action configuration for a specific button and action type
@@ -110,9 +191,42 @@ func buttonsMapFromConfig(userConfig *viper.Viper, logger *zap.SugaredLogger) *b
 	logger = logger.Named("button_map")
 
 	bm := &buttonsMap{
-		CancelOnReload: false,
-		Buttons:        make(map[int]*ButtonConfig),
-		logger:         logger,
+		CancelOnReload:    false,
+		DisabledActions:   make(map[string]bool),
+		Buttons:           make(map[int]*ButtonConfig),
+		MaxTypingLength:   userConfig.GetInt(configKey_MaxTypingLength),
+		MaxConcurrent:     userConfig.GetInt(configKey_MaxConcurrent),
+		FocusAssistPause:  userConfig.GetBool(configKey_FocusAssistPause),
+		FocusAssistNotify: userConfig.GetBool(configKey_FocusAssistNotify),
+		logger:            logger,
+	}
+
+	if bm.MaxTypingLength <= 0 {
+		logger.Warnw("Invalid max_typing_length, using default", "value", bm.MaxTypingLength, "default", default_MaxTypingLength)
+		bm.MaxTypingLength = default_MaxTypingLength
+	}
+
+	if bm.MaxConcurrent < 0 {
+		logger.Warnw("Invalid max_concurrent_actions, using default", "value", bm.MaxConcurrent, "default", default_MaxConcurrentActions)
+		bm.MaxConcurrent = default_MaxConcurrentActions
+	}
+
+	// disabled_actions is a global guard that lives outside button_actions
+	for _, actionType := range userConfig.GetStringSlice(configKey_DisabledActions) {
+		actionType = strings.ToLower(strings.TrimSpace(actionType))
+		if !knownActionType(actionType) {
+			logger.Warnw("Unknown action type in disabled_actions", "type", actionType)
+		}
+		bm.DisabledActions[actionType] = true
+	}
+
+	// on_connect is a button-less action, also outside button_actions
+	if onConnectMap := userConfig.GetStringMap(configKey_OnConnect); len(onConnectMap) > 0 {
+		bm.OnConnect = parseActionConfig(onConnectMap, logger, -1, ActionOnConnect)
+		bm.OnConnect.Inherit = "" // no sibling actions to inherit from
+		if every, ok := onConnectMap["every_connect"].(bool); ok {
+			bm.OnConnectEvery = every
+		}
 	}
 
 	// Get button_actions section
@@ -165,17 +279,86 @@ func buttonsMapFromConfig(userConfig *viper.Viper, logger *zap.SugaredLogger) *b
 			buttonConfig.Long = parseActionConfig(longMap, logger, buttonID, ButtonActionLong)
 		}
 
+		resolveInheritedSteps(buttonConfig, logger, buttonID)
+
 		bm.Buttons[buttonID] = buttonConfig
 		logger.Debugw("Parsed button configuration", "button", buttonID)
 	}
 
 	logger.Infow("Loaded button actions configuration",
 		"buttons_count", len(bm.Buttons),
-		"cancel_on_reload", bm.CancelOnReload)
+		"cancel_on_reload", bm.CancelOnReload,
+		"disabled_actions", len(bm.DisabledActions),
+		"on_connect", bm.OnConnect != nil)
 
 	return bm
 }
 
+// resolveInheritedSteps flattens inherit references, so every action of the button ends up with its
+// complete step list. All actions are resolved from their own steps; a broken reference or a cycle
+// (e.g. single inherits double, double inherits single) is logged and leaves the action's own steps
+func resolveInheritedSteps(buttonConfig *ButtonConfig, logger *zap.SugaredLogger, buttonID int) {
+	actions := map[string]*ButtonActionConfig{
+		ButtonActionSingle: buttonConfig.Single,
+		ButtonActionDouble: buttonConfig.Double,
+		ButtonActionLong:   buttonConfig.Long,
+	}
+
+	resolved := make(map[string][]ActionStep)
+	for actionType, config := range actions {
+		if config == nil || config.Inherit == "" {
+			continue
+		}
+
+		steps, err := inheritedSteps(actions, actionType, map[string]bool{})
+		if err != nil {
+			logger.Warnw("Can't resolve inherited steps, using the action's own steps", "button", buttonID, "action", actionType, "error", err)
+			continue
+		}
+
+		resolved[actionType] = steps
+	}
+
+	for actionType, steps := range resolved {
+		actions[actionType].Steps = steps
+		logger.Debugw("Resolved inherited steps", "button", buttonID, "action", actionType,
+			"inherit", actions[actionType].Inherit, "steps_count", len(steps))
+	}
+}
+
+// inheritedSteps returns an action's steps with the ones it inherits, following the chain of inherit references
+func inheritedSteps(actions map[string]*ButtonActionConfig, actionType string, seen map[string]bool) ([]ActionStep, error) {
+	config := actions[actionType]
+	if config.Inherit == "" {
+		return config.Steps, nil
+	}
+
+	if seen[actionType] {
+		return nil, fmt.Errorf("inherit cycle through %s", actionType)
+	}
+	seen[actionType] = true
+
+	if actions[config.Inherit] == nil {
+		return nil, fmt.Errorf("%s inherits %s, which isn't configured", actionType, config.Inherit)
+	}
+
+	parentSteps, err := inheritedSteps(actions, config.Inherit, seen)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := make([]ActionStep, 0, len(parentSteps)+len(config.Steps))
+	if config.InheritMode == InheritModePrepend {
+		steps = append(steps, config.Steps...)
+		steps = append(steps, parentSteps...)
+	} else {
+		steps = append(steps, parentSteps...)
+		steps = append(steps, config.Steps...)
+	}
+
+	return steps, nil
+}
+
 // parseActionConfig parses a single action configuration (single/double/long)
 func parseActionConfig(actionMap map[string]interface{}, logger *zap.SugaredLogger, buttonID int, actionType string) *ButtonActionConfig {
 	config := &ButtonActionConfig{
@@ -188,6 +371,89 @@ func parseActionConfig(actionMap map[string]interface{}, logger *zap.SugaredLogg
 		config.Exclusive = exclusive
 	}
 
+	// Parse inherit/inherit_mode (resolved once all of the button's actions are parsed)
+	if inherit, ok := actionMap["inherit"].(string); ok {
+		inherit = strings.ToLower(strings.TrimSpace(inherit))
+		switch inherit {
+		case ButtonActionSingle, ButtonActionDouble, ButtonActionLong:
+			if inherit == actionType {
+				logger.Warnw("Action can't inherit from itself, ignoring inherit", "button", buttonID, "action", actionType)
+			} else {
+				config.Inherit = inherit
+			}
+		default:
+			logger.Warnw("Invalid inherit value, expected single, double or long", "button", buttonID, "action", actionType, "value", inherit)
+		}
+	}
+	if inheritMode, ok := actionMap["inherit_mode"].(string); ok {
+		inheritMode = strings.ToLower(strings.TrimSpace(inheritMode))
+		switch inheritMode {
+		case InheritModeAppend, InheritModePrepend:
+			config.InheritMode = inheritMode
+		default:
+			logger.Warnw("Invalid inherit_mode value, using append", "button", buttonID, "action", actionType, "value", inheritMode)
+		}
+	}
+
+	// Parse confirm/confirm_ms (default: false, 3000ms)
+	if confirm, ok := actionMap["confirm"].(bool); ok {
+		config.Confirm = confirm
+	}
+	if confirmMsRaw, ok := actionMap["confirm_ms"]; ok && confirmMsRaw != nil {
+		confirmMs := 0
+		if v, ok := confirmMsRaw.(float64); ok {
+			confirmMs = int(v)
+		} else if v, ok := confirmMsRaw.(int); ok {
+			confirmMs = v
+		}
+		if confirmMs <= 0 {
+			logger.Warnw("Invalid confirm_ms, using default", "button", buttonID, "action", actionType, "value", confirmMsRaw, "default", defaultConfirmWindowMs)
+		} else {
+			config.ConfirmMs = confirmMs
+		}
+	}
+
+	// Parse restore_focus (default: false)
+	if restoreFocusRaw, ok := actionMap["restore_focus"]; ok {
+		if restoreFocus, ok := restoreFocusRaw.(bool); ok {
+			config.RestoreFocus = restoreFocus
+		} else {
+			logger.Warnw("Invalid restore_focus value, expected true or false", "button", buttonID, "action", actionType, "value", restoreFocusRaw)
+		}
+	}
+
+	// Parse hold_ms/progress/progress_clear (long only, default: the device times long presses)
+	if holdMsRaw, ok := actionMap["hold_ms"]; ok && holdMsRaw != nil {
+		holdMs := 0
+		if v, ok := holdMsRaw.(float64); ok {
+			holdMs = int(v)
+		} else if v, ok := holdMsRaw.(int); ok {
+			holdMs = v
+		}
+		if actionType != ButtonActionLong {
+			logger.Warnw("hold_ms only applies to long actions, ignoring it", "button", buttonID, "action", actionType)
+		} else if holdMs <= 0 {
+			logger.Warnw("Invalid hold_ms, expected a positive number of milliseconds", "button", buttonID, "action", actionType, "value", holdMsRaw)
+		} else {
+			config.HoldMs = holdMs
+		}
+	}
+	if progress, ok := actionMap["progress"].(string); ok {
+		config.Progress = progress
+	}
+	if progressClear, ok := actionMap["progress_clear"].(string); ok {
+		config.ProgressClear = progressClear
+	}
+
+	// Parse silent (default: false)
+	if silentRaw, ok := actionMap["silent"]; ok {
+		if silent, ok := silentRaw.(bool); ok {
+			config.Silent = silent
+		} else {
+			logger.Warnw("Invalid silent value, expected true or false", "button", buttonID, "action", actionType, "value", silentRaw)
+		}
+	}
+
 	// Parse steps
 	stepsRaw, ok := actionMap["steps"]
 	if !ok {
@@ -293,6 +559,14 @@ func parseActionConfig(actionMap map[string]interface{}, logger *zap.SugaredLogg
 			} else if waitTimeout, ok := stepMap["wait_timeout"].(int); ok {
 				step.WaitTimeout = waitTimeout
 			}
+			if ifRunning, ok := stepMap["if_running"].(string); ok {
+				step.IfRunning = ifRunning
+			}
+			if settleMs, ok := stepMap["settle_ms"].(float64); ok {
+				step.SettleMs = int(settleMs)
+			} else if settleMs, ok := stepMap["settle_ms"].(int); ok {
+				step.SettleMs = settleMs
+			}
 			// Parse wait_wnd
 			waitWndRaw, hasWaitWnd := stepMap["wait_wnd"]
 			logger.Debugw("Parsing wait_wnd", "button", buttonID, "action", actionType, "step", stepIdx, "has_wait_wnd", hasWaitWnd, "type", fmt.Sprintf("%T", waitWndRaw))
@@ -352,21 +626,92 @@ func parseActionConfig(actionMap map[string]interface{}, logger *zap.SugaredLogg
 			} else if ms, ok := stepMap["ms"].(int); ok {
 				step.Ms = ms
 			}
+			if duration, ok := stepMap["duration"].(string); ok {
+				step.Duration = strings.TrimSpace(duration)
+				// an unparseable duration is left for Validate to report
+				if d, err := time.ParseDuration(step.Duration); err == nil {
+					step.Ms = int(d.Milliseconds())
+				}
+			}
 
 		case ActionTypeKeystroke:
 			if keys, ok := stepMap["keys"].(string); ok {
 				step.Keys = keys
 			}
+			if scancode, ok := stepMap["scancode"].(bool); ok {
+				step.Scancode = scancode
+			}
+			if preserve, ok := stepMap["preserve_modifiers"].(bool); ok {
+				step.PreserveModifiers = preserve
+			}
+			if target, ok := stepMap["target"].(string); ok {
+				step.Target = target
+			}
 
 		case ActionTypeTyping:
 			if text, ok := stepMap["text"].(string); ok {
 				step.Text = text
 			}
+			if textFile, ok := stepMap["text_file"].(string); ok {
+				step.TextFile = textFile
+			}
+			if escapes, ok := stepMap["escapes"].(bool); ok {
+				step.Escapes = escapes
+			}
+			if preserve, ok := stepMap["preserve_modifiers"].(bool); ok {
+				step.PreserveModifiers = preserve
+			}
+			if target, ok := stepMap["target"].(string); ok {
+				step.Target = target
+			}
 			if charDelay, ok := stepMap["char_delay"].(float64); ok {
 				step.CharDelay = int(charDelay)
 			} else if charDelay, ok := stepMap["char_delay"].(int); ok {
 				step.CharDelay = charDelay
 			}
+
+		case ActionTypeWaitProcess:
+			if processName, ok := stepMap["process"].(string); ok {
+				step.ProcessName = processName
+			}
+			if mode, ok := stepMap["mode"].(string); ok {
+				step.Mode = mode
+			}
+			if timeout, ok := stepMap["timeout"].(float64); ok {
+				step.Timeout = int(timeout)
+			} else if timeout, ok := stepMap["timeout"].(int); ok {
+				step.Timeout = timeout
+			}
+
+		case ActionTypeKill:
+			if processName, ok := stepMap["process"].(string); ok {
+				step.ProcessName = processName
+			}
+			if force, ok := stepMap["force"].(bool); ok {
+				step.Force = force
+			}
+
+		case ActionTypeBeep:
+			if frequency, ok := stepMap["frequency"].(float64); ok {
+				step.Frequency = int(frequency)
+			} else if frequency, ok := stepMap["frequency"].(int); ok {
+				step.Frequency = frequency
+			}
+			if ms, ok := stepMap["ms"].(float64); ok {
+				step.Ms = int(ms)
+			} else if ms, ok := stepMap["ms"].(int); ok {
+				step.Ms = ms
+			}
+
+		case ActionTypeCall:
+			if button, ok := stepMap["button"].(float64); ok {
+				step.Button = int(button)
+			} else if button, ok := stepMap["button"].(int); ok {
+				step.Button = button
+			}
+			if action, ok := stepMap["action"].(string); ok {
+				step.Action = strings.ToLower(strings.TrimSpace(action))
+			}
 		}
 
 		config.Steps = append(config.Steps, step)
@@ -407,6 +752,11 @@ func (bm *buttonsMap) Validate() error {
 			}
 		}
 	}
+	if bm.OnConnect != nil {
+		if err := bm.validateActionConfig(-1, ActionOnConnect, bm.OnConnect); err != nil {
+			return fmt.Errorf("on_connect action: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -426,9 +776,17 @@ func (bm *buttonsMap) validateActionConfig(buttonID int, actionType string, conf
 			if step.WaitTimeout < 0 {
 				return fmt.Errorf("step %d: wait_timeout must be non-negative (0 = infinite)", stepIdx)
 			}
+			if step.SettleMs < 0 {
+				return fmt.Errorf("step %d: settle_ms must be non-negative", stepIdx)
+			}
 			if step.WaitTimeout > 0 && !step.Wait {
 				return fmt.Errorf("step %d: wait_timeout can only be used when wait is true", stepIdx)
 			}
+			switch step.IfRunning {
+			case "", IfRunningLaunch, IfRunningFocus, IfRunningSkip:
+			default:
+				return fmt.Errorf("step %d: if_running must be %q, %q or %q", stepIdx, IfRunningLaunch, IfRunningFocus, IfRunningSkip)
+			}
 			// Validate wait_wnd: can only be used with wait: false
 			if step.WaitWnd != nil {
 				if step.Wait {
@@ -439,6 +797,15 @@ func (bm *buttonsMap) validateActionConfig(buttonID int, actionType string, conf
 				}
 			}
 		case ActionTypeDelay:
+			if step.Duration != "" {
+				d, err := time.ParseDuration(step.Duration)
+				if err != nil {
+					return fmt.Errorf("step %d: invalid duration %q for delay action: %w", stepIdx, step.Duration, err)
+				}
+				if d < time.Millisecond {
+					return fmt.Errorf("step %d: duration must be at least 1ms for delay action", stepIdx)
+				}
+			}
 			if step.Ms <= 0 {
 				return fmt.Errorf("step %d: ms must be positive for delay action", stepIdx)
 			}
@@ -447,8 +814,55 @@ func (bm *buttonsMap) validateActionConfig(buttonID int, actionType string, conf
 				return fmt.Errorf("step %d: keys is required for keystroke action", stepIdx)
 			}
 		case ActionTypeTyping:
-			if step.Text == "" {
-				return fmt.Errorf("step %d: text is required for typing action", stepIdx)
+			if step.Text == "" && step.TextFile == "" {
+				return fmt.Errorf("step %d: text or text_file is required for typing action", stepIdx)
+			}
+			if step.Text != "" && step.TextFile != "" {
+				return fmt.Errorf("step %d: text and text_file can't both be set for typing action", stepIdx)
+			}
+			// not fatal, the text is cut off when the step runs
+			if length := utf8.RuneCountInString(step.Text); length > bm.MaxTypingLength {
+				bm.logger.Warnw("Typing text is longer than max_typing_length and will be cut off",
+					"button", buttonID, "action", actionType, "step", stepIdx, "length", length, "max_typing_length", bm.MaxTypingLength)
+			}
+		case ActionTypeWaitProcess:
+			if step.ProcessName == "" {
+				return fmt.Errorf("step %d: process is required for wait_process action", stepIdx)
+			}
+			if step.Mode != WaitProcessModeStart && step.Mode != WaitProcessModeExit {
+				return fmt.Errorf("step %d: mode must be %q or %q for wait_process action", stepIdx, WaitProcessModeStart, WaitProcessModeExit)
+			}
+			if step.Timeout < 0 {
+				return fmt.Errorf("step %d: timeout must be non-negative (0 = infinite)", stepIdx)
+			}
+		case ActionTypeKill:
+			if step.ProcessName == "" {
+				return fmt.Errorf("step %d: process is required for kill action", stepIdx)
+			}
+			if strings.ContainsAny(step.ProcessName, `/\`) {
+				return fmt.Errorf("step %d: process must be a process name, not a path, for kill action", stepIdx)
+			}
+			if protectedProcess(step.ProcessName) {
+				return fmt.Errorf("step %d: %s is a system process and can't be killed", stepIdx, step.ProcessName)
+			}
+		case ActionTypeBeep:
+			if step.Frequency != 0 && (step.Frequency < beepMinFrequency || step.Frequency > beepMaxFrequency) {
+				return fmt.Errorf("step %d: frequency must be between %d and %d Hz for beep action", stepIdx, beepMinFrequency, beepMaxFrequency)
+			}
+			if step.Ms < 0 || step.Ms > beepMaxDurationMs {
+				return fmt.Errorf("step %d: ms must be between 0 and %d for beep action", stepIdx, beepMaxDurationMs)
+			}
+		case ActionTypeCall:
+			switch step.Action {
+			case ButtonActionSingle, ButtonActionDouble, ButtonActionLong:
+			default:
+				return fmt.Errorf("step %d: action must be single, double or long for call action", stepIdx)
+			}
+			if step.Button == buttonID && step.Action == actionType {
+				return fmt.Errorf("step %d: an action can't call itself", stepIdx)
+			}
+			if called, ok := bm.get(step.Button, step.Action); !ok || called == nil {
+				return fmt.Errorf("step %d: button %d has no %s action to call", stepIdx, step.Button, step.Action)
 			}
 		default:
 			return fmt.Errorf("step %d: unknown action type: %s", stepIdx, step.Type)
@@ -464,8 +878,28 @@ func (bm *buttonsMap) ToButtonsMapping() *ButtonsMapping {
 	for k, v := range bm.Buttons {
 		buttons[k] = v
 	}
+	disabled := make(map[string]bool, len(bm.DisabledActions))
+	for k, v := range bm.DisabledActions {
+		disabled[k] = v
+	}
 	return &ButtonsMapping{
-		CancelOnReload: bm.CancelOnReload,
-		Buttons:        buttons,
+		CancelOnReload:    bm.CancelOnReload,
+		DisabledActions:   disabled,
+		Buttons:           buttons,
+		OnConnect:         bm.OnConnect,
+		OnConnectEvery:    bm.OnConnectEvery,
+		MaxTypingLength:   bm.MaxTypingLength,
+		MaxConcurrent:     bm.MaxConcurrent,
+		FocusAssistPause:  bm.FocusAssistPause,
+		FocusAssistNotify: bm.FocusAssistNotify,
+	}
+}
+
+// knownActionType reports whether actionType is a step type deej can run
+func knownActionType(actionType string) bool {
+	switch actionType {
+	case ActionTypeExecute, ActionTypeDelay, ActionTypeKeystroke, ActionTypeTyping, ActionTypeWaitProcess, ActionTypeBeep, ActionTypeKill, ActionTypeCall:
+		return true
 	}
+	return false
 }
@@ -0,0 +1,193 @@
+package deej
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// singleActionYAML builds a config.yaml body giving button 0 a single click action with the given steps,
+// one "- type: ..." flow mapping per entry
+func singleActionYAML(steps ...string) string {
+	var b strings.Builder
+	b.WriteString("button_actions:\n  0:\n    single:\n      steps:\n")
+	for _, step := range steps {
+		b.WriteString("        - " + step + "\n")
+	}
+	return b.String()
+}
+
+// singleActionSteps loads steps as button 0's single click action and validates the result
+func singleActionSteps(t *testing.T, steps ...string) ([]ActionStep, error) {
+	t.Helper()
+
+	cc := newTestConfig(t, singleActionYAML(steps...))
+	action, ok := cc.ButtonsMapping.get(0, ButtonActionSingle)
+	if !ok {
+		t.Fatal("button 0 has no single click action")
+	}
+
+	return action.Steps, cc.ButtonsMapping.Validate()
+}
+
+func TestBeepStep(t *testing.T) {
+	tests := []struct {
+		step          string
+		wantFrequency int
+		wantMs        int
+		wantErr       bool
+	}{
+		{"{type: beep}", 0, 0, false},
+		{"{type: beep, frequency: 880, ms: 200}", 880, 200, false},
+		{"{type: beep, frequency: 37}", 37, 0, false},
+		{"{type: beep, frequency: 36}", 36, 0, true},
+		{"{type: beep, frequency: 40000}", 40000, 0, true},
+		{"{type: beep, ms: 5000}", 0, 5000, false},
+		{"{type: beep, ms: 5001}", 0, 5001, true},
+		{"{type: beep, ms: -1}", 0, -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.step, func(t *testing.T) {
+			steps, err := singleActionSteps(t, tt.step)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validation error: got %v, want error %v", err, tt.wantErr)
+			}
+
+			if steps[0].Frequency != tt.wantFrequency || steps[0].Ms != tt.wantMs {
+				t.Errorf("got frequency %d ms %d, want frequency %d ms %d", steps[0].Frequency, steps[0].Ms, tt.wantFrequency, tt.wantMs)
+			}
+		})
+	}
+}
+
+func TestDelayStepDuration(t *testing.T) {
+	tests := []struct {
+		step    string
+		wantMs  int
+		wantErr bool
+	}{
+		{"{type: delay, ms: 300}", 300, false},
+		{"{type: delay, duration: 2s}", 2000, false},
+		{"{type: delay, duration: 500ms}", 500, false},
+		{"{type: delay, duration: 1.5s, ms: 100}", 1500, false},
+		{"{type: delay, duration: 100us}", 0, true},
+		{"{type: delay, duration: soon}", 0, true},
+		{"{type: delay}", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.step, func(t *testing.T) {
+			steps, err := singleActionSteps(t, tt.step)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validation error: got %v, want error %v", err, tt.wantErr)
+			}
+
+			if steps[0].Ms != tt.wantMs {
+				t.Errorf("got ms %d, want %d", steps[0].Ms, tt.wantMs)
+			}
+		})
+	}
+}
+
+func TestKillStep(t *testing.T) {
+	tests := []struct {
+		step        string
+		wantProcess string
+		wantForce   bool
+		wantErr     bool
+	}{
+		{"{type: kill, process: game.exe}", "game.exe", false, false},
+		{"{type: kill, process: game, force: true}", "game", true, false},
+		{"{type: kill}", "", false, true},
+		{`{type: kill, process: 'C:\Games\game.exe'}`, `C:\Games\game.exe`, false, true},
+		{"{type: kill, process: /usr/bin/game}", "/usr/bin/game", false, true},
+		{"{type: kill, process: explorer.exe}", "explorer.exe", false, true},
+		{"{type: kill, process: ' LSASS.EXE '}", " LSASS.EXE ", false, true},
+		{"{type: kill, process: systemd}", "systemd", false, true},
+		{"{type: kill, process: deej.exe}", "deej.exe", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.step, func(t *testing.T) {
+			steps, err := singleActionSteps(t, tt.step)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validation error: got %v, want error %v", err, tt.wantErr)
+			}
+
+			if steps[0].ProcessName != tt.wantProcess || steps[0].Force != tt.wantForce {
+				t.Errorf("got process %q force %v, want process %q force %v", steps[0].ProcessName, steps[0].Force, tt.wantProcess, tt.wantForce)
+			}
+		})
+	}
+}
+
+func TestInheritedSteps(t *testing.T) {
+	// every step is a delay, its ms tells which action it was written in
+	action := func(name string, extra string, ms ...int) string {
+		var b strings.Builder
+		b.WriteString("    " + name + ":\n" + extra + "      steps:\n")
+		for _, m := range ms {
+			b.WriteString(fmt.Sprintf("        - {type: delay, ms: %d}\n", m))
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		name    string
+		actions string
+		want    map[string]string // action type -> step ms in order
+	}{
+		{
+			name:    "double appends to single",
+			actions: action("single", "", 1, 2) + action("double", "      inherit: single\n", 3),
+			want:    map[string]string{"single": "[1 2]", "double": "[1 2 3]"},
+		},
+		{
+			name:    "prepend runs own steps first",
+			actions: action("single", "", 1, 2) + action("double", "      inherit: single\n      inherit_mode: prepend\n", 3),
+			want:    map[string]string{"single": "[1 2]", "double": "[3 1 2]"},
+		},
+		{
+			name:    "chain",
+			actions: action("single", "", 1) + action("double", "      inherit: single\n", 2) + action("long", "      inherit: double\n", 3),
+			want:    map[string]string{"single": "[1]", "double": "[1 2]", "long": "[1 2 3]"},
+		},
+		{
+			name:    "cycle keeps own steps",
+			actions: action("single", "      inherit: double\n", 1) + action("double", "      inherit: single\n", 2),
+			want:    map[string]string{"single": "[1]", "double": "[2]"},
+		},
+		{
+			name:    "missing parent keeps own steps",
+			actions: action("double", "      inherit: long\n", 2),
+			want:    map[string]string{"double": "[2]"},
+		},
+		{
+			name:    "self inheritance is ignored",
+			actions: action("single", "      inherit: single\n", 1),
+			want:    map[string]string{"single": "[1]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := newTestConfig(t, "button_actions:\n  0:\n"+tt.actions)
+
+			for actionType, want := range tt.want {
+				config, ok := cc.ButtonsMapping.get(0, actionType)
+				if !ok {
+					t.Fatalf("button 0 has no %s action", actionType)
+				}
+
+				ms := make([]int, 0, len(config.Steps))
+				for _, step := range config.Steps {
+					ms = append(ms, step.Ms)
+				}
+				if got := fmt.Sprint(ms); got != want {
+					t.Errorf("%s steps %s, want %s", actionType, got, want)
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 
 	"github.com/stalexteam/deej_esp32/pkg/deej"
 )
@@ -11,20 +12,37 @@ var (
 	gitCommit  string
 	versionTag string
 	buildType  string
+	buildDate  string
 
-	verbose bool
+	verbose     bool
+	showVersion bool
+	testMapping string
 )
 
 func init() {
 	flag.BoolVar(&verbose, "verbose", false, "show verbose logs (useful for debugging serial)")
 	flag.BoolVar(&verbose, "v", false, "shorthand for --verbose")
+	flag.BoolVar(&showVersion, "version", false, "print version and build info, then exit")
+	flag.StringVar(&testMapping, "test-mapping", "",
+		"apply one simulated event, e.g. slider:2=0.5 or switch:1=on, print the sessions it targeted, then exit")
 	flag.Parse()
 }
 
 func main() {
+	buildInfo := deej.BuildInfo{
+		VersionTag: versionTag,
+		GitCommit:  gitCommit,
+		BuildType:  buildType,
+		BuildDate:  buildDate,
+	}
+
+	if showVersion {
+		fmt.Println(buildInfo)
+		os.Exit(0)
+	}
 
 	// first we need a logger
-	logger, err := deej.NewLogger(buildType)
+	logger, logLevel, err := deej.NewLogger(buildType, deej.LoadLoggerOptions())
 	if err != nil {
 		panic(fmt.Sprintf("Failed to create logger: %v", err))
 	}
@@ -35,7 +53,8 @@ func main() {
 	named.Infow("Version info",
 		"gitCommit", gitCommit,
 		"versionTag", versionTag,
-		"buildType", buildType)
+		"buildType", buildType,
+		"buildDate", buildDate)
 
 	// provide a fair warning if the user's running in verbose mode
 	if verbose {
@@ -48,6 +67,10 @@ func main() {
 		named.Fatalw("Failed to create deej object", "error", err)
 	}
 
+	// let verbose mode (and the tray toggle for it) control the log level
+	d.SetLogLevel(logLevel)
+	d.SetBuildInfo(buildInfo)
+
 	// if injected by build process, set version info to show up in the tray
 	if buildType != "" && (versionTag != "" || gitCommit != "") {
 		identifier := gitCommit
@@ -59,6 +82,15 @@ func main() {
 		d.SetVersion(versionString)
 	}
 
+	// apply a single event from the command line instead of running
+	if testMapping != "" {
+		if err := d.TestMapping(testMapping, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Mapping test failed: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// onwards, to glory
 	if err = d.Initialize(); err != nil {
 		named.Fatalw("Failed to initialize deej", "error", err)
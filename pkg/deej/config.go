@@ -2,17 +2,37 @@ package deej
 
 import (
 	"fmt"
+	"math"
+	"os"
 	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 	"github.com/stalexteam/deej_esp32/pkg/deej/util"
 	"go.uber.org/zap"
 )
 
+// MuteIndicator holds the device commands sent when a switch mutes or unmutes its targets
+type MuteIndicator struct {
+	Muted   string
+	Unmuted string
+}
+
+// SliderFinePair ties a slider_fine slider to the coarse slider whose volume it fine-tunes
+type SliderFinePair struct {
+	Coarse int
+	Range  float64 // percent the fine slider adds or takes away at either end of its travel
+}
+
 // CanonicalConfig provides application-wide access to configuration fields,
 // as well as loading/file watching logic for deej's configuration file
 type CanonicalConfig struct {
@@ -21,16 +41,90 @@ type CanonicalConfig struct {
 	ButtonsMapping  *buttonsMap
 
 	ConnectionInfo struct {
-		SSE_URL         string
-		SSE_RELAY_PORT  int
-		SERIAL_Port     string
-		SERIAL_BaudRate int
+		SSE_URL              string
+		SSE_Username         string // HTTP basic auth for devices with a protected web server (ESPHome web_server auth)
+		SSE_Password         string // never logged, see Load
+		SSE_ConnectTimeout   time.Duration
+		SSE_IdleTimeout      time.Duration // reconnect when nothing (not even a ping) arrives for this long
+		SSE_RetryInterval    time.Duration // pause between reconnect attempts
+		SSE_RELAY_PORT       int
+		SSE_RELAY_Snapshot   bool // include current session volumes/mutes when a relay client connects
+		SSE_RELAY_PortSearch int  // how many ports after SSE_RELAY_PORT to try when it's taken
+		SERIAL_Port          string
+		SERIAL_ExtraPorts    []string // further boards when SERIAL_Port is a list, see serial_extra.go
+		SERIAL_BaudRate      int
+		SERIAL_BaudRates     []int // candidates tried in order; SERIAL_BaudRate is the first of them
+		OSC_Target           string
+		MQTT_Broker          string
+		MQTT_Topic           string
+
+		TransportPreference string // one of the TransportPreference* values
+
+		SerialIDOffset int // ids from the n-th extra serial board are shifted by n times this
 	}
 
-	InvertSliders  bool
-	InvertSwitches bool
+	MasterDevice string // full name of the device "master" targets instead of the default output device, if set
+
+	InvertSliders   bool
+	SliderOverflow  string // one of the SliderOverflow* values
+	InvertSwitches  bool
+	SwitchInvert    map[int]bool // per-switch inversion, applied on top of InvertSwitches
+	SwitchActiveLow map[int]bool // switches wired active-low, whose raw state is flipped as it's read
+	MuteIndicators  map[int]MuteIndicator
+
+	SwitchPTT        map[int]bool        // push-to-talk switches: targets are unmuted only while held
+	SwitchPTTRelease time.Duration       // delay before a released push-to-talk switch mutes again
+	SwitchSolo       map[int]bool        // solo switches: while on, every app session except the targets is muted
+	MuteGroups       map[string][]string // group name -> lowercase targets that switches mute and unmute together
+	RestoreSwitches  bool                // switch states are saved to preferences.yaml and applied again on startup
+
+	SliderIDPattern *regexp.Regexp  // matches slider event ids, the first capture group is the index
+	SwitchIDPattern *regexp.Regexp  // matches switch event ids, the first capture group is the index
+	NumberIDPattern *regexp.Regexp  // matches ESPHome number entities that drive sliders, like SliderIDPattern
+	SelectIDPattern *regexp.Regexp  // matches ESPHome select entities that drive switches, like SwitchIDPattern
+	SelectOnStates  map[string]bool // lowercase select options that turn the switch on
+
+	SliderRemap map[int]int // device slider id -> the id used in the rest of the config
+	SwitchRemap map[int]int // device switch id -> the id used in the rest of the config
+
+	SliderOverride      map[int]int
+	EncoderSteps        map[int]int
+	SliderJog           map[int]float64            // jog sliders -> max volume change in percent per second
+	SliderExpo          map[int]float64            // slider index -> expo factor in (0, 1], see util.Expo
+	SliderFine          map[int]SliderFinePair     // fine slider index -> the coarse slider it adjusts
+	SliderWeights       map[int]map[string]float64 // slider index -> lowercase target -> weight in [-1, 1]
+	TargetDefaultVolume map[string]float32         // lowercase target -> volume (0-1) for sessions that just appeared
+	SliderSnap          map[int][]float64          // slider index -> sorted snap points in percent
+
+	SliderSnapTolerance float64 // how close (in percent) a reading must be to a snap point to snap to it
+
+	SliderSpikeFilter int
+
+	SliderFineControlSpeed float64 // percent per second at which slider_fine_control stops scaling movement, 0 when off
+
+	IOWatchdogTimeout time.Duration
 
-	SliderOverride map[int]int
+	IdleDimAfter       time.Duration // dim the device after this long without activity (0 = never)
+	IdleDimCommand     string        // written to the device when it goes idle
+	IdleRestoreCommand string        // written to the device on the first activity after dimming
+	ShutdownTimeout    time.Duration // deej exits anyway if shutting down takes longer than this
+
+	SerialPingInterval time.Duration // 0 disables the serial keep-alive
+	SerialPingTimeout  time.Duration
+	SerialPingCommand  string
+
+	ConnectionNotifications bool // notify when the device connection is lost for a while, and when it's back
+
+	Editor string
+
+	SessionFilter   bool // only enumerate sessions of mapped processes, when the mapping allows it
+	SessionLogLimit int  // how many sessions are logged at INFO per refresh (0 = all)
+
+	UnmappedIncludes map[string]bool // session classes (master, system, mic, devices) deej.unmapped covers
+
+	SessionRefreshCooldown time.Duration // minimum time between regular session refreshes
+	SessionRefreshMaxAge   time.Duration // the session map is refreshed on the next event once it's this old
+	SessionRefreshMaxRate  int           // forced refreshes after failures allowed per second
 
 	logger             *zap.SugaredLogger
 	notifier           Notifier
@@ -40,6 +134,8 @@ type CanonicalConfig struct {
 
 	userConfig     *viper.Viper
 	internalConfig *viper.Viper
+
+	internalConfigMutex sync.Mutex // serializes switch state and mapping saves and reads of internalConfig
 }
 
 const (
@@ -50,25 +146,141 @@ const (
 
 	userConfigPath = "."
 
-	configType = "yaml"
-
-	configKey_SliderMapping   = "slider_mapping"
-	configKey_SwitchesMapping = "switches_mapping"
-	configKey_ButtonActions   = "button_actions"
+	// internal config key holding the switch states saved for restore_switches
+	internalKey_SwitchStates = "switch_states"
 
-	configKey_InvertSliders  = "invert_sliders"
-	configKey_InvertSwitches = "invert_switches"
-	configKey_SliderOverride = "slider_override"
+	configType = "yaml"
 
-	configKey_SSE_URL         = "SSE_URL"
-	configKey_SSE_RELAY_PORT  = "SSE_RELAY_PORT"
-	configKey_SERIAL_PORT     = "SERIAL_Port"
-	configKey_SERIAL_BaudRate = "SERIAL_BaudRate"
+	configKey_SliderMapping     = "slider_mapping"
+	configKey_SwitchesMapping   = "switches_mapping"
+	configKey_ButtonActions     = "button_actions"
+	configKey_DisabledActions   = "disabled_actions"
+	configKey_OnConnect         = "on_connect"
+	configKey_MaxTypingLength   = "max_typing_length"
+	configKey_MaxConcurrent     = "max_concurrent_actions"
+	configKey_FocusAssistPause  = "pause_in_focus_assist"
+	configKey_FocusAssistNotify = "focus_assist_notify"
+	configKey_MasterDevice      = "master_device"
+
+	configKey_InvertSliders       = "invert_sliders"
+	configKey_SliderOverflow      = "slider_overflow"
+	configKey_InvertSwitches      = "invert_switches"
+	configKey_SwitchInvert        = "switch_invert"
+	configKey_SwitchActiveLow     = "switch_active_low"
+	configKey_MuteIndicator       = "mute_indicator"
+	configKey_SwitchPTT           = "switch_ptt"
+	configKey_SwitchPTTRelease    = "switch_ptt_release_ms"
+	configKey_SwitchSolo          = "switch_solo"
+	configKey_MuteGroups          = "mute_groups"
+	configKey_RestoreSwitches     = "restore_switches"
+	configKey_SliderOverride      = "slider_override"
+	configKey_SliderRemap         = "slider_remap"
+	configKey_SliderIDPattern     = "slider_id_pattern"
+	configKey_SwitchIDPattern     = "switch_id_pattern"
+	configKey_NumberIDPattern     = "number_id_pattern"
+	configKey_SelectIDPattern     = "select_id_pattern"
+	configKey_SelectOnStates      = "select_on_states"
+	configKey_SwitchRemap         = "switch_remap"
+	configKey_EncoderSteps        = "encoder_steps"
+	configKey_SliderJog           = "slider_jog"
+	configKey_SliderExpo          = "slider_expo"
+	configKey_SliderFine          = "slider_fine"
+	configKey_SliderWeights       = "slider_weights"
+	configKey_TargetDefaultVolume = "target_default_volume"
+	configKey_SliderSnap          = "slider_snap"
+
+	configKey_SliderSnapTolerance = "slider_snap_tolerance"
+
+	configKey_SliderSpikeFilter = "slider_spike_filter"
+
+	configKey_SliderFineControl      = "slider_fine_control"
+	configKey_SliderFineControlSpeed = "slider_fine_control_speed"
+
+	default_SliderFineControlSpeed = 50
+	configKey_IOWatchdogTimeout    = "io_watchdog_timeout"
+	configKey_IdleDimAfter         = "idle_dim_after"
+	configKey_IdleDimCommand       = "idle_dim_command"
+	configKey_IdleRestoreCommand   = "idle_restore_command"
+	configKey_ShutdownTimeout      = "shutdown_timeout"
+
+	configKey_SerialPingInterval = "serial_ping_interval"
+	configKey_SerialPingTimeout  = "serial_ping_timeout"
+	configKey_SerialPingCommand  = "serial_ping_command"
+
+	default_SerialPingTimeout = 3 * time.Second
+	default_SerialPingCommand = "ping"
+
+	configKey_ConnectionNotifications = "connection_notifications"
+	configKey_Editor                  = "editor"
+	configKey_SessionFilter           = "session_filter"
+	configKey_SessionLogLimit         = "session_log_limit"
+	configKey_UnmappedIncludes        = "unmapped_includes"
+
+	configKey_SessionRefreshCooldown = "session_refresh_cooldown"
+	configKey_SessionRefreshMaxAge   = "session_refresh_max_age"
+	configKey_SessionRefreshMaxRate  = "session_refresh_max_rate"
+
+	// read by LoadLoggerOptions before the rest of the config
+	configKey_LogLevel      = "log_level"
+	configKey_LogFormat     = "log_format"
+	configKey_LogMaxSizeMB  = "log_max_size_mb"
+	configKey_LogMaxBackups = "log_max_backups"
+
+	configKey_SSE_URL              = "SSE_URL"
+	configKey_SSE_Username         = "SSE_Username"
+	configKey_SSE_Password         = "SSE_Password"
+	configKey_SSE_ConnectTimeout   = "SSE_ConnectTimeout"
+	configKey_SSE_IdleTimeout      = "SSE_IdleTimeout"
+	configKey_SSE_RetryInterval    = "SSE_RetryInterval"
+	configKey_SSE_RELAY_PORT       = "SSE_RELAY_PORT"
+	configKey_SSE_RELAY_PortSearch = "SSE_RELAY_PortSearch"
+	configKey_SSE_RELAY_Snapshot   = "SSE_RELAY_Snapshot"
+	configKey_SERIAL_PORT          = "SERIAL_Port"
+	configKey_SERIAL_BaudRate      = "SERIAL_BaudRate"
+	configKey_OSC_Target           = "OSC_Target"
+	configKey_MQTT_Broker          = "MQTT_Broker"
+	configKey_MQTT_Topic           = "MQTT_Topic"
+
+	configKey_TransportPreference = "transport_preference"
+	configKey_SerialIDOffset      = "serial_id_offset"
+
+	default_SerialIDOffset = 100
 
 	default_SSE_URL         = "" //http://mix.local/events
 	default_SSE_RELAY_PORT  = 0
 	default_SERIAL_PORT     = ""
 	default_SERIAL_BaudRate = 0
+	default_OSC_Target      = ""
+	default_MQTT_Broker     = ""
+	default_MQTT_Topic      = "deej"
+
+	// Most characters a typing step types, past that the text is cut off
+	default_MaxTypingLength = 10000
+
+	// Most button actions running at once, further presses are ignored until one finishes. 0 is no limit,
+	// like before the setting existed
+	default_MaxConcurrentActions = 0
+
+	// Percent moved per encoder detent when encoder_steps doesn't list the encoder
+	default_EncoderStep = 2
+
+	// Percent a slider_fine slider adds or takes away at its ends when the config doesn't say
+	default_SliderFineRange = 5
+
+	// Percent per second a jog slider changes the volume at full displacement, when slider_jog doesn't say
+	default_SliderJogRate = 50
+
+	// Readings within this many percent of a slider_snap point snap to it
+	default_SliderSnapTolerance = 5
+
+	// ESPHome entity ids of pots and switches, the capture group is the index
+	default_SliderIDPattern = `^sensor-pot(\d+)$`
+	default_SwitchIDPattern = `^binary_sensor-sw(\d+)$`
+	default_NumberIDPattern = `^number-volume(\d+)$`
+	default_SelectIDPattern = `^select-sw(\d+)$`
+
+	// How long a released push-to-talk switch keeps the mic open
+	default_SwitchPTTReleaseMs = 200
 )
 
 // has to be defined as a non-constant because we're using path.Join
@@ -89,18 +301,81 @@ func NewConfig(logger *zap.SugaredLogger, notifier Notifier) (*CanonicalConfig,
 	userConfig := viper.New()
 	userConfig.SetConfigName(userConfigName)
 	userConfig.SetConfigType(configType)
-	userConfig.AddConfigPath(userConfigPath)
+	for _, configPath := range userConfigPaths() {
+		userConfig.AddConfigPath(configPath)
+	}
 
 	userConfig.SetDefault(configKey_SliderMapping, map[string][]string{})
 	userConfig.SetDefault(configKey_SwitchesMapping, map[string][]string{})
 	userConfig.SetDefault(configKey_ButtonActions, map[string]interface{}{})
+	userConfig.SetDefault(configKey_DisabledActions, []string{})
+	userConfig.SetDefault(configKey_MaxTypingLength, default_MaxTypingLength)
+	userConfig.SetDefault(configKey_MaxConcurrent, default_MaxConcurrentActions)
+	userConfig.SetDefault(configKey_FocusAssistPause, false)
+	userConfig.SetDefault(configKey_FocusAssistNotify, false)
+	userConfig.SetDefault(configKey_MasterDevice, "")
 	userConfig.SetDefault(configKey_InvertSliders, false)
+	userConfig.SetDefault(configKey_SliderOverflow, SliderOverflowClamp)
 	userConfig.SetDefault(configKey_InvertSwitches, false)
+	userConfig.SetDefault(configKey_SwitchInvert, map[string]interface{}{})
+	userConfig.SetDefault(configKey_SwitchActiveLow, map[string]interface{}{})
+	userConfig.SetDefault(configKey_MuteIndicator, map[string]interface{}{})
+	userConfig.SetDefault(configKey_SwitchPTT, []int{})
+	userConfig.SetDefault(configKey_SwitchPTTRelease, default_SwitchPTTReleaseMs)
+	userConfig.SetDefault(configKey_SwitchSolo, []int{})
+	userConfig.SetDefault(configKey_MuteGroups, map[string]interface{}{})
+	userConfig.SetDefault(configKey_RestoreSwitches, false)
 	userConfig.SetDefault(configKey_SliderOverride, map[string]interface{}{})
+	userConfig.SetDefault(configKey_SliderRemap, map[string]interface{}{})
+	userConfig.SetDefault(configKey_SliderIDPattern, default_SliderIDPattern)
+	userConfig.SetDefault(configKey_SwitchIDPattern, default_SwitchIDPattern)
+	userConfig.SetDefault(configKey_NumberIDPattern, default_NumberIDPattern)
+	userConfig.SetDefault(configKey_SelectIDPattern, default_SelectIDPattern)
+	userConfig.SetDefault(configKey_SelectOnStates, []string{"on"})
+	userConfig.SetDefault(configKey_SwitchRemap, map[string]interface{}{})
+	userConfig.SetDefault(configKey_EncoderSteps, map[string]interface{}{})
+	userConfig.SetDefault(configKey_SliderJog, map[string]interface{}{})
+	userConfig.SetDefault(configKey_SliderExpo, map[string]interface{}{})
+	userConfig.SetDefault(configKey_SliderFine, map[string]interface{}{})
+	userConfig.SetDefault(configKey_SliderWeights, map[string]interface{}{})
+	userConfig.SetDefault(configKey_TargetDefaultVolume, map[string]interface{}{})
+	userConfig.SetDefault(configKey_SliderSnap, map[string]interface{}{})
+	userConfig.SetDefault(configKey_SliderSnapTolerance, default_SliderSnapTolerance)
+	userConfig.SetDefault(configKey_SliderSpikeFilter, 0)
+	userConfig.SetDefault(configKey_SliderFineControl, false)
+	userConfig.SetDefault(configKey_SliderFineControlSpeed, default_SliderFineControlSpeed)
+	userConfig.SetDefault(configKey_IOWatchdogTimeout, 0)
+	userConfig.SetDefault(configKey_IdleDimAfter, 0)
+	userConfig.SetDefault(configKey_IdleDimCommand, "")
+	userConfig.SetDefault(configKey_IdleRestoreCommand, "")
+	userConfig.SetDefault(configKey_ShutdownTimeout, defaultShutdownTimeout.Seconds())
+	userConfig.SetDefault(configKey_SerialPingInterval, 0)
+	userConfig.SetDefault(configKey_SerialPingTimeout, default_SerialPingTimeout.Seconds())
+	userConfig.SetDefault(configKey_SerialPingCommand, default_SerialPingCommand)
+	userConfig.SetDefault(configKey_ConnectionNotifications, false)
+	userConfig.SetDefault(configKey_Editor, "")
+	userConfig.SetDefault(configKey_SessionFilter, false)
+	userConfig.SetDefault(configKey_UnmappedIncludes, []string{})
+	userConfig.SetDefault(configKey_SessionLogLimit, 0)
+	userConfig.SetDefault(configKey_SessionRefreshCooldown, minTimeBetweenSessionRefreshes.Seconds())
+	userConfig.SetDefault(configKey_SessionRefreshMaxAge, maxTimeBetweenSessionRefreshes.Seconds())
+	userConfig.SetDefault(configKey_SessionRefreshMaxRate, maxFailureRefreshesPerSecond)
 	userConfig.SetDefault(configKey_SSE_URL, default_SSE_URL)
+	userConfig.SetDefault(configKey_SSE_Username, "")
+	userConfig.SetDefault(configKey_SSE_Password, "")
+	userConfig.SetDefault(configKey_SSE_ConnectTimeout, sseConnectTimeout.Seconds())
+	userConfig.SetDefault(configKey_SSE_IdleTimeout, sseIdleTimeout.Seconds())
+	userConfig.SetDefault(configKey_SSE_RetryInterval, sseRetryDelay.Seconds())
 	userConfig.SetDefault(configKey_SSE_RELAY_PORT, default_SSE_RELAY_PORT)
+	userConfig.SetDefault(configKey_SSE_RELAY_PortSearch, 0)
+	userConfig.SetDefault(configKey_SSE_RELAY_Snapshot, false)
 	userConfig.SetDefault(configKey_SERIAL_PORT, default_SERIAL_PORT)
 	userConfig.SetDefault(configKey_SERIAL_BaudRate, default_SERIAL_BaudRate)
+	userConfig.SetDefault(configKey_OSC_Target, default_OSC_Target)
+	userConfig.SetDefault(configKey_MQTT_Broker, default_MQTT_Broker)
+	userConfig.SetDefault(configKey_MQTT_Topic, default_MQTT_Topic)
+	userConfig.SetDefault(configKey_TransportPreference, TransportPreferenceAuto)
+	userConfig.SetDefault(configKey_SerialIDOffset, default_SerialIDOffset)
 
 	internalConfig := viper.New()
 	internalConfig.SetConfigName(internalConfigName)
@@ -117,10 +392,11 @@ func NewConfig(logger *zap.SugaredLogger, notifier Notifier) (*CanonicalConfig,
 
 // Load reads deej's config files from disk and tries to parse them
 func (cc *CanonicalConfig) Load() error {
-	cc.logger.Debugw("Loading config", "path", userConfigFilepath)
+	configFile := findUserConfigFile()
+	cc.logger.Debugw("Loading config", "path", configFile)
 
-	if !util.FileExists(userConfigFilepath) {
-		cc.logger.Warnw("Config file not found", "path", userConfigFilepath)
+	if configFile == "" {
+		cc.logger.Warnw("Config file not found", "path", userConfigFilepath, "searched", userConfigPaths())
 		cc.notifier.Notify("Can't find configuration!",
 			fmt.Sprintf("%s must be in the same directory as deej. Please re-launch", userConfigFilepath))
 		return fmt.Errorf("config file doesn't exist: %s", userConfigFilepath)
@@ -146,17 +422,51 @@ func (cc *CanonicalConfig) Load() error {
 		return fmt.Errorf("populate config fields: %w", err)
 	}
 
+	// keep the password out of the logs
+	connectionInfo := cc.ConnectionInfo
+	if connectionInfo.SSE_Password != "" {
+		connectionInfo.SSE_Password = "***"
+	}
+
 	cc.logger.Info("Loaded config successfully")
 	cc.logger.Infow("Config values",
 		"sliderMapping", cc.SliderMapping,
 		"switchesMapping", cc.SwitchesMapping,
 		"buttonsMapping", cc.ButtonsMapping,
-		"connectionInfo", cc.ConnectionInfo,
+		"connectionInfo", connectionInfo,
 		"invertSliders", cc.InvertSliders,
+		"sliderOverflow", cc.SliderOverflow,
 		"invertSwitches", cc.InvertSwitches,
+		"switchInvert", cc.SwitchInvert,
 		"sliderOverride", cc.SliderOverride,
+		"encoderSteps", cc.EncoderSteps,
+		"sliderSpikeFilter", cc.SliderSpikeFilter,
+		"sliderFineControlSpeed", cc.SliderFineControlSpeed,
+		"ioWatchdogTimeout", cc.IOWatchdogTimeout,
 	)
 
+	// surface labels so logs read the same way the user's config does. iterate holds the map's lock,
+	// which label takes too, so the targets are collected first
+	sliderTargets := make(map[int][]string)
+	cc.SliderMapping.iterate(func(sliderIdx int, targets []string) {
+		sliderTargets[sliderIdx] = targets
+	})
+	for sliderIdx, targets := range sliderTargets {
+		if label, ok := cc.SliderMapping.label(sliderIdx); ok {
+			cc.logger.Infow("Labeled slider", "slider", sliderIdx, "label", label, "targets", targets)
+		}
+	}
+
+	switchTargets := make(map[int][]string)
+	cc.SwitchesMapping.iterate(func(switchIdx int, targets []string) {
+		switchTargets[switchIdx] = targets
+	})
+	for switchIdx, targets := range switchTargets {
+		if label, ok := cc.SwitchesMapping.label(switchIdx); ok {
+			cc.logger.Infow("Labeled switch", "switch", switchIdx, "label", label, "targets", targets)
+		}
+	}
+
 	return nil
 }
 
@@ -168,10 +478,60 @@ func (cc *CanonicalConfig) SubscribeToChanges() chan bool {
 	return c
 }
 
+// UserConfigFile returns the path of the user config file in use, or the default name if none was found
+func (cc *CanonicalConfig) UserConfigFile() string {
+	if used := cc.userConfig.ConfigFileUsed(); used != "" {
+		return used
+	}
+
+	if found := findUserConfigFile(); found != "" {
+		return found
+	}
+
+	return userConfigFilepath
+}
+
+// executablePath returns the path of the running binary. It's a variable so the lookup can be replaced
+var executablePath = os.Executable
+
+// userConfigPaths lists the directories searched for the user config, in priority order:
+// the working directory first, then the directory the executable lives in (for autostart/service launches)
+func userConfigPaths() []string {
+	paths := []string{userConfigPath}
+
+	exePath, err := executablePath()
+	if err != nil {
+		return paths
+	}
+
+	if resolved, err := filepath.EvalSymlinks(exePath); err == nil {
+		exePath = resolved
+	}
+
+	exeDir := filepath.Dir(exePath)
+	if cwd, err := os.Getwd(); err == nil && filepath.Clean(cwd) == exeDir {
+		return paths
+	}
+
+	return append(paths, exeDir)
+}
+
+// findUserConfigFile returns the first config file found in the search paths, or "" if there's none
+func findUserConfigFile() string {
+	for _, configPath := range userConfigPaths() {
+		candidate := filepath.Join(configPath, userConfigFilepath)
+		if util.FileExists(candidate) {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
 // WatchConfigFileChanges starts watching for configuration file changes
 // and attempts reloading the config when they happen
 func (cc *CanonicalConfig) WatchConfigFileChanges() {
-	cc.logger.Debugw("Starting to watch user config file for changes", "path", userConfigFilepath)
+	cc.logger.Debugw("Starting to watch user config file for changes", "path", cc.UserConfigFile())
 
 	const (
 		minTimeBetweenReloadAttempts = time.Millisecond * 500
@@ -238,14 +598,15 @@ func (cc *CanonicalConfig) closeReloadChannels() {
 
 func (cc *CanonicalConfig) populateFromVipers() error {
 
-	// merge the slider mappings from the user and internal configs
+	// merge the slider mappings from the user and internal configs.
+	// user mappings are read raw since they may use the labeled {label, targets} form
 	cc.SliderMapping = sliderMapFromConfigs(
-		cc.userConfig.GetStringMapStringSlice(configKey_SliderMapping),
+		cc.userConfig.GetStringMap(configKey_SliderMapping),
 		cc.internalConfig.GetStringMapStringSlice(configKey_SliderMapping),
 	)
 
 	cc.SwitchesMapping = switchMapFromConfigs(
-		cc.userConfig.GetStringMapStringSlice(configKey_SwitchesMapping),
+		cc.userConfig.GetStringMap(configKey_SwitchesMapping),
 		cc.internalConfig.GetStringMapStringSlice(configKey_SwitchesMapping),
 	)
 
@@ -253,13 +614,208 @@ func (cc *CanonicalConfig) populateFromVipers() error {
 	cc.ButtonsMapping = buttonsMapFromConfig(cc.userConfig, cc.logger)
 
 	cc.ConnectionInfo.SSE_URL = cc.userConfig.GetString(configKey_SSE_URL)
+	cc.ConnectionInfo.SSE_Username = cc.userConfig.GetString(configKey_SSE_Username)
+	cc.ConnectionInfo.SSE_Password = cc.userConfig.GetString(configKey_SSE_Password)
+	cc.ConnectionInfo.SSE_ConnectTimeout = cc.secondsFromConfig(configKey_SSE_ConnectTimeout, sseConnectTimeout)
+	cc.ConnectionInfo.SSE_IdleTimeout = cc.secondsFromConfig(configKey_SSE_IdleTimeout, sseIdleTimeout)
+	cc.ConnectionInfo.SSE_RetryInterval = cc.secondsFromConfig(configKey_SSE_RetryInterval, sseRetryDelay)
 	cc.ConnectionInfo.SSE_RELAY_PORT = cc.userConfig.GetInt(configKey_SSE_RELAY_PORT)
-	cc.ConnectionInfo.SERIAL_Port = cc.userConfig.GetString(configKey_SERIAL_PORT)
-	cc.ConnectionInfo.SERIAL_BaudRate = cc.userConfig.GetInt(configKey_SERIAL_BaudRate)
+	cc.ConnectionInfo.SSE_RELAY_PortSearch = cc.userConfig.GetInt(configKey_SSE_RELAY_PortSearch)
+	if cc.ConnectionInfo.SSE_RELAY_PortSearch < 0 {
+		cc.ConnectionInfo.SSE_RELAY_PortSearch = 0
+	}
+	cc.ConnectionInfo.SSE_RELAY_Snapshot = cc.userConfig.GetBool(configKey_SSE_RELAY_Snapshot)
+	cc.ConnectionInfo.SERIAL_Port, cc.ConnectionInfo.SERIAL_ExtraPorts = cc.serialPortsFromConfig()
+	cc.ConnectionInfo.SerialIDOffset = cc.userConfig.GetInt(configKey_SerialIDOffset)
+	if cc.ConnectionInfo.SerialIDOffset <= 0 {
+		cc.logger.Warnw("Invalid serial_id_offset, using default", "value", cc.ConnectionInfo.SerialIDOffset, "default", default_SerialIDOffset)
+		cc.ConnectionInfo.SerialIDOffset = default_SerialIDOffset
+	}
+	cc.ConnectionInfo.SERIAL_BaudRates = cc.serialBaudRatesFromConfig()
+	cc.ConnectionInfo.SERIAL_BaudRate = 0
+	if len(cc.ConnectionInfo.SERIAL_BaudRates) > 0 {
+		cc.ConnectionInfo.SERIAL_BaudRate = cc.ConnectionInfo.SERIAL_BaudRates[0]
+	}
+	cc.ConnectionInfo.OSC_Target = cc.userConfig.GetString(configKey_OSC_Target)
+	cc.ConnectionInfo.MQTT_Broker = cc.userConfig.GetString(configKey_MQTT_Broker)
+	cc.ConnectionInfo.MQTT_Topic = cc.userConfig.GetString(configKey_MQTT_Topic)
+
+	cc.ConnectionInfo.TransportPreference = strings.ToLower(strings.TrimSpace(cc.userConfig.GetString(configKey_TransportPreference)))
+	switch cc.ConnectionInfo.TransportPreference {
+	case TransportPreferenceAuto, TransportPreferenceSerial, TransportPreferenceSSE:
+	default:
+		cc.logger.Warnw("Invalid transport_preference, using auto", "value", cc.ConnectionInfo.TransportPreference)
+		cc.ConnectionInfo.TransportPreference = TransportPreferenceAuto
+	}
+
+	cc.MasterDevice = strings.TrimSpace(cc.userConfig.GetString(configKey_MasterDevice))
 
 	cc.InvertSliders = cc.userConfig.GetBool(configKey_InvertSliders)
+
+	cc.SliderOverflow = strings.ToLower(strings.TrimSpace(cc.userConfig.GetString(configKey_SliderOverflow)))
+	switch cc.SliderOverflow {
+	case SliderOverflowClamp, SliderOverflowWrap:
+	default:
+		cc.logger.Warnw("Invalid slider_overflow, using clamp", "value", cc.SliderOverflow)
+		cc.SliderOverflow = SliderOverflowClamp
+	}
 	cc.InvertSwitches = cc.userConfig.GetBool(configKey_InvertSwitches)
 
+	// Load per-switch inversion and device-level polarity
+	cc.SwitchInvert = cc.switchBoolMapFromConfig(configKey_SwitchInvert)
+	cc.SwitchActiveLow = cc.switchBoolMapFromConfig(configKey_SwitchActiveLow)
+
+	cc.SliderIDPattern = cc.idPatternFromConfig(configKey_SliderIDPattern, default_SliderIDPattern)
+	cc.SwitchIDPattern = cc.idPatternFromConfig(configKey_SwitchIDPattern, default_SwitchIDPattern)
+	cc.NumberIDPattern = cc.idPatternFromConfig(configKey_NumberIDPattern, default_NumberIDPattern)
+	cc.SelectIDPattern = cc.idPatternFromConfig(configKey_SelectIDPattern, default_SelectIDPattern)
+
+	cc.SelectOnStates = make(map[string]bool)
+	for _, option := range cc.userConfig.GetStringSlice(configKey_SelectOnStates) {
+		cc.SelectOnStates[strings.ToLower(strings.TrimSpace(option))] = true
+	}
+
+	cc.SliderRemap = cc.remapFromConfig(configKey_SliderRemap)
+	cc.SwitchRemap = cc.remapFromConfig(configKey_SwitchRemap)
+
+	// Load push-to-talk and solo switches
+	cc.SwitchPTT = cc.switchSetFromConfig(configKey_SwitchPTT)
+	cc.SwitchSolo = cc.switchSetFromConfig(configKey_SwitchSolo)
+	for switchIdx := range cc.SwitchSolo {
+		if cc.SwitchPTT[switchIdx] {
+			cc.logger.Warnw("Switch is both push-to-talk and solo, using push-to-talk", "switch", switchIdx)
+			delete(cc.SwitchSolo, switchIdx)
+		}
+	}
+
+	pttReleaseMs := cc.userConfig.GetInt(configKey_SwitchPTTRelease)
+	if pttReleaseMs < 0 {
+		cc.logger.Warnw("Invalid switch_ptt_release_ms, using default", "value", pttReleaseMs, "default", default_SwitchPTTReleaseMs)
+		pttReleaseMs = default_SwitchPTTReleaseMs
+	}
+	cc.SwitchPTTRelease = time.Duration(pttReleaseMs) * time.Millisecond
+
+	cc.RestoreSwitches = cc.userConfig.GetBool(configKey_RestoreSwitches)
+
+	// Load mute groups
+	cc.MuteGroups = make(map[string][]string)
+	for groupName, value := range cc.userConfig.GetStringMap(configKey_MuteGroups) {
+		members, err := cast.ToStringSliceE(value)
+		if err != nil || len(members) < 2 {
+			cc.logger.Warnw("Invalid mute group, expected a list of at least two targets", "group", groupName, "value", value)
+			continue
+		}
+
+		for memberIdx, member := range members {
+			members[memberIdx] = strings.ToLower(strings.TrimSpace(member))
+		}
+
+		cc.MuteGroups[groupName] = members
+	}
+
+	// Load the device commands sent when a switch mutes or unmutes its targets
+	cc.MuteIndicators = make(map[int]MuteIndicator)
+	for switchIdxString, value := range cc.userConfig.GetStringMap(configKey_MuteIndicator) {
+		switchIdx, err := strconv.Atoi(switchIdxString)
+		if err != nil {
+			cc.logger.Warnw("Invalid switch index in mute_indicator", "index", switchIdxString, "error", err)
+			continue
+		}
+
+		if value == nil {
+			continue
+		}
+
+		commands, err := cast.ToStringMapStringE(value)
+		if err != nil {
+			cc.logger.Warnw("Invalid mute_indicator entry, expected muted/unmuted commands", "switch", switchIdx, "value", value)
+			continue
+		}
+
+		indicator := MuteIndicator{Muted: commands["muted"], Unmuted: commands["unmuted"]}
+		if indicator.Muted == "" && indicator.Unmuted == "" {
+			cc.logger.Warnw("mute_indicator entry has neither muted nor unmuted command", "switch", switchIdx)
+			continue
+		}
+
+		cc.MuteIndicators[switchIdx] = indicator
+	}
+
+	cc.SliderSpikeFilter = cc.userConfig.GetInt(configKey_SliderSpikeFilter)
+	if cc.SliderSpikeFilter < 0 || cc.SliderSpikeFilter > 100 {
+		cc.logger.Warnw("Invalid slider_spike_filter, disabling", "value", cc.SliderSpikeFilter)
+		cc.SliderSpikeFilter = 0
+	}
+
+	cc.SliderFineControlSpeed = 0
+	if cc.userConfig.GetBool(configKey_SliderFineControl) {
+		cc.SliderFineControlSpeed = cc.userConfig.GetFloat64(configKey_SliderFineControlSpeed)
+		if cc.SliderFineControlSpeed <= 0 {
+			cc.logger.Warnw("Invalid slider_fine_control_speed, using default",
+				"value", cc.SliderFineControlSpeed, "default", default_SliderFineControlSpeed)
+			cc.SliderFineControlSpeed = default_SliderFineControlSpeed
+		}
+	}
+
+	cc.IOWatchdogTimeout = 0
+	if seconds := cc.userConfig.GetInt(configKey_IOWatchdogTimeout); seconds > 0 {
+		cc.IOWatchdogTimeout = time.Duration(seconds) * time.Second
+	}
+
+	cc.IdleDimAfter = 0
+	if seconds := cc.userConfig.GetInt(configKey_IdleDimAfter); seconds > 0 {
+		cc.IdleDimAfter = time.Duration(seconds) * time.Second
+	}
+	cc.IdleDimCommand = cc.userConfig.GetString(configKey_IdleDimCommand)
+	cc.IdleRestoreCommand = cc.userConfig.GetString(configKey_IdleRestoreCommand)
+
+	cc.ShutdownTimeout = cc.secondsFromConfig(configKey_ShutdownTimeout, defaultShutdownTimeout)
+
+	cc.SerialPingInterval = 0
+	if seconds := cc.userConfig.GetFloat64(configKey_SerialPingInterval); seconds > 0 {
+		cc.SerialPingInterval = time.Duration(seconds * float64(time.Second))
+		cc.SerialPingTimeout = cc.secondsFromConfig(configKey_SerialPingTimeout, default_SerialPingTimeout)
+	}
+	cc.SerialPingCommand = strings.TrimSpace(cc.userConfig.GetString(configKey_SerialPingCommand))
+	if cc.SerialPingInterval > 0 && cc.SerialPingCommand == "" {
+		cc.logger.Warnw("Empty serial_ping_command, using default", "default", default_SerialPingCommand)
+		cc.SerialPingCommand = default_SerialPingCommand
+	}
+	cc.ConnectionNotifications = cc.userConfig.GetBool(configKey_ConnectionNotifications)
+
+	cc.Editor = cc.userConfig.GetString(configKey_Editor)
+
+	cc.SessionFilter = cc.userConfig.GetBool(configKey_SessionFilter)
+	cc.SessionLogLimit = cc.userConfig.GetInt(configKey_SessionLogLimit)
+	if cc.SessionLogLimit < 0 {
+		cc.SessionLogLimit = 0
+	}
+
+	cc.UnmappedIncludes = make(map[string]bool)
+	for _, class := range cc.userConfig.GetStringSlice(configKey_UnmappedIncludes) {
+		class = strings.ToLower(strings.TrimSpace(class))
+		switch class {
+		case UnmappedIncludeMaster, UnmappedIncludeSystem, UnmappedIncludeMic, UnmappedIncludeDevices:
+			cc.UnmappedIncludes[class] = true
+		default:
+			cc.logger.Warnw("Unknown session class in unmapped_includes", "class", class)
+		}
+	}
+
+	cc.SessionRefreshCooldown = cc.secondsFromConfig(configKey_SessionRefreshCooldown, minTimeBetweenSessionRefreshes)
+	cc.SessionRefreshMaxAge = cc.secondsFromConfig(configKey_SessionRefreshMaxAge, maxTimeBetweenSessionRefreshes)
+	if cc.SessionRefreshMaxAge < cc.SessionRefreshCooldown {
+		cc.logger.Warnw("session_refresh_max_age is shorter than session_refresh_cooldown, using the cooldown",
+			"max_age", cc.SessionRefreshMaxAge, "cooldown", cc.SessionRefreshCooldown)
+		cc.SessionRefreshMaxAge = cc.SessionRefreshCooldown
+	}
+
+	cc.SessionRefreshMaxRate = cc.userConfig.GetInt(configKey_SessionRefreshMaxRate)
+	if cc.SessionRefreshMaxRate <= 0 {
+		cc.logger.Warnw("Invalid session_refresh_max_rate, using default", "value", cc.SessionRefreshMaxRate)
+		cc.SessionRefreshMaxRate = maxFailureRefreshesPerSecond
+	}
+
 	// Load slider override map
 	cc.SliderOverride = make(map[int]int)
 	overrideMap := cc.userConfig.GetStringMap(configKey_SliderOverride)
@@ -312,11 +868,544 @@ func (cc *CanonicalConfig) populateFromVipers() error {
 		cc.SliderOverride[sliderIdx] = percent
 	}
 
+	// Load per-encoder step sizes
+	cc.EncoderSteps = make(map[int]int)
+	for encoderIdxString, value := range cc.userConfig.GetStringMap(configKey_EncoderSteps) {
+		encoderIdx, err := strconv.Atoi(encoderIdxString)
+		if err != nil {
+			cc.logger.Warnw("Invalid encoder index in encoder_steps", "index", encoderIdxString, "error", err)
+			continue
+		}
+
+		// nil means use the default step for this encoder
+		if value == nil {
+			continue
+		}
+
+		step, err := cast.ToIntE(value)
+		if err != nil || step <= 0 || step > 100 {
+			cc.logger.Warnw("Invalid encoder step, using default", "encoder", encoderIdx, "value", value, "default", default_EncoderStep)
+			continue
+		}
+
+		cc.EncoderSteps[encoderIdx] = step
+	}
+
+	// Load jog sliders and their max rates
+	cc.SliderJog = make(map[int]float64)
+	for sliderIdxString, value := range cc.userConfig.GetStringMap(configKey_SliderJog) {
+		sliderIdx, err := strconv.Atoi(sliderIdxString)
+		if err != nil {
+			cc.logger.Warnw("Invalid slider index in slider_jog", "index", sliderIdxString, "error", err)
+			continue
+		}
+
+		// nil means jog at the default rate
+		if value == nil {
+			cc.SliderJog[sliderIdx] = default_SliderJogRate
+			continue
+		}
+
+		rate, err := cast.ToFloat64E(value)
+		if err != nil || rate <= 0 {
+			cc.logger.Warnw("Invalid slider_jog rate, using default", "slider", sliderIdx, "value", value, "default", default_SliderJogRate)
+			rate = default_SliderJogRate
+		}
+
+		cc.SliderJog[sliderIdx] = rate
+	}
+
+	// Load per-slider expo curves
+	cc.SliderExpo = make(map[int]float64)
+	for sliderIdxString, value := range cc.userConfig.GetStringMap(configKey_SliderExpo) {
+		sliderIdx, err := strconv.Atoi(sliderIdxString)
+		if err != nil {
+			cc.logger.Warnw("Invalid slider index in slider_expo", "index", sliderIdxString, "error", err)
+			continue
+		}
+
+		expo, err := cast.ToFloat64E(value)
+		if err != nil || expo < 0 || expo > 1 {
+			cc.logger.Warnw("Invalid slider_expo value, expected 0 to 1", "slider", sliderIdx, "value", value)
+			continue
+		}
+
+		// 0 is linear, same as not listing the slider
+		if expo > 0 {
+			cc.SliderExpo[sliderIdx] = expo
+		}
+	}
+
+	// Load fine sliders and the coarse sliders they adjust
+	cc.SliderFine = make(map[int]SliderFinePair)
+	fineOfCoarse := make(map[int]int)
+	for sliderIdxString, value := range cc.userConfig.GetStringMap(configKey_SliderFine) {
+		sliderIdx, err := strconv.Atoi(sliderIdxString)
+		if err != nil {
+			cc.logger.Warnw("Invalid slider index in slider_fine", "index", sliderIdxString, "error", err)
+			continue
+		}
+
+		entry, err := cast.ToStringMapE(value)
+		if err != nil {
+			cc.logger.Warnw("Invalid slider_fine entry, expected coarse and range", "slider", sliderIdx, "value", value)
+			continue
+		}
+
+		coarse, err := cast.ToIntE(entry["coarse"])
+		if err != nil || coarse == sliderIdx {
+			cc.logger.Warnw("Invalid slider_fine coarse slider", "slider", sliderIdx, "coarse", entry["coarse"])
+			continue
+		}
+
+		// one fine slider per coarse slider, otherwise they'd fight over the volume
+		if other, taken := fineOfCoarse[coarse]; taken {
+			cc.logger.Warnw("Coarse slider already has a fine slider, ignoring this one", "slider", sliderIdx, "coarse", coarse, "fine", other)
+			continue
+		}
+
+		rangePercent := float64(default_SliderFineRange)
+		if rawRange, ok := entry["range"]; ok && rawRange != nil {
+			if rangePercent, err = cast.ToFloat64E(rawRange); err != nil || rangePercent <= 0 || rangePercent > 50 {
+				cc.logger.Warnw("Invalid slider_fine range, using default", "slider", sliderIdx, "value", rawRange, "default", default_SliderFineRange)
+				rangePercent = default_SliderFineRange
+			}
+		}
+
+		fineOfCoarse[coarse] = sliderIdx
+		cc.SliderFine[sliderIdx] = SliderFinePair{Coarse: coarse, Range: rangePercent}
+	}
+
+	// Load per-target slider weights
+	cc.SliderWeights = make(map[int]map[string]float64)
+	for sliderIdxString, value := range cc.userConfig.GetStringMap(configKey_SliderWeights) {
+		sliderIdx, err := strconv.Atoi(sliderIdxString)
+		if err != nil {
+			cc.logger.Warnw("Invalid slider index in slider_weights", "index", sliderIdxString, "error", err)
+			continue
+		}
+
+		// nil means no weights for this slider
+		if value == nil {
+			continue
+		}
+
+		targetWeights, err := cast.ToStringMapE(value)
+		if err != nil {
+			cc.logger.Warnw("Invalid slider_weights entry, expected target: weight pairs", "slider", sliderIdx, "value", value)
+			continue
+		}
+
+		weights := make(map[string]float64)
+		for target, rawWeight := range targetWeights {
+			weight, err := cast.ToFloat64E(rawWeight)
+			if err != nil || weight == 0 || weight < -1 || weight > 1 {
+				cc.logger.Warnw("Invalid slider weight, must be between -1 and 1 and not 0", "slider", sliderIdx, "target", target, "value", rawWeight)
+				continue
+			}
+
+			weights[strings.ToLower(target)] = weight
+		}
+
+		if len(weights) > 0 {
+			cc.SliderWeights[sliderIdx] = weights
+		}
+	}
+
+	// Load per-target default volumes
+	cc.TargetDefaultVolume = make(map[string]float32)
+	for target, value := range cc.userConfig.GetStringMap(configKey_TargetDefaultVolume) {
+		// nil means no default for this target
+		if value == nil {
+			continue
+		}
+
+		percent, err := cast.ToFloat64E(value)
+		if err != nil || percent < 0 || percent > 100 {
+			cc.logger.Warnw("Invalid target_default_volume, must be between 0 and 100", "target", target, "value", value)
+			continue
+		}
+
+		cc.TargetDefaultVolume[strings.ToLower(target)] = float32(percent / 100)
+	}
+
+	// Load per-slider snap points
+	cc.SliderSnap = make(map[int][]float64)
+	for sliderIdxString, value := range cc.userConfig.GetStringMap(configKey_SliderSnap) {
+		sliderIdx, err := strconv.Atoi(sliderIdxString)
+		if err != nil {
+			cc.logger.Warnw("Invalid slider index in slider_snap", "index", sliderIdxString, "error", err)
+			continue
+		}
+
+		// nil means this slider doesn't snap
+		if value == nil {
+			continue
+		}
+
+		rawPoints, err := cast.ToSliceE(value)
+		if err != nil {
+			cc.logger.Warnw("Invalid slider_snap entry, expected a list of percentages", "slider", sliderIdx, "value", value)
+			continue
+		}
+
+		points := []float64{}
+		for _, rawPoint := range rawPoints {
+			point, err := cast.ToFloat64E(rawPoint)
+			if err != nil || point < 0 || point > 100 {
+				cc.logger.Warnw("Invalid slider snap point, must be between 0 and 100", "slider", sliderIdx, "value", rawPoint)
+				continue
+			}
+			points = append(points, point)
+		}
+
+		if len(points) > 0 {
+			sort.Float64s(points)
+			cc.SliderSnap[sliderIdx] = points
+		}
+	}
+
+	cc.SliderSnapTolerance = cc.userConfig.GetFloat64(configKey_SliderSnapTolerance)
+	if cc.SliderSnapTolerance <= 0 || cc.SliderSnapTolerance > 50 {
+		cc.logger.Warnw("Invalid slider_snap_tolerance, using default", "value", cc.SliderSnapTolerance, "default", default_SliderSnapTolerance)
+		cc.SliderSnapTolerance = default_SliderSnapTolerance
+	}
+
 	cc.logger.Debug("Populated config fields from vipers")
 
 	return nil
 }
 
+// secondsFromConfig reads a positive duration given in (possibly fractional) seconds, falling back to fallback
+func (cc *CanonicalConfig) secondsFromConfig(key string, fallback time.Duration) time.Duration {
+	seconds := cc.userConfig.GetFloat64(key)
+	if seconds <= 0 {
+		cc.logger.Warnw("Invalid duration in config, using default", "key", key, "value", seconds, "default", fallback)
+		return fallback
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// ExpandMuteGroups returns targets plus the other members of every mute group one of them belongs to
+func (cc *CanonicalConfig) ExpandMuteGroups(targets []string) []string {
+	if len(cc.MuteGroups) == 0 {
+		return targets
+	}
+
+	expanded := make([]string, 0, len(targets))
+	seen := make(map[string]bool)
+	add := func(target string) {
+		if key := strings.ToLower(target); !seen[key] {
+			seen[key] = true
+			expanded = append(expanded, target)
+		}
+	}
+
+	for _, target := range targets {
+		add(target)
+		for _, members := range cc.MuteGroups {
+			if slices.Contains(members, strings.ToLower(target)) {
+				for _, member := range members {
+					add(member)
+				}
+			}
+		}
+	}
+
+	return expanded
+}
+
+// switchSetFromConfig reads a list of switch indices (e.g. switch_ptt: [1, 2]) into a set
+func (cc *CanonicalConfig) switchSetFromConfig(key string) map[int]bool {
+	result := make(map[int]bool)
+
+	raw := cc.userConfig.Get(key)
+	if raw == nil {
+		return result
+	}
+
+	switches, err := cast.ToIntSliceE(raw)
+	if err != nil {
+		cc.logger.Warnw("Invalid switch list, expected a list of switch indices", "key", key, "value", raw)
+		return result
+	}
+
+	for _, switchIdx := range switches {
+		result[switchIdx] = true
+	}
+
+	return result
+}
+
+// switchBoolMapFromConfig reads a per-switch map of booleans, e.g. switch_invert
+func (cc *CanonicalConfig) switchBoolMapFromConfig(key string) map[int]bool {
+	result := make(map[int]bool)
+
+	for switchIdxString, value := range cc.userConfig.GetStringMap(key) {
+		switchIdx, err := strconv.Atoi(switchIdxString)
+		if err != nil {
+			cc.logger.Warnw("Invalid switch index", "key", key, "index", switchIdxString, "error", err)
+			continue
+		}
+
+		// nil means leave this switch alone
+		if value == nil {
+			continue
+		}
+
+		enabled, err := cast.ToBoolE(value)
+		if err != nil {
+			cc.logger.Warnw("Invalid switch value", "key", key, "switch", switchIdx, "value", value, "error", err)
+			continue
+		}
+
+		result[switchIdx] = enabled
+	}
+
+	return result
+}
+
+// idPatternFromConfig compiles an event id pattern, e.g. slider_id_pattern. It must have a capture group
+// for the index; otherwise the default pattern is used
+func (cc *CanonicalConfig) idPatternFromConfig(key string, fallback string) *regexp.Regexp {
+	expr := strings.TrimSpace(cc.userConfig.GetString(key))
+	if expr == "" {
+		expr = fallback
+	}
+
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		cc.logger.Warnw("Invalid id pattern, using default", "key", key, "pattern", expr, "error", err, "default", fallback)
+		return regexp.MustCompile(fallback)
+	}
+
+	if pattern.NumSubexp() < 1 {
+		cc.logger.Warnw("Id pattern has no capture group for the index, using default", "key", key, "pattern", expr, "default", fallback)
+		return regexp.MustCompile(fallback)
+	}
+
+	return pattern
+}
+
+// remapFromConfig reads a device id -> logical id table, e.g. slider_remap. Two device ids can't share
+// a logical id, so on a collision only the lowest device id keeps it
+func (cc *CanonicalConfig) remapFromConfig(key string) map[int]int {
+	result := make(map[int]int)
+
+	raw := cc.userConfig.GetStringMap(key)
+	deviceIDs := make([]int, 0, len(raw))
+	values := make(map[int]interface{}, len(raw))
+
+	for deviceIdxString, value := range raw {
+		deviceIdx, err := strconv.Atoi(deviceIdxString)
+		if err != nil || deviceIdx < 0 {
+			cc.logger.Warnw("Invalid device id in remap table", "key", key, "id", deviceIdxString)
+			continue
+		}
+
+		// nil means keep this id as it is
+		if value == nil {
+			continue
+		}
+
+		deviceIDs = append(deviceIDs, deviceIdx)
+		values[deviceIdx] = value
+	}
+
+	sort.Ints(deviceIDs)
+
+	takenBy := make(map[int]int)
+	for _, deviceIdx := range deviceIDs {
+		logicalIdx, err := cast.ToIntE(values[deviceIdx])
+		if err != nil || logicalIdx < 0 {
+			cc.logger.Warnw("Invalid logical id in remap table", "key", key, "device", deviceIdx, "value", values[deviceIdx])
+			continue
+		}
+
+		if other, taken := takenBy[logicalIdx]; taken {
+			cc.logger.Warnw("Remap collision, two device ids map to the same id",
+				"key", key, "id", logicalIdx, "kept", other, "ignored", deviceIdx)
+			continue
+		}
+
+		takenBy[logicalIdx] = deviceIdx
+		result[deviceIdx] = logicalIdx
+	}
+
+	return result
+}
+
+// RemapSlider translates a slider id as sent by the device into the one used in the config
+func (cc *CanonicalConfig) RemapSlider(deviceIdx int) int {
+	if logicalIdx, ok := cc.SliderRemap[deviceIdx]; ok {
+		return logicalIdx
+	}
+
+	return deviceIdx
+}
+
+// RemapSwitch translates a switch id as sent by the device into the one used in the config
+func (cc *CanonicalConfig) RemapSwitch(deviceIdx int) int {
+	if logicalIdx, ok := cc.SwitchRemap[deviceIdx]; ok {
+		return logicalIdx
+	}
+
+	return deviceIdx
+}
+
+// serialPortsFromConfig reads SERIAL_Port, which may be a single port or a list of them. The first
+// port is the regular serial transport, the rest are extra boards
+func (cc *CanonicalConfig) serialPortsFromConfig() (string, []string) {
+	raw := cc.userConfig.Get(configKey_SERIAL_PORT)
+
+	list, ok := raw.([]interface{})
+	if !ok {
+		return cc.userConfig.GetString(configKey_SERIAL_PORT), nil
+	}
+
+	ports := []string{}
+	for _, value := range list {
+		port, err := cast.ToStringE(value)
+		port = strings.TrimSpace(port)
+		if err != nil || port == "" {
+			cc.logger.Warnw("Invalid serial port, ignoring", "value", value)
+			continue
+		}
+		if slices.Contains(ports, port) {
+			cc.logger.Warnw("Serial port listed twice, ignoring the repeat", "port", port)
+			continue
+		}
+		ports = append(ports, port)
+	}
+
+	if len(ports) == 0 {
+		return "", nil
+	}
+
+	return ports[0], ports[1:]
+}
+
+// serialBaudRatesFromConfig reads SERIAL_BaudRate, which may be a single value or a list of candidates
+func (cc *CanonicalConfig) serialBaudRatesFromConfig() []int {
+	raw := cc.userConfig.Get(configKey_SERIAL_BaudRate)
+
+	var values []interface{}
+	if list, ok := raw.([]interface{}); ok {
+		values = list
+	} else {
+		values = []interface{}{raw}
+	}
+
+	baudRates := []int{}
+	for _, value := range values {
+		baud, err := cast.ToIntE(value)
+		if err != nil || baud < 0 {
+			cc.logger.Warnw("Invalid serial baud rate, ignoring", "value", value)
+			continue
+		}
+
+		// 0 keeps its historical meaning of "serial disabled"
+		if baud > 0 {
+			baudRates = append(baudRates, baud)
+		}
+	}
+
+	return baudRates
+}
+
+// transport_preference values: auto tries serial first and falls back to SSE, the others use only that transport
+const (
+	TransportPreferenceAuto   = "auto"
+	TransportPreferenceSerial = "serial"
+	TransportPreferenceSSE    = "sse"
+)
+
+// slider_overflow values: what happens to readings outside 0-100, clamp pins them to the nearest end
+// while wrap carries them around to the other end, for rotary setups that go past a full turn
+const (
+	SliderOverflowClamp = "clamp"
+	SliderOverflowWrap  = "wrap"
+)
+
+// unmapped_includes values: session classes deej.unmapped skips unless they're listed
+const (
+	UnmappedIncludeMaster  = "master"
+	UnmappedIncludeSystem  = "system"
+	UnmappedIncludeMic     = "mic"
+	UnmappedIncludeDevices = "devices"
+)
+
+// SerialEnabled reports whether the serial transport is configured and allowed by transport_preference
+func (cc *CanonicalConfig) SerialEnabled() bool {
+	return cc.ConnectionInfo.SERIAL_Port != "" && cc.ConnectionInfo.SERIAL_BaudRate != 0 &&
+		cc.ConnectionInfo.TransportPreference != TransportPreferenceSSE
+}
+
+// SSEEnabled reports whether the SSE transport is configured and allowed by transport_preference
+func (cc *CanonicalConfig) SSEEnabled() bool {
+	return cc.ConnectionInfo.SSE_URL != "" && cc.ConnectionInfo.TransportPreference != TransportPreferenceSerial
+}
+
+// SwitchInverted reports whether a switch's state should be flipped. A per-switch switch_invert
+// entry flips it relative to invert_switches, so both set means not inverted
+func (cc *CanonicalConfig) SwitchInverted(switchIdx int) bool {
+	return cc.InvertSwitches != cc.SwitchInvert[switchIdx]
+}
+
+// EncoderStep returns the percent an encoder moves per detent
+func (cc *CanonicalConfig) EncoderStep(encoderIdx int) int {
+	if step, ok := cc.EncoderSteps[encoderIdx]; ok {
+		return step
+	}
+
+	return default_EncoderStep
+}
+
+// SnapSlider moves a slider's 0-1 value onto its nearest slider_snap point, if one is within the tolerance
+func (cc *CanonicalConfig) SnapSlider(sliderIdx int, value float32) float32 {
+	points, ok := cc.SliderSnap[sliderIdx]
+	if !ok {
+		return value
+	}
+
+	percent := float64(value) * 100
+	nearest, nearestDistance := 0.0, math.Inf(1)
+	for _, point := range points {
+		if distance := math.Abs(percent - point); distance < nearestDistance {
+			nearest, nearestDistance = point, distance
+		}
+	}
+
+	if nearestDistance > cc.SliderSnapTolerance {
+		return value
+	}
+
+	return float32(nearest / 100)
+}
+
+// WeightedVolume applies a target's slider_weights entry to a slider position. A positive weight w gives
+// w*percent, a negative one 1+w*percent, so -1 runs the target opposite to the slider (crossfade).
+// Targets without a weight follow the slider as usual
+func (cc *CanonicalConfig) WeightedVolume(sliderIdx int, target string, percent float32) float32 {
+	weight, ok := cc.SliderWeights[sliderIdx][strings.ToLower(target)]
+	if !ok {
+		return percent
+	}
+
+	volume := float32(weight) * percent
+	if weight < 0 {
+		volume += 1
+	}
+
+	if volume < 0 {
+		return 0
+	} else if volume > 1 {
+		return 1
+	}
+	return volume
+}
+
 func (cc *CanonicalConfig) onConfigReloaded() {
 	cc.logger.Debug("Notifying consumers about configuration reload")
 
@@ -337,3 +1426,97 @@ func (cc *CanonicalConfig) onConfigReloaded() {
 		}()
 	}
 }
+
+// SavedSwitchStates returns the switch states last saved with SaveSwitchStates, by switch id
+func (cc *CanonicalConfig) SavedSwitchStates() map[int]bool {
+	cc.internalConfigMutex.Lock()
+	defer cc.internalConfigMutex.Unlock()
+
+	states := make(map[int]bool)
+	for switchIdxString, value := range cc.internalConfig.GetStringMap(internalKey_SwitchStates) {
+		switchIdx, err := strconv.Atoi(switchIdxString)
+		if err != nil {
+			continue
+		}
+
+		state, err := cast.ToBoolE(value)
+		if err != nil {
+			cc.logger.Debugw("Ignoring invalid saved switch state", "switch", switchIdx, "value", value)
+			continue
+		}
+
+		states[switchIdx] = state
+	}
+
+	return states
+}
+
+// SaveSwitchStates writes the switch states to the internal config (logs/preferences.yaml),
+// so they can be restored on the next start
+func (cc *CanonicalConfig) SaveSwitchStates(states map[int]bool) error {
+	cc.internalConfigMutex.Lock()
+	defer cc.internalConfigMutex.Unlock()
+
+	saved := make(map[string]bool, len(states))
+	for switchIdx, state := range states {
+		saved[strconv.Itoa(switchIdx)] = state
+	}
+	cc.internalConfig.Set(internalKey_SwitchStates, saved)
+
+	return cc.writeInternalConfig()
+}
+
+// SaveMapping replaces the slider_mapping or switches_mapping part of the internal config (logs/preferences.yaml)
+// with mapping, merges it with the user config's mapping like Load does and notifies consumers, so everything
+// re-binds to the result. It's how mappings are edited at runtime without touching config.yaml
+func (cc *CanonicalConfig) SaveMapping(key string, mapping map[int][]string) error {
+	if key != configKey_SliderMapping && key != configKey_SwitchesMapping {
+		return fmt.Errorf("not a mapping key: %s", key)
+	}
+
+	cc.internalConfigMutex.Lock()
+
+	saved := make(map[string][]string, len(mapping))
+	for idx, targets := range mapping {
+		saved[strconv.Itoa(idx)] = targets
+	}
+	cc.internalConfig.Set(key, saved)
+
+	if key == configKey_SliderMapping {
+		cc.SliderMapping = sliderMapFromConfigs(
+			cc.userConfig.GetStringMap(configKey_SliderMapping),
+			cc.internalConfig.GetStringMapStringSlice(configKey_SliderMapping),
+		)
+	} else {
+		cc.SwitchesMapping = switchMapFromConfigs(
+			cc.userConfig.GetStringMap(configKey_SwitchesMapping),
+			cc.internalConfig.GetStringMapStringSlice(configKey_SwitchesMapping),
+		)
+	}
+
+	err := cc.writeInternalConfig()
+	cc.internalConfigMutex.Unlock()
+
+	// the new mapping is active either way, it just won't survive a restart
+	if err != nil {
+		cc.logger.Warnw("Failed to persist mapping", "key", key, "error", err)
+	}
+
+	cc.onConfigReloaded()
+
+	return err
+}
+
+// writeInternalConfig writes the internal config to logs/preferences.yaml. Callers hold internalConfigMutex
+func (cc *CanonicalConfig) writeInternalConfig() error {
+	if err := util.EnsureDirExists(internalConfigPath); err != nil {
+		return fmt.Errorf("ensure internal config directory exists: %w", err)
+	}
+
+	internalConfigFilepath := path.Join(internalConfigPath, internalConfigName+"."+configType)
+	if err := cc.internalConfig.WriteConfigAs(internalConfigFilepath); err != nil {
+		return fmt.Errorf("write internal config: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,325 @@
+package deej
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// testNotifier drops notifications, tests check logs and state instead
+type testNotifier struct{}
+
+func (testNotifier) Notify(title string, message string) {}
+
+// recordingNotifier keeps the titles of the notifications it was asked to show
+type recordingNotifier struct {
+	mu     sync.Mutex
+	titles []string
+}
+
+func (n *recordingNotifier) Notify(title string, message string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.titles = append(n.titles, title)
+}
+
+func (n *recordingNotifier) Titles() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return append([]string(nil), n.titles...)
+}
+
+// newTestConfig builds a config from a config.yaml body, with the internal config kept in a temp dir
+func newTestConfig(t *testing.T, userYAML string) *CanonicalConfig {
+	t.Helper()
+
+	previousPath := internalConfigPath
+	internalConfigPath = t.TempDir()
+	t.Cleanup(func() { internalConfigPath = previousPath })
+
+	cc, err := NewConfig(zap.NewNop().Sugar(), testNotifier{})
+	if err != nil {
+		t.Fatalf("NewConfig: %v", err)
+	}
+
+	cc.userConfig.SetConfigType(configType)
+	if err := cc.userConfig.ReadConfig(strings.NewReader(userYAML)); err != nil {
+		t.Fatalf("read test config: %v", err)
+	}
+
+	if err := cc.populateFromVipers(); err != nil {
+		t.Fatalf("populateFromVipers: %v", err)
+	}
+
+	return cc
+}
+
+func TestSaveMapping(t *testing.T) {
+	cc := newTestConfig(t, "slider_mapping:\n  0: master\n")
+
+	// buffered, unlike SubscribeToChanges, so the notification isn't dropped while nobody is receiving
+	reloaded := make(chan bool, 1)
+	cc.reloadConsumers = append(cc.reloadConsumers, reloaded)
+
+	if err := cc.SaveMapping(configKey_SliderMapping, map[int][]string{0: {"spotify.exe"}, 2: {"discord.exe"}}); err != nil {
+		t.Fatalf("SaveMapping: %v", err)
+	}
+
+	select {
+	case <-reloaded:
+	default:
+		t.Error("SaveMapping didn't notify reload consumers")
+	}
+
+	tests := []struct {
+		slider int
+		want   []string
+	}{
+		{0, []string{"master", "spotify.exe"}},
+		{2, []string{"discord.exe"}},
+	}
+
+	for _, tt := range tests {
+		got, _ := cc.SliderMapping.get(tt.slider)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("slider %d: got %v, want %v", tt.slider, got, tt.want)
+		}
+	}
+
+	// a fresh config reading the same preferences.yaml sees the saved mapping
+	cc.internalConfig.Set(configKey_SliderMapping, nil)
+	if err := cc.internalConfig.ReadInConfig(); err != nil {
+		t.Fatalf("read saved internal config: %v", err)
+	}
+	saved := cc.internalConfig.GetStringMapStringSlice(configKey_SliderMapping)
+	if strings.Join(saved["2"], ",") != "discord.exe" {
+		t.Errorf("saved mapping: got %v", saved)
+	}
+
+	if err := cc.SaveMapping("invert_sliders", nil); err == nil {
+		t.Error("SaveMapping accepted a key that isn't a mapping")
+	}
+}
+
+func TestSwitchInverted(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		switch0 bool
+		switch1 bool
+	}{
+		{"neither", "", false, false},
+		{"global only", "invert_switches: true\n", true, true},
+		{"per-switch only", "switch_invert:\n  0: true\n", true, false},
+		{"global and per-switch cancel out", "invert_switches: true\nswitch_invert:\n  0: true\n", false, true},
+		{"per-switch false keeps global", "invert_switches: true\nswitch_invert:\n  0: false\n", true, true},
+		{"per-switch false without global", "switch_invert:\n  0: false\n", false, false},
+		{"null entry is ignored", "invert_switches: true\nswitch_invert:\n  0: ~\n", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := newTestConfig(t, tt.yaml)
+
+			if got := cc.SwitchInverted(0); got != tt.switch0 {
+				t.Errorf("switch 0: got %v, want %v", got, tt.switch0)
+			}
+			if got := cc.SwitchInverted(1); got != tt.switch1 {
+				t.Errorf("switch 1: got %v, want %v", got, tt.switch1)
+			}
+		})
+	}
+}
+
+func TestSessionRefreshConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		yaml         string
+		wantCooldown time.Duration
+		wantMaxAge   time.Duration
+		wantMaxRate  int
+	}{
+		{"defaults", "", minTimeBetweenSessionRefreshes, maxTimeBetweenSessionRefreshes, maxFailureRefreshesPerSecond},
+		{"configured", "session_refresh_cooldown: 2.5\nsession_refresh_max_age: 60\nsession_refresh_max_rate: 4\n", 2500 * time.Millisecond, time.Minute, 4},
+		{"invalid values fall back", "session_refresh_cooldown: 0\nsession_refresh_max_age: -1\nsession_refresh_max_rate: 0\n",
+			minTimeBetweenSessionRefreshes, maxTimeBetweenSessionRefreshes, maxFailureRefreshesPerSecond},
+		{"max age can't be shorter than the cooldown", "session_refresh_cooldown: 10\nsession_refresh_max_age: 5\n", 10 * time.Second, 10 * time.Second, maxFailureRefreshesPerSecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := newTestConfig(t, tt.yaml)
+
+			if cc.SessionRefreshCooldown != tt.wantCooldown || cc.SessionRefreshMaxAge != tt.wantMaxAge || cc.SessionRefreshMaxRate != tt.wantMaxRate {
+				t.Errorf("got cooldown %v, max age %v, max rate %d, want %v, %v, %d",
+					cc.SessionRefreshCooldown, cc.SessionRefreshMaxAge, cc.SessionRefreshMaxRate,
+					tt.wantCooldown, tt.wantMaxAge, tt.wantMaxRate)
+			}
+		})
+	}
+}
+
+func TestConfigBesideExecutable(t *testing.T) {
+	tests := []struct {
+		name       string
+		inCwd      bool
+		inExeDir   bool
+		wantDir    string // "cwd", "exe" or "" when nothing should be found
+		wantTarget string
+	}{
+		{"only beside the executable", false, true, "exe", "exe.exe"},
+		{"working directory wins", true, true, "cwd", "cwd.exe"},
+		{"only in the working directory", true, false, "cwd", "cwd.exe"},
+		{"nowhere", false, false, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cwd, exeDir := t.TempDir(), t.TempDir()
+			dirs := map[string]string{"cwd": cwd, "exe": exeDir}
+
+			if tt.inCwd {
+				writeTestFile(t, filepath.Join(cwd, userConfigFilepath), "slider_mapping:\n  0: cwd.exe\n")
+			}
+			if tt.inExeDir {
+				writeTestFile(t, filepath.Join(exeDir, userConfigFilepath), "slider_mapping:\n  0: exe.exe\n")
+			}
+
+			previousExe := executablePath
+			executablePath = func() (string, error) { return filepath.Join(exeDir, "deej"), nil }
+			t.Cleanup(func() { executablePath = previousExe })
+
+			useWorkingDir(t, cwd)
+
+			found := findUserConfigFile()
+			if tt.wantDir == "" {
+				if found != "" {
+					t.Fatalf("found %q, want no config", found)
+				}
+				return
+			}
+
+			if want := filepath.Join(dirs[tt.wantDir], userConfigFilepath); !sameFile(t, found, want) {
+				t.Fatalf("found %q, want %q", found, want)
+			}
+
+			previousPath := internalConfigPath
+			internalConfigPath = t.TempDir()
+			t.Cleanup(func() { internalConfigPath = previousPath })
+
+			cc, err := NewConfig(zap.NewNop().Sugar(), testNotifier{})
+			if err != nil {
+				t.Fatalf("NewConfig: %v", err)
+			}
+			if err := cc.Load(); err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+
+			if got, _ := cc.SliderMapping.get(0); len(got) != 1 || got[0] != tt.wantTarget {
+				t.Errorf("slider 0 mapped to %v, want [%s]", got, tt.wantTarget)
+			}
+		})
+	}
+}
+
+// useWorkingDir makes dir the working directory until the test ends
+func useWorkingDir(t *testing.T, dir string) {
+	t.Helper()
+
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Chdir: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+// sameFile compares paths by what they point at, since "." and the temp dir's absolute path name the same file
+func sameFile(t *testing.T, a string, b string) bool {
+	t.Helper()
+
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		t.Fatalf("stat %s: %v", b, err)
+	}
+
+	return os.SameFile(aInfo, bInfo)
+}
+
+func TestTransportPreference(t *testing.T) {
+	const both = "SERIAL_Port: COM3\nSERIAL_BaudRate: 115200\nSSE_URL: http://mix.local/events\n"
+
+	tests := []struct {
+		name       string
+		yaml       string
+		wantPref   string
+		wantSerial bool
+		wantSSE    bool
+	}{
+		{"auto by default", both, TransportPreferenceAuto, true, true},
+		{"auto", both + "transport_preference: auto\n", TransportPreferenceAuto, true, true},
+		{"serial", both + "transport_preference: serial\n", TransportPreferenceSerial, true, false},
+		{"sse", both + "transport_preference: ' SSE '\n", TransportPreferenceSSE, false, true},
+		{"invalid falls back to auto", both + "transport_preference: usb\n", TransportPreferenceAuto, true, true},
+		{"sse preferred but only serial configured", "SERIAL_Port: COM3\nSERIAL_BaudRate: 115200\ntransport_preference: sse\n", TransportPreferenceSSE, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := newTestConfig(t, tt.yaml)
+
+			if got := cc.ConnectionInfo.TransportPreference; got != tt.wantPref {
+				t.Errorf("preference %q, want %q", got, tt.wantPref)
+			}
+			if got := cc.SerialEnabled(); got != tt.wantSerial {
+				t.Errorf("SerialEnabled() = %v, want %v", got, tt.wantSerial)
+			}
+			if got := cc.SSEEnabled(); got != tt.wantSSE {
+				t.Errorf("SSEEnabled() = %v, want %v", got, tt.wantSSE)
+			}
+		})
+	}
+}
+
+func TestMaxConcurrentActions(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want int
+	}{
+		{"no limit by default", "", 0},
+		{"opted in", "max_concurrent_actions: 4\n", 4},
+		{"negative falls back to no limit", "max_concurrent_actions: -2\n", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := newTestConfig(t, tt.yaml)
+
+			if got := cc.ButtonsMapping.MaxConcurrent; got != tt.want {
+				t.Errorf("max concurrent actions %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
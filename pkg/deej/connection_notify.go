@@ -0,0 +1,74 @@
+package deej
+
+import (
+	"fmt"
+	"time"
+)
+
+// connectionLostNotifyDelay is how long a lost connection has to stay lost before the user is told
+// about it. It's a variable so the delay can be shortened
+var connectionLostNotifyDelay = 5 * time.Second
+
+// notifyConnectionLost is called by a transport when an established connection drops. The notification
+// waits for connectionLostNotifyDelay, so a blip that reconnects in time never shows one
+func (d *Deej) notifyConnectionLost(transport string) {
+	if !d.config.ConnectionNotifications {
+		return
+	}
+
+	d.connNotifyMutex.Lock()
+	defer d.connNotifyMutex.Unlock()
+
+	if d.connLostTimer != nil || d.connLostNotified {
+		return
+	}
+
+	d.connLostTimer = time.AfterFunc(connectionLostNotifyDelay, func() {
+		d.connNotifyMutex.Lock()
+		if d.connLostTimer == nil {
+			// reconnected in the meantime
+			d.connNotifyMutex.Unlock()
+			return
+		}
+		d.connLostTimer = nil
+		d.connLostNotified = true
+		d.connNotifyMutex.Unlock()
+
+		if d.stopped.Load() {
+			return
+		}
+
+		d.logger.Infow("Connection still lost, notifying", "transport", transport, "after", connectionLostNotifyDelay)
+		d.notifier.Notify("Device disconnected", fmt.Sprintf("Lost the %s connection, retrying.", transport))
+	})
+}
+
+// notifyConnectionRestored is called by a transport whenever it connects. It cancels a pending
+// disconnect notification, or pairs one that was already shown with a reconnect notification
+func (d *Deej) notifyConnectionRestored(transport string) {
+	d.connNotifyMutex.Lock()
+	if d.connLostTimer != nil {
+		d.connLostTimer.Stop()
+		d.connLostTimer = nil
+	}
+	notified := d.connLostNotified
+	d.connLostNotified = false
+	d.connNotifyMutex.Unlock()
+
+	d.scheduleHardwareCheck()
+
+	if notified && d.config.ConnectionNotifications {
+		d.notifier.Notify("Device reconnected", fmt.Sprintf("The %s connection is back.", transport))
+	}
+}
+
+// runOnConnectAction starts the on_connect action, if one is configured, after a transport connects
+func (d *Deej) runOnConnectAction(transport string) {
+	if d.buttonHandler == nil {
+		return
+	}
+
+	if err := d.buttonHandler.HandleConnect(transport); err != nil {
+		d.logger.Warnw("Failed to run on_connect action", "transport", transport, "error", err)
+	}
+}
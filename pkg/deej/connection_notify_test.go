@@ -0,0 +1,60 @@
+package deej
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConnectionNotifications(t *testing.T) {
+	const delay = 30 * time.Millisecond
+
+	tests := []struct {
+		name    string
+		enabled bool
+		events  string // l = connection lost, r = restored, w = wait out the notify delay
+		want    []string
+	}{
+		{"initial connect", true, "r", nil},
+		{"blip is debounced", true, "lrw", nil},
+		{"outage is paired", true, "lwr", []string{"Device disconnected", "Device reconnected"}},
+		{"repeated loss notifies once", true, "lwlwr", []string{"Device disconnected", "Device reconnected"}},
+		{"two outages", true, "lwrlwr", []string{"Device disconnected", "Device reconnected", "Device disconnected", "Device reconnected"}},
+		{"disabled", false, "lwr", nil},
+	}
+
+	previousDelay := connectionLostNotifyDelay
+	connectionLostNotifyDelay = delay
+	t.Cleanup(func() { connectionLostNotifyDelay = previousDelay })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, "")
+			d.config.ConnectionNotifications = tt.enabled
+			notifier := &recordingNotifier{}
+			d.notifier = notifier
+			t.Cleanup(func() {
+				d.stateMutex.Lock()
+				defer d.stateMutex.Unlock()
+				if d.hardwareCheckTimer != nil {
+					d.hardwareCheckTimer.Stop()
+				}
+			})
+
+			for _, event := range tt.events {
+				switch event {
+				case 'l':
+					d.notifyConnectionLost("serial")
+				case 'r':
+					d.notifyConnectionRestored("serial")
+				case 'w':
+					time.Sleep(3 * delay)
+				}
+			}
+
+			if got := notifier.Titles(); strings.Join(got, "; ") != strings.Join(tt.want, "; ") {
+				t.Errorf("got notifications %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
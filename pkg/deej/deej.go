@@ -3,11 +3,15 @@
 package deej
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"net"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,6 +19,7 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/stalexteam/deej_esp32/pkg/deej/util"
 )
@@ -29,6 +34,18 @@ const (
 
 	// Timeout for waiting for interface to stop during switching
 	interfaceStopTimeout = 500 * time.Millisecond
+
+	// Newest device wire protocol version this build understands
+	supportedProtocolVersion = 1
+
+	// How long shutdown waits for cancelled button actions to finish
+	buttonActionsShutdownGrace = 1 * time.Second
+
+	// How long shutdown may take before deej exits anyway (shutdown_timeout)
+	defaultShutdownTimeout = 10 * time.Second
+
+	// Virtual position (percent) an encoder starts from before its first delta arrives
+	encoderInitialPosition = 50.0
 )
 
 // IOInterface defines the common interface for all I/O implementations (Serial, SSE, etc.)
@@ -40,12 +57,58 @@ type IOInterface interface {
 	SubscribeToSwitchEvents() chan SwitchEvent
 }
 
+// DeviceWriter is implemented by I/O interfaces that can send data back to the device
+type DeviceWriter interface {
+	Write(data []byte) error
+}
+
+// ConnectionReporter is implemented by I/O interfaces that can tell whether they're connected
+type ConnectionReporter interface {
+	IsConnected() bool
+}
+
 var (
-	potPattern = regexp.MustCompile(`^sensor-pot(\d+)$`)
-	swPattern  = regexp.MustCompile(`^binary_sensor-sw(\d+)$`)
-	btnStateID = "text_sensor-last_btn_state"
+	potPattern      = regexp.MustCompile(default_SliderIDPattern)
+	swPattern       = regexp.MustCompile(default_SwitchIDPattern)
+	encPattern      = regexp.MustCompile(`^sensor-enc(\d+)$`)
+	numberPattern   = regexp.MustCompile(default_NumberIDPattern)
+	selectPattern   = regexp.MustCompile(default_SelectIDPattern)
+	btnStateID      = "text_sensor-last_btn_state"
+	btnStatePattern = regexp.MustCompile("^" + regexp.QuoteMeta(btnStateID) + "$")
+	infoPattern     = regexp.MustCompile(`^info$`)
 )
 
+// stateHandler is a built-in handler for state events whose id matches pattern
+type stateHandler struct {
+	pattern       *regexp.Regexp
+	configPattern func() *regexp.Regexp // if set and non-nil, replaces pattern (user-configurable ids)
+	handle        func(logger *zap.SugaredLogger, id string, match []string, raw map[string]interface{})
+}
+
+// matcher returns the pattern currently used to match event ids
+func (h stateHandler) matcher() *regexp.Regexp {
+	if h.configPattern != nil {
+		if pattern := h.configPattern(); pattern != nil {
+			return pattern
+		}
+	}
+
+	return h.pattern
+}
+
+// potFilterState is the spike filter history of a single slider
+type potFilterState struct {
+	last       float64 // last accepted reading
+	pending    float64 // suspected spike awaiting confirmation
+	hasPending bool
+}
+
+// sensorHandler is a handler registered through SubscribeToSensor
+type sensorHandler struct {
+	pattern *regexp.Regexp
+	handle  SensorHandlerFunc
+}
+
 // Deej is the main entity managing access to all sub-components
 type Deej struct {
 	logger   *zap.SugaredLogger
@@ -58,24 +121,84 @@ type Deej struct {
 
 	stopChannel chan bool
 	version     string
-	verbose     bool
-	stopping    sync.Once  // Ensures signalStop is only called once
-	stopped     atomic.Bool // Set to true once shutdown begins; guards handleStateEvent from sending to closed channels
+	buildInfo   BuildInfo
+	verbose     atomic.Bool
+	stopping    sync.Once    // Ensures signalStop is only called once
+	stopped     atomic.Bool  // Set to true once shutdown begins; guards handleStateEvent from sending to closed channels
+	lastEventAt atomic.Int64 // UnixNano of the last state event, watched by the I/O watchdog
+
+	shutdownStage atomic.Value // string naming the shutdown step in progress, for logging a hung shutdown
+
+	// idle_dim state, see idle_dim.go
+	lastActivityAt atomic.Int64 // UnixNano of the last slider/switch/button activity
+	idleDimmed     atomic.Bool  // the idle_dim_command was sent and awaits its idle_restore_command
+
+	// connection_notifications state, see connection_notify.go
+	connNotifyMutex  sync.Mutex
+	connLostTimer    *time.Timer // pending "disconnected" notification
+	connLostNotified bool        // a "disconnected" notification was shown and awaits its "reconnected"
+
+	logLevel      *zap.AtomicLevel // set by SetLogLevel, nil until then
+	baseLogLevel  zapcore.Level    // restored when verbose mode is turned off
+	logLevelMutex sync.Mutex
 
 	// Common event consumers for all I/O implementations
 	sliderMoveConsumers []chan SliderMoveEvent
 	switchConsumers     []chan SwitchEvent
-	consumersMutex      sync.RWMutex // Protects consumers slices
+	sensorHandlers      []sensorHandler
+	consumersMutex      sync.RWMutex // Protects consumers slices and sensorHandlers
+
+	// Built-in state event handlers (pots, switches, buttons), matched in order
+	stateHandlers []stateHandler
 
 	// Synchronization for I/O operations
 	ioMutex sync.Mutex // Protects io field and startIO() calls
 
+	// Extra serial boards, see serial_extra.go
+	extraSerialsMutex sync.Mutex
+	extraSerials      []*SerialIO
+	extraSerialPorts  []string // the SERIAL_Port entries extraSerials were started from
+
 	// State storage for SSE server
 	stateMutex      sync.RWMutex                      // Protects state maps
 	sensorStates    map[string]map[string]interface{} // id -> state data
 	switchStates    map[string]map[string]interface{} // id -> state data
 	switchStateByID map[int]bool                      // switch index -> state
-	sseServer       *SseServer
+
+	sliderPercents map[int]int // slider index -> last dispatched percent, for action templates
+
+	// Protocol version announced by the device in its info frame (protected by stateMutex)
+	deviceProtocol      int
+	deviceProtocolKnown bool
+
+	// Sliders and switches the device has shown, see hardware_check.go (protected by stateMutex)
+	deviceSliders      int
+	deviceSwitches     int
+	hardwareWarned     map[string]bool // mappings already warned about since the last config load
+	hardwareCheckTimer *time.Timer
+
+	// Debounced saving of switch states for restore_switches, see switch_persist.go
+	switchSaveMutex sync.Mutex
+	switchSaveTimer *time.Timer
+
+	// Per-slider history for the pot spike filter
+	potFilterMutex  sync.Mutex
+	potFilterStates map[int]potFilterState
+
+	// Per-slider movement history for slider_fine_control
+	sliderVelocityMutex  sync.Mutex
+	sliderVelocityStates map[int]sliderVelocityState
+
+	// Rotary encoder virtual positions (percent), accumulated from relative deltas
+	encoderMutex     sync.Mutex
+	encoderPositions map[int]float64 // encoder index -> position (0-100)
+
+	// SSE relay server, relaying device state to other clients
+	sseServer *SseServer
+
+	// Optional OSC/MQTT outputs
+	osc  *OscSender
+	mqtt *MqttPublisher
 
 	// Button handler
 	buttonHandler *ButtonHandler
@@ -98,18 +221,25 @@ func NewDeej(logger *zap.SugaredLogger, verbose bool) (*Deej, error) {
 	}
 
 	d := &Deej{
-		logger:              logger,
-		notifier:            notifier,
-		config:              config,
-		stopChannel:         make(chan bool),
-		verbose:             verbose,
-		sliderMoveConsumers: []chan SliderMoveEvent{},
-		switchConsumers:     []chan SwitchEvent{},
-		sensorStates:        make(map[string]map[string]interface{}),
-		switchStates:        make(map[string]map[string]interface{}),
-		switchStateByID:     make(map[int]bool),
+		logger:               logger,
+		notifier:             notifier,
+		config:               config,
+		stopChannel:          make(chan bool),
+		sliderMoveConsumers:  []chan SliderMoveEvent{},
+		switchConsumers:      []chan SwitchEvent{},
+		sensorStates:         make(map[string]map[string]interface{}),
+		sliderPercents:       make(map[int]int),
+		switchStates:         make(map[string]map[string]interface{}),
+		switchStateByID:      make(map[int]bool),
+		hardwareWarned:       make(map[string]bool),
+		potFilterStates:      make(map[int]potFilterState),
+		sliderVelocityStates: make(map[int]sliderVelocityState),
+		encoderPositions:     make(map[int]float64),
 	}
 
+	d.verbose.Store(verbose)
+	d.setupStateHandlers()
+
 	serial, err := NewSerialIO(d, logger)
 	if err != nil {
 		logger.Errorw("Failed to create SerialIO", "error", err)
@@ -133,6 +263,22 @@ func NewDeej(logger *zap.SugaredLogger, verbose bool) (*Deej, error) {
 	}
 	d.sseServer = sseServer
 
+	// Initialize OSC sender (no-op unless OSC_Target is configured)
+	osc, err := NewOscSender(d, logger)
+	if err != nil {
+		logger.Errorw("Failed to create OscSender", "error", err)
+		return nil, fmt.Errorf("create new OscSender: %w", err)
+	}
+	d.osc = osc
+
+	// Initialize MQTT publisher (no-op unless MQTT_Broker is configured)
+	mqttPublisher, err := NewMqttPublisher(d, logger)
+	if err != nil {
+		logger.Errorw("Failed to create MqttPublisher", "error", err)
+		return nil, fmt.Errorf("create new MqttPublisher: %w", err)
+	}
+	d.mqtt = mqttPublisher
+
 	sessionFinder, err := newSessionFinder(logger)
 	if err != nil {
 		logger.Errorw("Failed to create SessionFinder", "error", err)
@@ -160,6 +306,19 @@ func NewDeej(logger *zap.SugaredLogger, verbose bool) (*Deej, error) {
 	return d, nil
 }
 
+// setupStateHandlers registers the built-in state event handlers, matched in this order
+func (d *Deej) setupStateHandlers() {
+	d.stateHandlers = []stateHandler{
+		{pattern: potPattern, configPattern: func() *regexp.Regexp { return d.config.SliderIDPattern }, handle: d.handlePotState},
+		{pattern: swPattern, configPattern: func() *regexp.Regexp { return d.config.SwitchIDPattern }, handle: d.handleSwitchState},
+		{pattern: encPattern, handle: d.handleEncoderState},
+		{pattern: numberPattern, configPattern: func() *regexp.Regexp { return d.config.NumberIDPattern }, handle: d.handleNumberState},
+		{pattern: selectPattern, configPattern: func() *regexp.Regexp { return d.config.SelectIDPattern }, handle: d.handleSelectState},
+		{pattern: btnStatePattern, handle: d.handleButtonState},
+		{pattern: infoPattern, handle: d.handleInfoState},
+	}
+}
+
 // Initialize sets up components and starts to run in the background
 func (d *Deej) Initialize() error {
 	d.logger.Debug("Initializing")
@@ -170,17 +329,40 @@ func (d *Deej) Initialize() error {
 		return fmt.Errorf("load config during init: %w", err)
 	}
 
-	// Update button handler configuration
-	if d.buttonHandler != nil && d.config.ButtonsMapping != nil {
-		d.buttonHandler.UpdateConfig(d.config.ButtonsMapping)
+	// everything has to check out before anything starts running, so a failed init never leaves
+	// a half-working deej behind (tray up, buttons armed, but no sessions)
+	if d.config.ButtonsMapping != nil {
+		if err := d.config.ButtonsMapping.Validate(); err != nil {
+			d.logger.Errorw("Invalid button actions during initialization", "error", err)
+			d.notifier.Notify("Invalid button actions!", fmt.Sprintf("%v\n\nPlease fix button_actions in your config.", err))
+			return fmt.Errorf("validate button actions during init: %w", err)
+		}
 	}
 
 	// initialize the session map
 	if err := d.sessions.initialize(); err != nil {
 		d.logger.Errorw("Failed to initialize session map", "error", err)
+		d.notifier.Notify("Can't access audio sessions!", "Please check deej's logs for more details.")
+
+		if releaseErr := d.sessions.release(); releaseErr != nil {
+			d.logger.Warnw("Failed to release session map after failed initialization", "error", releaseErr)
+		}
+
 		return fmt.Errorf("init session map: %w", err)
 	}
 
+	// with restore_switches, mutes left on at the last exit come back before the device reports in
+	d.restoreSwitchStates()
+
+	// Update button handler configuration
+	if d.buttonHandler != nil && d.config.ButtonsMapping != nil {
+		d.buttonHandler.UpdateConfig(d.config.ButtonsMapping)
+	}
+
+	// start forwarding events over OSC/MQTT
+	d.osc.initialize()
+	d.mqtt.initialize()
+
 	// decide whether to run with/without tray
 	if _, noTraySet := os.LookupEnv(envNoTray); noTraySet {
 
@@ -203,9 +385,65 @@ func (d *Deej) SetVersion(version string) {
 	d.version = version
 }
 
+// BuildInfo describes the running build, as embedded by the build scripts
+type BuildInfo struct {
+	VersionTag string `json:"version"`
+	GitCommit  string `json:"commit"`
+	BuildType  string `json:"buildType"`
+	BuildDate  string `json:"buildDate"`
+}
+
+// String formats the build info on one line for --version and bug reports, e.g.
+// "deej v1.2.34 (release), commit 1a2b3c4, built 2026-01-02T03:04:05Z"
+func (b BuildInfo) String() string {
+	orUnknown := func(value string) string {
+		if value == "" {
+			return "unknown"
+		}
+		return value
+	}
+
+	return fmt.Sprintf("deej %s (%s), commit %s, built %s",
+		orUnknown(b.VersionTag), orUnknown(b.BuildType), orUnknown(b.GitCommit), orUnknown(b.BuildDate))
+}
+
+// SetBuildInfo hands deej the build metadata it reports on the relay's /status endpoint
+func (d *Deej) SetBuildInfo(info BuildInfo) {
+	d.buildInfo = info
+}
+
 // Verbose returns a boolean indicating whether deej is running in verbose mode
 func (d *Deej) Verbose() bool {
-	return d.verbose
+	return d.verbose.Load()
+}
+
+// SetLogLevel hands deej the logger's level so verbose mode can change it at runtime. The level
+// it's at now is the one restored when verbose mode is turned off
+func (d *Deej) SetLogLevel(level zap.AtomicLevel) {
+	d.logLevelMutex.Lock()
+	d.logLevel = &level
+	d.baseLogLevel = level.Level()
+	d.logLevelMutex.Unlock()
+
+	d.SetVerbose(d.Verbose())
+}
+
+// SetVerbose turns verbose mode on or off, logging everything down to debug level while it's on
+func (d *Deej) SetVerbose(verbose bool) {
+	d.verbose.Store(verbose)
+
+	d.logLevelMutex.Lock()
+	defer d.logLevelMutex.Unlock()
+
+	if d.logLevel == nil {
+		return
+	}
+
+	if verbose {
+		d.logLevel.SetLevel(zapcore.DebugLevel)
+	} else {
+		d.logLevel.SetLevel(d.baseLogLevel)
+	}
 }
 
 func (d *Deej) setupInterruptHandler() {
@@ -239,16 +477,49 @@ func (d *Deej) run() {
 		}
 	}
 
+	// connect to the MQTT broker if configured
+	d.mqtt.Start()
+
+	// restart the I/O interface if it goes silent (no-op unless io_watchdog_timeout is set)
+	go d.runIOWatchdog()
+
+	// dim the device's backlight while nothing is touched (no-op unless idle_dim_after is set)
+	go d.runIdleDim()
+
 	// wait until stopped (gracefully)
 	<-d.stopChannel
 	d.logger.Debug("Stop channel signaled, terminating")
 
-	if err := d.stop(); err != nil {
-		d.logger.Warnw("Failed to stop deej", "error", err)
-		os.Exit(1)
-	} else {
-		// exit with 0
-		os.Exit(0)
+	os.Exit(d.shutdown())
+}
+
+// shutdown stops deej and returns the exit code to quit with. Stopping happens in the background,
+// so a hung session release or I/O stop can't keep deej from quitting past shutdown_timeout
+func (d *Deej) shutdown() int {
+	stopResult := make(chan error, 1)
+	go func() {
+		stopResult <- d.stop()
+	}()
+
+	timeout := d.config.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+
+	select {
+	case err := <-stopResult:
+		if err != nil {
+			d.logger.Warnw("Failed to stop deej", "error", err)
+			return 1
+		}
+
+		return 0
+
+	case <-time.After(timeout):
+		stage, _ := d.shutdownStage.Load().(string)
+		d.logger.Errorw("Shutdown did not finish in time, forcing exit", "timeout", timeout, "pending", stage)
+		d.logger.Sync()
+		return 1
 	}
 }
 
@@ -271,9 +542,11 @@ func (d *Deej) signalStop() {
 func (d *Deej) stop() error {
 	d.logger.Info("Stopping")
 
+	d.shutdownStage.Store("config watcher")
 	d.config.StopWatchingConfigFile()
 
 	// Stop I/O interface and wait for it to fully stop
+	d.shutdownStage.Store("I/O interface")
 	if d.io != nil {
 		d.io.Stop()
 		// Wait for interface to fully stop with timeout
@@ -283,27 +556,41 @@ func (d *Deej) stop() error {
 			d.logger.Warn("I/O interface did not stop within timeout, proceeding anyway")
 		}
 	}
+	d.stopExtraSerials()
+
+	// no more switch events are coming, save the final states right away
+	d.flushSwitchStateSave()
 
 	// Close all event channels to signal goroutines to exit
 	d.closeEventChannels()
 
-	// Cancel all running button actions
+	// Cancel all running button actions and give them a moment to unwind
+	d.shutdownStage.Store("button actions")
 	if d.buttonHandler != nil {
 		d.logger.Debug("Cancelling all running button actions on shutdown")
-		d.buttonHandler.CancelAllActions()
+		d.buttonHandler.Shutdown(buttonActionsShutdownGrace)
 	}
 
 	// Stop SSE server if running
+	d.shutdownStage.Store("SSE relay server")
 	if d.sseServer != nil {
 		d.sseServer.Stop()
 	}
 
+	// Disconnect from the MQTT broker
+	d.shutdownStage.Store("MQTT")
+	if d.mqtt != nil {
+		d.mqtt.Stop()
+	}
+
 	// release the session map
+	d.shutdownStage.Store("session release")
 	if err := d.sessions.release(); err != nil {
 		d.logger.Errorw("Failed to release session map", "error", err)
 		return fmt.Errorf("release session map: %w", err)
 	}
 
+	d.shutdownStage.Store("tray")
 	d.stopTray()
 
 	// attempt to sync on exit - this won't necessarily work but can't harm
@@ -351,17 +638,20 @@ func (d *Deej) handleStateEvent(logger *zap.SugaredLogger, data []byte) {
 		return
 	}
 
+	d.markEventReceived()
+
 	// Save state for SSE server
 	d.stateMutex.Lock()
-	// Check if this is a sensor (pot), switch, or button
-	if strings.HasPrefix(id, "sensor-") || strings.HasPrefix(id, "binary_sensor-") || id == btnStateID {
+	// Check if this is a sensor (pot), switch, button, or a number/select entity standing in for a pot or switch
+	if strings.HasPrefix(id, "sensor-") || strings.HasPrefix(id, "binary_sensor-") || id == btnStateID ||
+		strings.HasPrefix(id, "number-") || strings.HasPrefix(id, "select-") {
 		// Make a copy of the state data for storage
 		stateCopy := make(map[string]interface{})
 		for k, v := range raw {
 			stateCopy[k] = v
 		}
 		// Determine if it's a sensor, switch, or button based on prefix/id
-		if strings.HasPrefix(id, "binary_sensor-") {
+		if strings.HasPrefix(id, "binary_sensor-") || strings.HasPrefix(id, "select-") {
 			d.switchStates[id] = stateCopy
 		} else if id == btnStateID {
 			// Store button state in sensorStates for SSE server
@@ -377,169 +667,378 @@ func (d *Deej) handleStateEvent(logger *zap.SugaredLogger, data []byte) {
 		d.sseServer.NotifyStateChange(id, raw)
 	}
 
-	// ---- POTENTIOMETER
-	if m := potPattern.FindStringSubmatch(id); len(m) == 2 {
-		var val float64
-		var ok bool
-
-		// JSON numbers are always parsed as float64 when using map[string]interface{}
-		// This handles both SSE format: {"id":"sensor-pot2","value":81} and Serial: {"id": "sensor-pot2", "value": 73}
-		if v, okFloat := raw["value"].(float64); okFloat {
-			val = v
-			ok = true
-		}
-
-		if !ok {
+	// Built-in handlers take precedence; the first matching one consumes the event
+	for _, h := range d.stateHandlers {
+		if m := h.matcher().FindStringSubmatch(id); m != nil {
+			h.handle(logger, id, m, raw)
 			return
 		}
+	}
+
+	// Everything else goes to sensor subscribers registered through SubscribeToSensor
+	d.dispatchSensorEvent(logger, id, raw)
+}
 
-		idx, _ := strconv.Atoi(m[1])
+// handlePotState dispatches a SliderMoveEvent for a sensor-potN state event
+func (d *Deej) handlePotState(logger *zap.SugaredLogger, id string, match []string, raw map[string]interface{}) {
+	// This handles both SSE format: {"id":"sensor-pot2","value":81} and Serial: {"id": "sensor-pot2", "value": 73},
+	// as well as firmware that quotes the number: {"id":"sensor-pot2","value":"73"}
+	val, ok := numericValue(raw["value"])
+	if !ok {
+		return
+	}
 
-		// Check if there's an override value for this slider
-		var n float32
-		if overridePercent, hasOverride := d.config.SliderOverride[idx]; hasOverride {
-			// Use override value instead of ESP32 value
-			n = float32(overridePercent) / 100.0
-			if d.Verbose() {
-				logger.Debugw("Using slider override value", "slider", idx, "override", overridePercent)
-			}
-		} else {
-			// Use value from ESP32
-			n = float32(val) / 100.0
-			if n < 0 {
-				n = 0
-			} else if n > 1 {
-				n = 1
-			}
+	idx, err := strconv.Atoi(match[1])
+	if err != nil {
+		if d.Verbose() {
+			logger.Debugw("Failed to parse slider index", "error", err, "id", id)
 		}
+		return
+	}
 
-		if d.config.InvertSliders {
-			n = 1 - n
+	if !d.acceptPotReading(idx, val) {
+		if d.Verbose() {
+			logger.Debugw("Suppressed pot reading spike", "slider", idx, "value", val)
 		}
+		return
+	}
 
-		move := SliderMoveEvent{
-			SliderID:     idx,
-			PercentValue: n,
-		}
+	val = d.applySliderVelocity(idx, val, time.Now())
+
+	d.dispatchSliderValue(logger, idx, val)
+}
 
-		d.consumersMutex.RLock()
-		consumers := make([]chan SliderMoveEvent, len(d.sliderMoveConsumers))
-		copy(consumers, d.sliderMoveConsumers)
-		d.consumersMutex.RUnlock()
+// handleNumberState dispatches a SliderMoveEvent for an ESPHome number entity, e.g. number-volume2.
+// Its value is set on purpose, not read off a pot, so the spike filter and fine control don't apply
+func (d *Deej) handleNumberState(logger *zap.SugaredLogger, id string, match []string, raw map[string]interface{}) {
+	val, ok := numericValue(raw["value"])
+	if !ok {
+		return
+	}
 
-		for _, c := range consumers {
-			// If shutdown has begun, channels may already be closed — stop dispatching.
-			// We check the flag rather than using recover() to avoid silently swallowing panics
-			// that could indicate real bugs unrelated to shutdown.
-			if d.stopped.Load() {
-				return
-			}
-			select {
-			case c <- move:
-			default:
-				// Channel is full, drop the event
-			}
+	idx, err := strconv.Atoi(match[1])
+	if err != nil {
+		if d.Verbose() {
+			logger.Debugw("Failed to parse number index", "error", err, "id", id)
 		}
 		return
 	}
 
-	// ---- SWITCH
-	if m := swPattern.FindStringSubmatch(id); len(m) == 2 {
-		var state bool
-		if v, ok := raw["value"].(bool); ok {
-			state = v
-		} else if sStr, ok := raw["state"].(string); ok {
-			state = strings.ToUpper(sStr) == "ON"
-		} else {
-			return
+	d.dispatchSliderValue(logger, idx, val)
+}
+
+// numericValue reads a state event value as a number. JSON numbers are always parsed as float64
+// when using map[string]interface{}; numbers sent as strings are parsed too
+func numericValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, false
 		}
+		return f, true
+	default:
+		return 0, false
+	}
+}
 
-		idx, err := strconv.Atoi(m[1])
-		if err != nil {
-			if d.Verbose() {
-				logger.Debugw("Failed to parse switch index", "error", err, "id", id)
-			}
+// dispatchSliderValue applies remapping/overrides/inversion to a 0-100 reading and sends a SliderMoveEvent for it
+func (d *Deej) dispatchSliderValue(logger *zap.SugaredLogger, idx int, val float64) {
+	d.noteDeviceSlider(idx)
+
+	// everything from here on uses the slider id from the config, not the device's
+	idx = d.config.RemapSlider(idx)
+
+	// Check if there's an override value for this slider
+	var n float32
+	if overridePercent, hasOverride := d.config.SliderOverride[idx]; hasOverride {
+		// Use override value instead of ESP32 value
+		n = float32(overridePercent) / 100.0
+		if d.Verbose() {
+			logger.Debugw("Using slider override value", "slider", idx, "override", overridePercent)
+		}
+	} else {
+		// Use value from ESP32
+		n = float32(val) / 100.0
+		if d.config.SliderOverflow == SliderOverflowWrap {
+			n = float32(util.Wrap(float64(n)))
+		} else if n < 0 {
+			n = 0
+		} else if n > 1 {
+			n = 1
+		}
+
+		if expo, ok := d.config.SliderExpo[idx]; ok {
+			n = float32(util.Expo(float64(n), expo))
+		}
+	}
+
+	if d.config.InvertSliders {
+		n = 1 - n
+	}
+
+	// slider_snap applies to the final position, so its points are the volumes you end up with.
+	// Overrides are exact already
+	if _, hasOverride := d.config.SliderOverride[idx]; !hasOverride {
+		n = d.config.SnapSlider(idx, n)
+	}
+
+	percent := int(math.Round(float64(n) * 100))
+
+	d.stateMutex.Lock()
+	prevPercent, hadPercent := d.sliderPercents[idx]
+	d.sliderPercents[idx] = percent
+	d.stateMutex.Unlock()
+
+	// repeated readings of a resting slider aren't activity, only moves are
+	if !hadPercent || prevPercent != percent {
+		d.markActivity()
+	}
+
+	move := SliderMoveEvent{
+		SliderID:     idx,
+		PercentValue: n,
+	}
+
+	d.consumersMutex.RLock()
+	consumers := make([]chan SliderMoveEvent, len(d.sliderMoveConsumers))
+	copy(consumers, d.sliderMoveConsumers)
+	d.consumersMutex.RUnlock()
+
+	for _, c := range consumers {
+		// If shutdown has begun, channels may already be closed — stop dispatching.
+		// We check the flag rather than using recover() to avoid silently swallowing panics
+		// that could indicate real bugs unrelated to shutdown.
+		if d.stopped.Load() {
 			return
 		}
+		select {
+		case c <- move:
+		default:
+			// Channel is full, drop the event
+		}
+	}
+}
 
-		d.stateMutex.Lock()
-		prevState, hasPrev := d.switchStateByID[idx]
-		d.switchStateByID[idx] = state
-		d.stateMutex.Unlock()
+// handleEncoderState turns a sensor-encN relative delta into an absolute slider position.
+// Encoder N drives slider N, exactly like sensor-potN would
+func (d *Deej) handleEncoderState(logger *zap.SugaredLogger, id string, match []string, raw map[string]interface{}) {
+	delta, ok := numericValue(raw["value"])
+	if !ok || delta == 0 {
+		return
+	}
 
-		sw := SwitchEvent{
-			SwitchID:  idx,
-			State:     state,
-			PrevState: prevState,
-			HasPrev:   hasPrev,
+	idx, err := strconv.Atoi(match[1])
+	if err != nil {
+		if d.Verbose() {
+			logger.Debugw("Failed to parse encoder index", "error", err, "id", id)
 		}
+		return
+	}
 
-		d.consumersMutex.RLock()
-		consumers := make([]chan SwitchEvent, len(d.switchConsumers))
-		copy(consumers, d.switchConsumers)
-		d.consumersMutex.RUnlock()
+	position := d.applyEncoderDelta(idx, delta)
 
-		for _, c := range consumers {
-			// Same shutdown guard as for slider events above
-			if d.stopped.Load() {
-				return
-			}
-			select {
-			case c <- sw:
-			default:
-				// Channel is full, drop the event
-			}
+	if d.Verbose() {
+		logger.Debugw("Encoder moved", "encoder", idx, "delta", delta, "position", position)
+	}
+
+	// from here on it's indistinguishable from a pot at that position
+	d.dispatchSliderValue(logger, idx, position)
+}
+
+// applyEncoderDelta accumulates delta steps into the encoder's virtual position and returns it, clamped to 0-100
+func (d *Deej) applyEncoderDelta(idx int, delta float64) float64 {
+	step := float64(d.config.EncoderStep(idx))
+
+	d.encoderMutex.Lock()
+	defer d.encoderMutex.Unlock()
+
+	position, ok := d.encoderPositions[idx]
+	if !ok {
+		position = encoderInitialPosition
+	}
+
+	position += delta * step
+	if position < 0 {
+		position = 0
+	} else if position > 100 {
+		position = 100
+	}
+
+	d.encoderPositions[idx] = position
+
+	return position
+}
+
+// acceptPotReading implements the opt-in spike filter (slider_spike_filter). A reading that jumps more
+// than the configured delta from the last accepted one is held back, and only let through if the
+// next reading confirms it - so a lone EMI spike never reaches the volume, but a real fast move
+// is delayed by a single sample at most
+func (d *Deej) acceptPotReading(idx int, val float64) bool {
+	maxDelta := float64(d.config.SliderSpikeFilter)
+
+	d.potFilterMutex.Lock()
+	defer d.potFilterMutex.Unlock()
+
+	state, seen := d.potFilterStates[idx]
+	if maxDelta <= 0 || !seen {
+		d.potFilterStates[idx] = potFilterState{last: val}
+		return true
+	}
+
+	if math.Abs(val-state.last) <= maxDelta ||
+		(state.hasPending && math.Abs(val-state.pending) <= maxDelta) {
+		d.potFilterStates[idx] = potFilterState{last: val}
+		return true
+	}
+
+	d.potFilterStates[idx] = potFilterState{last: state.last, pending: val, hasPending: true}
+	return false
+}
+
+// handleSwitchState dispatches a SwitchEvent for a binary_sensor-swN state event
+func (d *Deej) handleSwitchState(logger *zap.SugaredLogger, id string, match []string, raw map[string]interface{}) {
+	var state bool
+	if v, ok := raw["value"].(bool); ok {
+		state = v
+	} else if sStr, ok := raw["state"].(string); ok {
+		state = strings.ToUpper(sStr) == "ON"
+	} else {
+		return
+	}
+
+	idx, err := strconv.Atoi(match[1])
+	if err != nil {
+		if d.Verbose() {
+			logger.Debugw("Failed to parse switch index", "error", err, "id", id)
 		}
 		return
 	}
 
-	// ---- BUTTON
-	if id == btnStateID {
-		value, _ := raw["value"].(string)
-		// Handle empty values (clearing from ESP32)
-		if value == "" {
-			// Remove state from sensorStates to prevent stale data in SSE server
-			d.stateMutex.Lock()
-			delete(d.sensorStates, id)
-			d.stateMutex.Unlock()
+	d.noteDeviceSwitch(idx)
 
-			if d.Verbose() {
-				logger.Debugw("Button state cleared", "id", id)
-			}
+	// everything from here on uses the switch id from the config, not the device's
+	idx = d.config.RemapSwitch(idx)
+
+	// normalize active-low sensors first, so "ON" always means closed; inversion is applied later by consumers
+	if d.config.SwitchActiveLow[idx] {
+		state = !state
+	}
+
+	d.stateMutex.Lock()
+	prevState, hasPrev := d.switchStateByID[idx]
+	d.switchStateByID[idx] = state
+	d.stateMutex.Unlock()
+
+	if !hasPrev || prevState != state {
+		d.markActivity()
+		d.scheduleSwitchStateSave()
+	}
+
+	d.dispatchSwitchEvent(SwitchEvent{
+		SwitchID:  idx,
+		State:     state,
+		PrevState: prevState,
+		HasPrev:   hasPrev,
+	})
+}
+
+// dispatchSwitchEvent sends a SwitchEvent to every subscriber
+func (d *Deej) dispatchSwitchEvent(sw SwitchEvent) {
+	d.consumersMutex.RLock()
+	consumers := make([]chan SwitchEvent, len(d.switchConsumers))
+	copy(consumers, d.switchConsumers)
+	d.consumersMutex.RUnlock()
+
+	for _, c := range consumers {
+		// Same shutdown guard as for slider events above
+		if d.stopped.Load() {
 			return
 		}
+		select {
+		case c <- sw:
+		default:
+			// Channel is full, drop the event
+		}
+	}
+}
 
-		// Parse value format: "ID_Action" (e.g., "1_single", "2_double", "3_long")
-		parts := strings.Split(value, "_")
-		if len(parts) != 2 {
-			if d.Verbose() {
-				logger.Debugw("Invalid button value format", "value", value, "id", id)
-			}
+// handleSelectState turns an ESPHome select entity, e.g. select-sw1, into a switch: the switch is on
+// while one of the select_on_states options is selected, and off for any other option
+func (d *Deej) handleSelectState(logger *zap.SugaredLogger, id string, match []string, raw map[string]interface{}) {
+	option, ok := raw["state"].(string)
+	if !ok {
+		if option, ok = raw["value"].(string); !ok {
 			return
 		}
+	}
 
-		buttonID, err := strconv.Atoi(parts[0])
-		if err != nil {
-			if d.Verbose() {
-				logger.Debugw("Failed to parse button ID", "value", value, "error", err)
-			}
-			return
+	on := d.config.SelectOnStates[strings.ToLower(strings.TrimSpace(option))]
+	if d.Verbose() {
+		logger.Debugw("Select option received", "id", id, "option", option, "on", on)
+	}
+
+	d.handleSwitchState(logger, id, match, map[string]interface{}{"value": on})
+}
+
+// handleButtonState runs the configured button action for a last_btn_state event
+func (d *Deej) handleButtonState(logger *zap.SugaredLogger, id string, match []string, raw map[string]interface{}) {
+	value, _ := raw["value"].(string)
+	// Handle empty values (clearing from ESP32)
+	if value == "" {
+		// Remove state from sensorStates to prevent stale data in SSE server
+		d.stateMutex.Lock()
+		delete(d.sensorStates, id)
+		d.stateMutex.Unlock()
+
+		if d.Verbose() {
+			logger.Debugw("Button state cleared", "id", id)
 		}
+		return
+	}
 
-		actionType := parts[1] // single, double, or long
+	// Parse value format: "ID_Action" (e.g., "1_single", "2_double", "3_long")
+	parts := strings.Split(value, "_")
+	if len(parts) != 2 {
+		if d.Verbose() {
+			logger.Debugw("Invalid button value format", "value", value, "id", id)
+		}
+		return
+	}
 
+	buttonID, err := strconv.Atoi(parts[0])
+	if err != nil {
 		if d.Verbose() {
-			logger.Debugw("Button pressed", "button", buttonID, "action", actionType)
+			logger.Debugw("Failed to parse button ID", "value", value, "error", err)
 		}
+		return
+	}
+
+	actionType := parts[1] // single, double, or long - or down/up from firmware that reports them
+
+	d.markActivity()
 
-		// Handle button press
+	if actionType == ButtonEventDown || actionType == ButtonEventUp {
 		if d.buttonHandler != nil {
-			if err := d.buttonHandler.HandleButtonPress(buttonID, actionType); err != nil {
-				logger.Warnw("Failed to handle button press", "button", buttonID, "action", actionType, "error", err)
+			if err := d.buttonHandler.HandleButtonHold(buttonID, actionType == ButtonEventDown); err != nil {
+				logger.Warnw("Failed to handle button hold", "button", buttonID, "event", actionType, "error", err)
 			}
 		}
 		return
 	}
+
+	if d.Verbose() {
+		logger.Debugw("Button pressed", "button", buttonID, "action", actionType)
+	}
+
+	// Handle button press
+	if d.buttonHandler != nil {
+		if err := d.buttonHandler.HandleButtonPress(buttonID, actionType); err != nil {
+			logger.Warnw("Failed to handle button press", "button", buttonID, "action", actionType, "error", err)
+		}
+	}
 }
 
 // SubscribeToSliderMoveEvents returns an unbuffered channel that receives a SliderMoveEvent every time a slider moves
@@ -551,6 +1050,21 @@ func (d *Deej) SubscribeToSliderMoveEvents() chan SliderMoveEvent {
 	return ch
 }
 
+// SubscribeToSliderMoveEventsBuffered is like SubscribeToSliderMoveEvents, but the channel buffers up to size
+// events, so a consumer that briefly falls behind still receives every sample (e.g. for recording).
+// Events are only dropped once the buffer is full
+func (d *Deej) SubscribeToSliderMoveEventsBuffered(size int) chan SliderMoveEvent {
+	if size < 0 {
+		size = 0
+	}
+
+	ch := make(chan SliderMoveEvent, size)
+	d.consumersMutex.Lock()
+	d.sliderMoveConsumers = append(d.sliderMoveConsumers, ch)
+	d.consumersMutex.Unlock()
+	return ch
+}
+
 // SubscribeToSwitchEvents returns an unbuffered channel that receives a SwitchEvent every time a switch changes
 func (d *Deej) SubscribeToSwitchEvents() chan SwitchEvent {
 	ch := make(chan SwitchEvent)
@@ -560,6 +1074,123 @@ func (d *Deej) SubscribeToSwitchEvents() chan SwitchEvent {
 	return ch
 }
 
+// handleInfoState records the protocol version from a {"id":"info","protocol":N} frame, along with
+// the slider and switch counts if the device includes them ("sliders":N, "switches":N)
+func (d *Deej) handleInfoState(logger *zap.SugaredLogger, id string, match []string, raw map[string]interface{}) {
+	d.noteDeviceHardware(raw)
+
+	version, ok := raw["protocol"].(float64)
+	if !ok {
+		if d.Verbose() {
+			logger.Debugw("Info frame without protocol version", "data", raw)
+		}
+		return
+	}
+
+	protocol := int(version)
+
+	d.stateMutex.Lock()
+	changed := !d.deviceProtocolKnown || d.deviceProtocol != protocol
+	d.deviceProtocol = protocol
+	d.deviceProtocolKnown = true
+	d.stateMutex.Unlock()
+
+	// devices may repeat the frame on every reconnect, only speak up when it changes
+	if !changed {
+		return
+	}
+
+	if protocol > supportedProtocolVersion {
+		logger.Warnw("Device uses a newer protocol than supported, some events may be misread",
+			"protocol", protocol, "supported", supportedProtocolVersion)
+		d.notifier.Notify("Device firmware is newer than deej",
+			fmt.Sprintf("The device speaks protocol %d, but this deej only supports up to %d. Please update deej.", protocol, supportedProtocolVersion))
+		return
+	}
+
+	logger.Infow("Device protocol version", "protocol", protocol)
+}
+
+// DeviceProtocolVersion returns the protocol version the device announced, if any
+func (d *Deej) DeviceProtocolVersion() (int, bool) {
+	d.stateMutex.RLock()
+	defer d.stateMutex.RUnlock()
+
+	return d.deviceProtocol, d.deviceProtocolKnown
+}
+
+// WriteToDevice sends data to the device over the active I/O interface, if it supports writing
+func (d *Deej) WriteToDevice(data []byte) error {
+	d.ioMutex.Lock()
+	io := d.io
+	d.ioMutex.Unlock()
+
+	writer, ok := io.(DeviceWriter)
+	if !ok {
+		return errors.New("active I/O interface can't write to the device")
+	}
+
+	return writer.Write(data)
+}
+
+// ForegroundWindowTitle returns the title of the focused window, for action templates
+func (d *Deej) ForegroundWindowTitle() (string, error) {
+	return util.GetForegroundWindowTitle()
+}
+
+// SliderPercent returns the last volume percent dispatched for a slider, after overrides and inversion
+func (d *Deej) SliderPercent(idx int) (int, bool) {
+	d.stateMutex.RLock()
+	defer d.stateMutex.RUnlock()
+
+	percent, ok := d.sliderPercents[idx]
+	return percent, ok
+}
+
+// SensorHandlerFunc receives the id and value of a state event matched by SubscribeToSensor.
+// The value is the event's "value" field, or its "state" field if there is no value
+type SensorHandlerFunc func(id string, value interface{})
+
+// SubscribeToSensor registers fn to be called for every state event whose id matches pattern
+// and isn't one of the built-in pot, switch or button ids. Handlers are called synchronously
+// from the I/O goroutine, so they must not block
+func (d *Deej) SubscribeToSensor(pattern string, fn SensorHandlerFunc) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("compile sensor pattern %q: %w", pattern, err)
+	}
+
+	d.consumersMutex.Lock()
+	d.sensorHandlers = append(d.sensorHandlers, sensorHandler{pattern: re, handle: fn})
+	d.consumersMutex.Unlock()
+
+	return nil
+}
+
+func (d *Deej) dispatchSensorEvent(logger *zap.SugaredLogger, id string, raw map[string]interface{}) {
+	d.consumersMutex.RLock()
+	handlers := make([]sensorHandler, len(d.sensorHandlers))
+	copy(handlers, d.sensorHandlers)
+	d.consumersMutex.RUnlock()
+
+	value, hasValue := raw["value"]
+	if !hasValue {
+		value = raw["state"]
+	}
+
+	for _, h := range handlers {
+		if d.stopped.Load() {
+			return
+		}
+		if h.pattern.MatchString(id) {
+			if d.Verbose() {
+				logger.Debugw("Dispatching sensor event", "id", id, "pattern", h.pattern.String())
+			}
+			h.handle(id, value)
+		}
+	}
+}
+
 // GetSwitchState returns the last known raw switch state.
 func (d *Deej) GetSwitchState(switchID int) (bool, bool) {
 	d.stateMutex.RLock()
@@ -568,16 +1199,47 @@ func (d *Deej) GetSwitchState(switchID int) (bool, bool) {
 	return state, ok
 }
 
+// Categorized reasons for a failed or dropped I/O connection
+const (
+	connReasonBusy      = "busy"      // port held by another program, or access denied
+	connReasonNotFound  = "not_found" // port or host doesn't exist
+	connReasonTimeout   = "timeout"   // the device didn't answer in time
+	connReasonIdle      = "idle"      // connected, but no events arrived (I/O watchdog)
+	connReasonUnplugged = "unplugged" // the serial adapter was physically removed
+	connReasonIOError   = "io_error"  // anything else
+)
+
+// connectionErrorReason maps a transport error to one of the connReason* categories
+func connectionErrorReason(err error) string {
+	var netErr net.Error
+
+	switch {
+	case serialDeviceRemoved(err):
+		return connReasonUnplugged
+	case errors.Is(err, os.ErrPermission):
+		return connReasonBusy
+	case errors.Is(err, os.ErrNotExist):
+		return connReasonNotFound
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return connReasonTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return connReasonTimeout
+	default:
+		return connReasonIOError
+	}
+}
+
 // startIO starts the appropriate I/O interface based on configuration
 func (d *Deej) startIO() {
 	d.ioMutex.Lock()
 	defer d.ioMutex.Unlock()
 
-	serialConfigured := d.config.ConnectionInfo.SERIAL_Port != "" && d.config.ConnectionInfo.SERIAL_BaudRate != 0
-	sseConfigured := d.config.ConnectionInfo.SSE_URL != ""
+	serialConfigured := d.config.SerialEnabled()
+	sseConfigured := d.config.SSEEnabled()
 
 	if !serialConfigured && !sseConfigured {
-		d.logger.Warnw("No I/O interface configured", "error", "neither serial nor SSE configured")
+		d.logger.Warnw("No I/O interface configured", "error", "neither serial nor SSE configured",
+			"transportPreference", d.config.ConnectionInfo.TransportPreference)
 		d.notifier.Notify("No I/O interface configured!", "Please set up either a serial port or an SSE URL in the configuration.")
 		d.signalStop()
 		return
@@ -587,15 +1249,16 @@ func (d *Deej) startIO() {
 	if serialConfigured {
 		d.io = d.serial
 		if err := d.serial.Start(); err != nil {
-			d.logger.Warnw("Failed to start first-time serial connection", "error", err)
+			reason := connectionErrorReason(err)
+			d.logger.Warnw("Failed to start first-time serial connection", "error", err, "reason", reason)
 
-			if errors.Is(err, os.ErrPermission) { // If the port is busy, that's because something else is connected - notify and quit
+			if reason == connReasonBusy { // If the port is busy, that's because something else is connected - notify and quit
 				d.logger.Warnw("Serial port seems busy, notifying user and closing", "comPort", d.config.ConnectionInfo.SERIAL_Port)
 				d.notifier.Notify(fmt.Sprintf("Can't connect to %s!", d.config.ConnectionInfo.SERIAL_Port), "This serial port is busy, make sure to close any serial monitor or other deej instance.")
 				d.signalStop()
 				return // no need to try SSE if serial is explicitly configured && busy
 
-			} else if errors.Is(err, os.ErrNotExist) { // also notify if the COM port they gave isn't found, maybe their config is wrong
+			} else if reason == connReasonNotFound { // also notify if the COM port they gave isn't found, maybe their config is wrong
 				if !sseConfigured {
 					d.logger.Warnw("Provided COM port seems wrong, notifying user and closing", "comPort", d.config.ConnectionInfo.SERIAL_Port)
 					d.notifier.Notify(fmt.Sprintf("Can't connect to %s!", d.config.ConnectionInfo.SERIAL_Port), "This serial port doesn't exist, check your configuration and make sure it's set correctly.")
@@ -607,6 +1270,7 @@ func (d *Deej) startIO() {
 				}
 			}
 		} else {
+			d.startExtraSerials()
 			return // Serial started successfully, no need to try SSE
 		}
 	}
@@ -620,7 +1284,7 @@ func (d *Deej) startIO() {
 	// Fallback to SSE if serial is not configured or failed to start
 	d.io = d.sse
 	if err := d.sse.Start(); err != nil {
-		d.logger.Warnw("Failed to start first-time SSE connection", "error", err)
+		d.logger.Warnw("Failed to start first-time SSE connection", "error", err, "reason", connectionErrorReason(err))
 
 		// User-facing hint: URL might be wrong/unreachable
 		url := d.config.ConnectionInfo.SSE_URL
@@ -640,22 +1304,27 @@ func (d *Deej) setupOnConfigReload() {
 		for {
 			<-configReloadedChannel
 
-			// Update button handler configuration
-			if d.buttonHandler != nil {
-				// Check if we need to cancel running actions (check NEW config)
-				shouldCancel := false
-				if d.config.ButtonsMapping != nil {
-					shouldCancel = d.config.ButtonsMapping.CancelOnReload
-				}
-
-				if shouldCancel {
-					d.logger.Info("Config reloaded with cancel_on_reload=true, cancelling all running button actions")
-					d.buttonHandler.CancelAllActions()
+			// Update button handler configuration. A broken mapping is never applied, the handler keeps
+			// the one it had until the config is fixed
+			if d.buttonHandler != nil && d.config.ButtonsMapping != nil {
+				if err := d.config.ButtonsMapping.Validate(); err != nil {
+					d.logger.Errorw("Invalid button actions after config reload, keeping the previous ones", "error", err)
+					d.notifier.Notify("Invalid button actions!", fmt.Sprintf("%v\n\nThe previous button actions stay active until you fix button_actions in your config.", err))
+				} else {
+					// Check if we need to cancel running actions (check NEW config)
+					if d.config.ButtonsMapping.CancelOnReload {
+						d.logger.Info("Config reloaded with cancel_on_reload=true, cancelling all running button actions")
+						d.buttonHandler.CancelAllActions()
+					}
+					// Update configuration (this happens after cancel check to use new config)
+					d.buttonHandler.UpdateConfig(d.config.ButtonsMapping)
 				}
-				// Update configuration (this happens after cancel check to use new config)
-				d.buttonHandler.UpdateConfig(d.config.ButtonsMapping)
 			}
 
+			// the new mappings may point at hardware the device doesn't have
+			d.resetHardwareWarnings()
+			d.scheduleHardwareCheck()
+
 			// Handle SSE server port changes (independent of I/O interface)
 			newPort := d.config.ConnectionInfo.SSE_RELAY_PORT
 			if d.sseServer != nil {
@@ -692,12 +1361,17 @@ func (d *Deej) setupOnConfigReload() {
 				}
 			}
 
+			// Handle MQTT broker changes (Start is a no-op when the broker is unchanged)
+			if d.mqtt != nil {
+				d.mqtt.Start()
+			}
+
 			// Acquire lock to prevent concurrent startIO() calls
 			d.ioMutex.Lock()
 
 			// Determine which interface should be active based on new config
-			shouldUseSerial := d.config.ConnectionInfo.SERIAL_Port != "" && d.config.ConnectionInfo.SERIAL_BaudRate != 0
-			sseConfigured := d.config.ConnectionInfo.SSE_URL != ""
+			shouldUseSerial := d.config.SerialEnabled()
+			sseConfigured := d.config.SSEEnabled()
 			currentIsSerial := d.io == d.serial
 
 			// Check if we need to switch interfaces or if current transport was removed
@@ -725,6 +1399,7 @@ func (d *Deej) setupOnConfigReload() {
 
 				// Release lock before stopping interface and waiting (these operations can take time)
 				d.ioMutex.Unlock()
+				d.stopExtraSerials()
 
 				if d.io != nil {
 					d.io.Stop()
@@ -747,7 +1422,7 @@ func (d *Deej) setupOnConfigReload() {
 					d.serial.mu.Unlock()
 
 					if d.config.ConnectionInfo.SERIAL_Port != currentPort ||
-						uint(d.config.ConnectionInfo.SERIAL_BaudRate) != currentBaud {
+						!slices.Contains(d.config.ConnectionInfo.SERIAL_BaudRates, int(currentBaud)) {
 						d.logger.Info("Detected change in serial connection parameters, renewing connection")
 						// Release ioMutex before stopping and starting (these operations can take time)
 						d.ioMutex.Unlock()
@@ -816,6 +1491,10 @@ func (d *Deej) setupOnConfigReload() {
 				} else {
 					d.ioMutex.Unlock()
 				}
+
+				if currentIsSerial {
+					d.syncExtraSerials()
+				}
 			} else {
 				d.ioMutex.Unlock()
 			}
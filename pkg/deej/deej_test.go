@@ -0,0 +1,864 @@
+package deej
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newTestDeej builds a Deej with the given config.yaml body that handles state events,
+// without any I/O, sessions or button handler behind it
+func newTestDeej(t *testing.T, userYAML string) *Deej {
+	t.Helper()
+
+	d := &Deej{
+		logger:               zap.NewNop().Sugar(),
+		notifier:             testNotifier{},
+		config:               newTestConfig(t, userYAML),
+		stopChannel:          make(chan bool),
+		sensorStates:         make(map[string]map[string]interface{}),
+		sliderPercents:       make(map[int]int),
+		switchStates:         make(map[string]map[string]interface{}),
+		switchStateByID:      make(map[int]bool),
+		hardwareWarned:       make(map[string]bool),
+		potFilterStates:      make(map[int]potFilterState),
+		sliderVelocityStates: make(map[int]sliderVelocityState),
+		encoderPositions:     make(map[int]float64),
+	}
+	d.setupStateHandlers()
+
+	return d
+}
+
+// sendStates feeds raw state frames to d as if they came from the device
+func sendStates(d *Deej, frames ...string) {
+	for _, frame := range frames {
+		d.handleStateEvent(d.logger, []byte(frame))
+	}
+}
+
+// receiveSliderMoves collects the slider moves already waiting on events
+func receiveSliderMoves(events chan SliderMoveEvent) []SliderMoveEvent {
+	moves := []SliderMoveEvent{}
+	for {
+		select {
+		case move := <-events:
+			moves = append(moves, move)
+		case <-time.After(10 * time.Millisecond):
+			return moves
+		}
+	}
+}
+
+func TestSubscribeToSensor(t *testing.T) {
+	d := newTestDeej(t, "")
+	sliderEvents := d.SubscribeToSliderMoveEventsBuffered(8)
+
+	type received struct {
+		id    string
+		value interface{}
+	}
+	var got []received
+	if err := d.SubscribeToSensor(`^sensor-(temp|humidity)\d*$`, func(id string, value interface{}) {
+		got = append(got, received{id, value})
+	}); err != nil {
+		t.Fatalf("SubscribeToSensor: %v", err)
+	}
+
+	sendStates(d,
+		`{"id":"sensor-temp1","value":21.5}`,
+		`{"id":"sensor-humidity","state":"40 %"}`,
+		`{"id":"sensor-pot0","value":50}`,
+		`{"id":"sensor-rssi","value":-60}`,
+	)
+
+	want := []received{{"sensor-temp1", 21.5}, {"sensor-humidity", "40 %"}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// the built-in pot handler still consumes its own ids
+	if moves := receiveSliderMoves(sliderEvents); len(moves) != 1 || moves[0].SliderID != 0 {
+		t.Errorf("slider moves: got %v", moves)
+	}
+
+	if err := d.SubscribeToSensor(`^sensor-(`, func(string, interface{}) {}); err == nil {
+		t.Error("SubscribeToSensor accepted an invalid pattern")
+	}
+}
+
+func TestApplyEncoderDelta(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoder int
+		deltas  []float64
+		want    float64
+	}{
+		{"starts centered", 0, []float64{1}, 52},
+		{"accumulates", 0, []float64{1, 1, -1, 3}, 58},
+		{"clamps at the top", 0, []float64{30}, 100},
+		{"clamps at the bottom", 0, []float64{-30}, 0},
+		{"moves off the end right away", 0, []float64{30, -1}, 98},
+		{"configured step", 1, []float64{2, 1}, 65},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, "encoder_steps:\n  1: 5\n")
+
+			var got float64
+			for _, delta := range tt.deltas {
+				got = d.applyEncoderDelta(tt.encoder, delta)
+			}
+
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncoderDrivesSlider(t *testing.T) {
+	d := newTestDeej(t, "")
+	events := d.SubscribeToSliderMoveEventsBuffered(8)
+
+	sendStates(d, `{"id":"sensor-enc2","value":5}`, `{"id":"sensor-enc2","value":0}`)
+
+	moves := receiveSliderMoves(events)
+	if len(moves) != 1 || moves[0].SliderID != 2 || moves[0].PercentValue != 0.6 {
+		t.Errorf("got %v, want one move of slider 2 to 0.6", moves)
+	}
+}
+
+func TestAcceptPotReading(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   int
+		readings []float64
+		want     []bool
+	}{
+		{"disabled lets everything through", 0, []float64{50, 0, 50}, []bool{true, true, true}},
+		{"lone spike is suppressed", 10, []float64{50, 51, 0, 51, 52}, []bool{true, true, false, true, true}},
+		{"confirmed jump goes through one sample late", 10, []float64{50, 100, 99, 98}, []bool{true, false, true, true}},
+		{"small steps always pass", 10, []float64{50, 58, 66, 74}, []bool{true, true, true, true}},
+		{"spike in the other direction", 10, []float64{20, 100, 21}, []bool{true, false, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, fmt.Sprintf("slider_spike_filter: %d\n", tt.filter))
+
+			for i, reading := range tt.readings {
+				if got := d.acceptPotReading(0, reading); got != tt.want[i] {
+					t.Errorf("reading %d (%v): got %v, want %v", i, reading, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// observeLogs makes d log into an observer instead of discarding its logs
+func observeLogs(d *Deej) *observer.ObservedLogs {
+	core, logs := observer.New(zapcore.DebugLevel)
+	d.logger = zap.New(core).Sugar()
+	return logs
+}
+
+func TestHandleInfoState(t *testing.T) {
+	tests := []struct {
+		name        string
+		frames      []string
+		wantVersion int
+		wantKnown   bool
+		wantWarns   int
+		wantNotify  int
+	}{
+		{"no info frame", nil, 0, false, 0, 0},
+		{"info without protocol", []string{`{"id":"info","sliders":4}`}, 0, false, 0, 0},
+		{"supported version", []string{fmt.Sprintf(`{"id":"info","protocol":%d}`, supportedProtocolVersion)}, supportedProtocolVersion, true, 0, 0},
+		{"newer version warns once", []string{
+			fmt.Sprintf(`{"id":"info","protocol":%d}`, supportedProtocolVersion+1),
+			fmt.Sprintf(`{"id":"info","protocol":%d}`, supportedProtocolVersion+1),
+		}, supportedProtocolVersion + 1, true, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, "")
+			logs := observeLogs(d)
+			notifier := &recordingNotifier{}
+			d.notifier = notifier
+
+			sendStates(d, tt.frames...)
+
+			version, known := d.DeviceProtocolVersion()
+			if version != tt.wantVersion || known != tt.wantKnown {
+				t.Errorf("version: got %d (%v), want %d (%v)", version, known, tt.wantVersion, tt.wantKnown)
+			}
+			if warns := logs.FilterLevelExact(zapcore.WarnLevel).Len(); warns != tt.wantWarns {
+				t.Errorf("warnings: got %d, want %d", warns, tt.wantWarns)
+			}
+			if notified := len(notifier.Titles()); notified != tt.wantNotify {
+				t.Errorf("notifications: got %d, want %d", notified, tt.wantNotify)
+			}
+		})
+	}
+}
+
+func TestSliderMoveEventsBuffered(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+		want int
+	}{
+		{"unbuffered drops the burst", 0, 0},
+		{"small buffer keeps what fits", 4, 4},
+		{"large buffer keeps the whole burst", 16, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, "")
+			events := d.SubscribeToSliderMoveEventsBuffered(tt.size)
+
+			// nobody reads while the burst arrives
+			for i := 1; i <= 10; i++ {
+				sendStates(d, fmt.Sprintf(`{"id":"sensor-pot0","value":%d}`, i*10))
+			}
+
+			if moves := receiveSliderMoves(events); len(moves) != tt.want {
+				t.Errorf("got %d moves, want %d", len(moves), tt.want)
+			}
+		})
+	}
+}
+
+// timeoutError is a net.Error that timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestConnectionErrorReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"access denied", &os.PathError{Op: "open", Path: "COM3", Err: os.ErrPermission}, connReasonBusy},
+		{"wrapped access denied", fmt.Errorf("open serial port: %w", os.ErrPermission), connReasonBusy},
+		{"missing port", &os.PathError{Op: "open", Path: "/dev/ttyUSB0", Err: os.ErrNotExist}, connReasonNotFound},
+		{"deadline", fmt.Errorf("read: %w", os.ErrDeadlineExceeded), connReasonTimeout},
+		{"context deadline", fmt.Errorf("connect: %w", context.DeadlineExceeded), connReasonTimeout},
+		{"network timeout", &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}, connReasonTimeout},
+		{"anything else", errors.New("unexpected EOF"), connReasonIOError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := connectionErrorReason(tt.err); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSwitchActiveLow(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		frame string
+		want  bool
+	}{
+		{"boolean, active-high", "", `{"id":"binary_sensor-sw0","value":true}`, true},
+		{"boolean, active-low", "switch_active_low:\n  0: true\n", `{"id":"binary_sensor-sw0","value":true}`, false},
+		{"boolean off, active-low", "switch_active_low:\n  0: true\n", `{"id":"binary_sensor-sw0","value":false}`, true},
+		{"string, active-high", "", `{"id":"binary_sensor-sw0","state":"ON"}`, true},
+		{"string, active-low", "switch_active_low:\n  0: true\n", `{"id":"binary_sensor-sw0","state":"ON"}`, false},
+		{"string off, active-low", "switch_active_low:\n  0: true\n", `{"id":"binary_sensor-sw0","state":"off"}`, true},
+		{"other switch is active-low", "switch_active_low:\n  1: true\n", `{"id":"binary_sensor-sw0","state":"ON"}`, true},
+		{"explicitly active-high", "switch_active_low:\n  0: false\n", `{"id":"binary_sensor-sw0","value":true}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, tt.yaml)
+			events := make(chan SwitchEvent, 1)
+			d.switchConsumers = append(d.switchConsumers, events)
+
+			sendStates(d, tt.frame)
+
+			select {
+			case event := <-events:
+				if event.SwitchID != 0 || event.State != tt.want {
+					t.Errorf("got switch %d state %v, want switch 0 state %v", event.SwitchID, event.State, tt.want)
+				}
+			default:
+				t.Fatal("no switch event dispatched")
+			}
+		})
+	}
+}
+
+func TestNumericValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  interface{}
+		want   float64
+		wantOk bool
+	}{
+		{"number", 73.0, 73, true},
+		{"string", "73", 73, true},
+		{"padded string", " 73.5 ", 73.5, true},
+		{"negative string", "-2", -2, true},
+		{"not a number", "loud", 0, false},
+		{"empty string", "", 0, false},
+		{"NaN", "NaN", 0, false},
+		{"infinity", "Inf", 0, false},
+		{"boolean", true, 0, false},
+		{"missing", nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := numericValue(tt.value)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("got %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestStringifiedPotValue(t *testing.T) {
+	d := newTestDeej(t, "")
+	events := d.SubscribeToSliderMoveEventsBuffered(8)
+
+	sendStates(d, `{"id":"sensor-pot1","value":"73"}`, `{"id":"sensor-pot2","value":"n/a"}`)
+
+	moves := receiveSliderMoves(events)
+	if len(moves) != 1 || moves[0].SliderID != 1 || moves[0].PercentValue != 0.73 {
+		t.Errorf("got %v, want one move of slider 1 to 0.73", moves)
+	}
+}
+
+func TestSetVerbose(t *testing.T) {
+	tests := []struct {
+		name         string
+		startVerbose bool
+		base         zapcore.Level
+		toggles      []bool
+		want         zapcore.Level
+	}{
+		{"verbose from the start", true, zapcore.WarnLevel, nil, zapcore.DebugLevel},
+		{"turned on", false, zapcore.InfoLevel, []bool{true}, zapcore.DebugLevel},
+		{"turned off restores base level", false, zapcore.WarnLevel, []bool{true, false}, zapcore.WarnLevel},
+		{"started verbose, turned off", true, zapcore.ErrorLevel, []bool{false}, zapcore.ErrorLevel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, "")
+			d.SetVerbose(tt.startVerbose)
+
+			level := zap.NewAtomicLevelAt(tt.base)
+			d.SetLogLevel(level)
+
+			for _, verbose := range tt.toggles {
+				d.SetVerbose(verbose)
+			}
+
+			if got := level.Level(); got != tt.want {
+				t.Errorf("level = %v, want %v", got, tt.want)
+			}
+			if got, want := d.Verbose(), tt.want == zapcore.DebugLevel; got != want {
+				t.Errorf("Verbose() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestIDRemap(t *testing.T) {
+	const sliderRemap = "slider_remap:\n  1: 0\n  2: 1\n"
+
+	tests := []struct {
+		name       string
+		yaml       string
+		frame      string
+		wantSlider int // -1 when the frame is a switch
+		wantSwitch int // -1 when the frame is a slider
+	}{
+		{"device slider 1 is slider 0", sliderRemap, `{"id":"sensor-pot1","value":50}`, 0, -1},
+		{"device slider 2 is slider 1", sliderRemap, `{"id":"sensor-pot2","value":50}`, 1, -1},
+		{"unlisted slider keeps its id", sliderRemap, `{"id":"sensor-pot4","value":50}`, 4, -1},
+		{"collision keeps the lowest device id", "slider_remap:\n  1: 0\n  3: 0\n", `{"id":"sensor-pot3","value":50}`, 3, -1},
+		{"device switch 1 is switch 0", "switch_remap:\n  1: 0\n", `{"id":"binary_sensor-sw1","value":true}`, -1, 0},
+		{"slider remap leaves switches alone", sliderRemap, `{"id":"binary_sensor-sw1","value":true}`, -1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, tt.yaml)
+			sliderEvents := d.SubscribeToSliderMoveEventsBuffered(8)
+			switchEvents := make(chan SwitchEvent, 1)
+			d.switchConsumers = append(d.switchConsumers, switchEvents)
+
+			sendStates(d, tt.frame)
+
+			if tt.wantSlider >= 0 {
+				moves := receiveSliderMoves(sliderEvents)
+				if len(moves) != 1 || moves[0].SliderID != tt.wantSlider {
+					t.Errorf("got slider moves %+v, want one for slider %d", moves, tt.wantSlider)
+				}
+				return
+			}
+
+			select {
+			case event := <-switchEvents:
+				if event.SwitchID != tt.wantSwitch {
+					t.Errorf("got switch %d, want switch %d", event.SwitchID, tt.wantSwitch)
+				}
+			default:
+				t.Fatal("no switch event dispatched")
+			}
+		})
+	}
+}
+
+func TestIDPatterns(t *testing.T) {
+	const customPatterns = "slider_id_pattern: '^sensor-volume_(\\d+)$'\nswitch_id_pattern: '^binary_sensor-mute_(\\d+)$'\n"
+
+	tests := []struct {
+		name       string
+		yaml       string
+		frame      string
+		wantSlider int // -1 when no slider move is expected
+		wantSwitch int // -1 when no switch event is expected
+	}{
+		{"default slider pattern", "", `{"id":"sensor-pot3","value":50}`, 3, -1},
+		{"custom slider pattern", customPatterns, `{"id":"sensor-volume_3","value":50}`, 3, -1},
+		{"default id under custom pattern", customPatterns, `{"id":"sensor-pot3","value":50}`, -1, -1},
+		{"custom switch pattern", customPatterns, `{"id":"binary_sensor-mute_2","value":true}`, -1, 2},
+		{"no capture group falls back", "slider_id_pattern: '^sensor-volume_\\d+$'\n", `{"id":"sensor-pot1","value":50}`, 1, -1},
+		{"invalid regex falls back", "switch_id_pattern: '^binary_sensor-sw(\\d+$'\n", `{"id":"binary_sensor-sw4","value":true}`, -1, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, tt.yaml)
+			sliderEvents := d.SubscribeToSliderMoveEventsBuffered(8)
+			switchEvents := make(chan SwitchEvent, 1)
+			d.switchConsumers = append(d.switchConsumers, switchEvents)
+
+			sendStates(d, tt.frame)
+
+			moves := receiveSliderMoves(sliderEvents)
+			if tt.wantSlider < 0 && len(moves) != 0 {
+				t.Errorf("got slider moves %+v, want none", moves)
+			} else if tt.wantSlider >= 0 && (len(moves) != 1 || moves[0].SliderID != tt.wantSlider) {
+				t.Errorf("got slider moves %+v, want one for slider %d", moves, tt.wantSlider)
+			}
+
+			select {
+			case event := <-switchEvents:
+				if event.SwitchID != tt.wantSwitch {
+					t.Errorf("got switch %d, want switch %d", event.SwitchID, tt.wantSwitch)
+				}
+			default:
+				if tt.wantSwitch >= 0 {
+					t.Errorf("no switch event dispatched, want switch %d", tt.wantSwitch)
+				}
+			}
+		})
+	}
+}
+
+func TestNumberAndSelectEntities(t *testing.T) {
+	type switchState struct {
+		id int
+		on bool
+	}
+
+	tests := []struct {
+		name        string
+		yaml        string
+		frame       string
+		wantSlider  int     // -1 when no slider move is expected
+		wantPercent float32 // the slider move's value
+		wantSwitch  *switchState
+	}{
+		{"number drives a slider", "", `{"id":"number-volume2","value":40}`, 2, 0.4, nil},
+		{"number sent as a string", "", `{"id":"number-volume2","value":"40.0","state":"40.0"}`, 2, 0.4, nil},
+		{"custom number pattern", "number_id_pattern: '^number-level_(\\d+)$'\n", `{"id":"number-level_1","value":75}`, 1, 0.75, nil},
+		{"select on", "", `{"id":"select-sw3","state":"On"}`, -1, 0, &switchState{3, true}},
+		{"select other option", "", `{"id":"select-sw3","state":"Off"}`, -1, 0, &switchState{3, false}},
+		{"custom on states", "select_on_states: [muted, silent]\n", `{"id":"select-sw0","value":"Silent"}`, -1, 0, &switchState{0, true}},
+		{"select without an option", "", `{"id":"select-sw3","value":1}`, -1, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, tt.yaml)
+			sliderEvents := d.SubscribeToSliderMoveEventsBuffered(8)
+			switchEvents := make(chan SwitchEvent, 1)
+			d.switchConsumers = append(d.switchConsumers, switchEvents)
+
+			sendStates(d, tt.frame)
+
+			moves := receiveSliderMoves(sliderEvents)
+			if tt.wantSlider < 0 && len(moves) != 0 {
+				t.Errorf("got slider moves %+v, want none", moves)
+			} else if tt.wantSlider >= 0 && (len(moves) != 1 || moves[0].SliderID != tt.wantSlider ||
+				math.Abs(float64(moves[0].PercentValue-tt.wantPercent)) > 1e-6) {
+				t.Errorf("got slider moves %+v, want slider %d at %v", moves, tt.wantSlider, tt.wantPercent)
+			}
+
+			select {
+			case event := <-switchEvents:
+				if tt.wantSwitch == nil {
+					t.Errorf("got switch event %+v, want none", event)
+				} else if event.SwitchID != tt.wantSwitch.id || event.State != tt.wantSwitch.on {
+					t.Errorf("got switch %d on %v, want switch %d on %v", event.SwitchID, event.State, tt.wantSwitch.id, tt.wantSwitch.on)
+				}
+			default:
+				if tt.wantSwitch != nil {
+					t.Errorf("no switch event dispatched, want switch %d", tt.wantSwitch.id)
+				}
+			}
+		})
+	}
+}
+
+func TestRelayStateStorage(t *testing.T) {
+	tests := []struct {
+		name       string
+		frame      string
+		wantSensor bool // stored with the pots, sent to relay clients when they connect
+		wantSwitch bool // stored with the switches
+	}{
+		{"pot", `{"id":"sensor-pot0","value":40}`, true, false},
+		{"switch", `{"id":"binary_sensor-sw1","value":true}`, false, true},
+		{"number entity", `{"id":"number-volume2","value":40}`, true, false},
+		{"select entity", `{"id":"select-sw3","value":"On","state":"On"}`, false, true},
+		{"other entity", `{"id":"text_sensor-uptime","value":"1h"}`, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, "")
+			var raw map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.frame), &raw); err != nil {
+				t.Fatalf("bad test frame: %v", err)
+			}
+			id := raw["id"].(string)
+
+			sendStates(d, tt.frame)
+
+			d.stateMutex.RLock()
+			sensor, gotSensor := d.sensorStates[id]
+			switchState, gotSwitch := d.switchStates[id]
+			d.stateMutex.RUnlock()
+
+			if gotSensor != tt.wantSensor || gotSwitch != tt.wantSwitch {
+				t.Fatalf("stored as sensor %v, switch %v, want sensor %v, switch %v", gotSensor, gotSwitch, tt.wantSensor, tt.wantSwitch)
+			}
+
+			stored := sensor
+			if gotSwitch {
+				stored = switchState
+			}
+			if stored != nil && fmt.Sprint(stored["value"]) != fmt.Sprint(raw["value"]) {
+				t.Errorf("stored value %v, want %v", stored["value"], raw["value"])
+			}
+		})
+	}
+}
+
+// failingSessionFinder can't list sessions, and counts how often it was released
+type failingSessionFinder struct {
+	fakeSessionFinder
+
+	lookups  int
+	releases int
+}
+
+func (f *failingSessionFinder) GetAllSessions() ([]Session, error) {
+	f.lookups++
+	return nil, errors.New("audio service unavailable")
+}
+
+func (f *failingSessionFinder) Release() error {
+	f.releases++
+	return nil
+}
+
+func TestInitializeFailureDoesNotRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       string // written as config.yaml unless empty
+		wantNotified string
+		wantLookups  int
+		wantReleases int
+	}{
+		{"missing config", "", "Can't find configuration!", 0, 0},
+		{"invalid button actions", singleActionYAML("{type: kill, process: explorer.exe}"), "Invalid button actions!", 0, 0},
+		{"sessions unavailable", "slider_mapping:\n  0: master\n", "Can't access audio sessions!", 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.config != "" {
+				writeTestFile(t, filepath.Join(dir, userConfigFilepath), tt.config)
+			}
+			useWorkingDir(t, dir)
+
+			previousExe := executablePath
+			executablePath = func() (string, error) { return filepath.Join(dir, "deej"), nil }
+			t.Cleanup(func() { executablePath = previousExe })
+
+			d := newTestDeej(t, "")
+			logs := observeLogs(d)
+			notifier := &recordingNotifier{}
+			d.notifier = notifier
+			d.config.notifier = notifier
+			d.config.logger = d.logger
+
+			finder := &failingSessionFinder{}
+			sessions, err := newSessionMap(d, d.logger, finder)
+			if err != nil {
+				t.Fatalf("create session map: %v", err)
+			}
+			d.sessions = sessions
+
+			if err := d.Initialize(); err == nil {
+				t.Fatal("Initialize succeeded, want an error")
+			}
+
+			if titles := notifier.Titles(); len(titles) != 1 || titles[0] != tt.wantNotified {
+				t.Errorf("notified %q, want %q", titles, tt.wantNotified)
+			}
+			if finder.lookups != tt.wantLookups || finder.releases != tt.wantReleases {
+				t.Errorf("session finder looked up %d times and released %d times, want %d and %d",
+					finder.lookups, finder.releases, tt.wantLookups, tt.wantReleases)
+			}
+			if started := logs.FilterMessage("Run loop starting").Len(); started != 0 {
+				t.Error("run loop started after a failed initialization")
+			}
+		})
+	}
+}
+
+// stuckSessionFinder's Release fails, or blocks until unblock is closed if it's set
+type stuckSessionFinder struct {
+	fakeSessionFinder
+
+	unblock chan struct{}
+}
+
+func (f *stuckSessionFinder) Release() error {
+	if f.unblock != nil {
+		<-f.unblock
+		return nil
+	}
+	return errors.New("release failed")
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		blocks      bool
+		wantForced  bool
+		wantPending string
+	}{
+		{"hung session release forces exit", true, true, "session release"},
+		{"failed session release exits with error", false, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, "")
+			logs := observeLogs(d)
+			d.config.ShutdownTimeout = 50 * time.Millisecond
+
+			finder := &stuckSessionFinder{}
+			if tt.blocks {
+				finder.unblock = make(chan struct{})
+				t.Cleanup(func() { close(finder.unblock) })
+			}
+			sessions, err := newSessionMap(d, d.logger, finder)
+			if err != nil {
+				t.Fatalf("create session map: %v", err)
+			}
+			d.sessions = sessions
+
+			// stand in for the config watcher run() starts, which shutdown stops first
+			go func() { <-d.config.stopWatcherChannel }()
+
+			start := time.Now()
+			if code := d.shutdown(); code != 1 {
+				t.Errorf("exit code %d, want 1", code)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("shutdown took %v, want it cut off after the timeout", elapsed)
+			}
+
+			forced := logs.FilterMessage("Shutdown did not finish in time, forcing exit").All()
+			if (len(forced) == 1) != tt.wantForced {
+				t.Fatalf("forced exit logged %d times, want forced %v", len(forced), tt.wantForced)
+			}
+			if tt.wantForced {
+				if pending := forced[0].ContextMap()["pending"]; pending != tt.wantPending {
+					t.Errorf("pending stage %q, want %q", pending, tt.wantPending)
+				}
+			}
+		})
+	}
+}
+
+func TestSliderSnap(t *testing.T) {
+	const snap = "slider_snap:\n  0: [100, 0, 25, 50, 75]\n"
+
+	tests := []struct {
+		name  string
+		yaml  string
+		frame string
+		want  float32
+	}{
+		{"snaps down to the nearest point", snap, `{"id":"sensor-pot0","value":48}`, 0.5},
+		{"snaps up to the nearest point", snap, `{"id":"sensor-pot0","value":96}`, 1},
+		{"outside the tolerance stays continuous", snap, `{"id":"sensor-pot0","value":62}`, 0.62},
+		{"wider tolerance", snap + "slider_snap_tolerance: 15\n", `{"id":"sensor-pot0","value":62}`, 0.5},
+		{"other sliders don't snap", snap, `{"id":"sensor-pot1","value":48}`, 0.48},
+		{"snaps after inversion", snap + "invert_sliders: true\n", `{"id":"sensor-pot0","value":53}`, 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, tt.yaml)
+			sliderEvents := d.SubscribeToSliderMoveEventsBuffered(8)
+
+			sendStates(d, tt.frame)
+
+			moves := receiveSliderMoves(sliderEvents)
+			if len(moves) != 1 {
+				t.Fatalf("got %d slider moves, want 1", len(moves))
+			}
+			if got := moves[0].PercentValue; math.Abs(float64(got-tt.want)) > 1e-6 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSliderExpo(t *testing.T) {
+	const expo = "slider_expo:\n  0: 1\n"
+
+	tests := []struct {
+		name  string
+		yaml  string
+		frame string
+		want  float32
+	}{
+		{"curve applied", expo, `{"id":"sensor-pot0","value":75}`, 0.5625},
+		{"center stays", expo, `{"id":"sensor-pot0","value":50}`, 0.5},
+		{"other sliders stay linear", expo, `{"id":"sensor-pot1","value":75}`, 0.75},
+		{"zero is linear", "slider_expo:\n  0: 0\n", `{"id":"sensor-pot0","value":75}`, 0.75},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, tt.yaml)
+			sliderEvents := d.SubscribeToSliderMoveEventsBuffered(8)
+
+			sendStates(d, tt.frame)
+
+			moves := receiveSliderMoves(sliderEvents)
+			if len(moves) != 1 {
+				t.Fatalf("got %d slider moves, want 1", len(moves))
+			}
+			if got := moves[0].PercentValue; math.Abs(float64(got-tt.want)) > 1e-6 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSliderOverflow(t *testing.T) {
+	const wrap = "slider_overflow: wrap\n"
+
+	tests := []struct {
+		name  string
+		yaml  string
+		value int // raw pot reading, in percent
+		want  float32
+	}{
+		{"clamp by default, over", "", 125, 1},
+		{"clamp by default, under", "", -20, 0},
+		{"clamp, in range", "slider_overflow: clamp\n", 40, 0.4},
+		{"wrap, over", wrap, 125, 0.25},
+		{"wrap, under", wrap, -20, 0.8},
+		{"wrap, in range", wrap, 40, 0.4},
+		{"wrap keeps the top end", wrap, 100, 1},
+		{"unknown mode clamps", "slider_overflow: bounce\n", 125, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, tt.yaml)
+			sliderEvents := d.SubscribeToSliderMoveEventsBuffered(8)
+
+			sendStates(d, fmt.Sprintf(`{"id":"sensor-pot0","value":%d}`, tt.value))
+
+			moves := receiveSliderMoves(sliderEvents)
+			if len(moves) != 1 {
+				t.Fatalf("got %d slider moves, want 1", len(moves))
+			}
+			if got := moves[0].PercentValue; math.Abs(float64(got-tt.want)) > 1e-6 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildInfoString(t *testing.T) {
+	tests := []struct {
+		info BuildInfo
+		want string
+	}{
+		{
+			BuildInfo{VersionTag: "v1.2.34", GitCommit: "1a2b3c4", BuildType: "release", BuildDate: "2026-01-02T03:04:05Z"},
+			"deej v1.2.34 (release), commit 1a2b3c4, built 2026-01-02T03:04:05Z",
+		},
+		{
+			BuildInfo{GitCommit: "1a2b3c4", BuildType: "dev"},
+			"deej unknown (dev), commit 1a2b3c4, built unknown",
+		},
+		{
+			BuildInfo{},
+			"deej unknown (unknown), commit unknown, built unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.info.String(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package deej
+
+// focusAssistActive reports whether Focus Assist is on. It's a variable so the platform lookup can be replaced
+var focusAssistActive = focusAssistActiveImpl
+
+// focusAssistPaused reports whether button actions are paused: pause_in_focus_assist is set and Windows
+// Focus Assist is on. If its state can't be read, actions run as usual
+func (bh *ButtonHandler) focusAssistPaused(config *ButtonsMapping) bool {
+	if !config.FocusAssistPause {
+		return false
+	}
+
+	active, err := focusAssistActive()
+	if err != nil {
+		bh.logger.Debugw("Failed to read Focus Assist state", "error", err)
+		return false
+	}
+
+	return active
+}
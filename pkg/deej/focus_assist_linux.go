@@ -0,0 +1,9 @@
+//go:build linux
+// +build linux
+
+package deej
+
+// focusAssistActiveImpl reports whether Focus Assist is on. Linux has no equivalent, so actions are never paused
+func focusAssistActiveImpl() (bool, error) {
+	return false, nil
+}
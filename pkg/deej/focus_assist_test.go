@@ -0,0 +1,57 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestFocusAssistPause(t *testing.T) {
+	const action = "button_actions:\n  1:\n    single:\n      steps:\n        - {type: keystroke, keys: ctrl+c}\n"
+
+	tests := []struct {
+		name       string
+		yaml       string
+		active     bool
+		readErr    error
+		wantPaused bool
+		wantTitles string
+	}{
+		{"focus assist on", "pause_in_focus_assist: true\n", true, nil, true, "[]"},
+		{"focus assist on, with a notification", "pause_in_focus_assist: true\nfocus_assist_notify: true\n", true, nil, true, "[Button action paused]"},
+		{"focus assist off", "pause_in_focus_assist: true\nfocus_assist_notify: true\n", false, nil, false, "[]"},
+		{"state can't be read", "pause_in_focus_assist: true\n", true, errors.New("NtQueryWnfStateData unavailable"), false, "[]"},
+		{"pausing disabled", "focus_assist_notify: true\n", true, nil, false, "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := focusAssistActive
+			focusAssistActive = func() (bool, error) { return tt.active, tt.readErr }
+			defer func() { focusAssistActive = previous }()
+
+			executor := &fakeExecutor{}
+			notifier := &recordingNotifier{}
+			bh := newTestButtonHandler()
+			bh.executor = executor
+			bh.notifier = notifier
+			bh.config = newTestConfig(t, tt.yaml+action).ButtonsMapping.ToButtonsMapping()
+
+			if got := bh.focusAssistPaused(bh.config); got != tt.wantPaused {
+				t.Errorf("focusAssistPaused() = %v, want %v", got, tt.wantPaused)
+			}
+
+			if err := bh.HandleButtonPress(1, ButtonActionSingle); err != nil {
+				t.Fatalf("HandleButtonPress: %v", err)
+			}
+			bh.actionsWG.Wait()
+
+			if ran := len(executor.calls) > 0; ran == tt.wantPaused {
+				t.Errorf("action ran: %v, want %v (calls %q)", ran, !tt.wantPaused, executor.calls)
+			}
+			if got := fmt.Sprint(notifier.Titles()); got != tt.wantTitles {
+				t.Errorf("notifications: got %s, want %s", got, tt.wantTitles)
+			}
+		})
+	}
+}
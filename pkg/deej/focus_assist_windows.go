@@ -0,0 +1,54 @@
+//go:build windows
+// +build windows
+
+package deej
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// WNF_SHEL_QUIETHOURS_ACTIVE_PROFILE_CHANGED, the notification state Windows keeps the Focus Assist profile in.
+// It isn't documented, but it's what the shell itself reads and hasn't changed since Focus Assist was introduced
+const wnfFocusAssistProfile uint64 = 0x0d83063ea3bf1c75
+
+// Focus Assist profiles: off, priority only and alarms only. Both of the latter count as on
+const focusAssistOff = 0
+
+var (
+	modntdll = syscall.NewLazyDLL("ntdll.dll")
+
+	procNtQueryWnfStateData = modntdll.NewProc("NtQueryWnfStateData")
+)
+
+// focusAssistActiveImpl reports whether Focus Assist (Quiet Hours) is on
+func focusAssistActiveImpl() (bool, error) {
+	if err := procNtQueryWnfStateData.Find(); err != nil {
+		return false, fmt.Errorf("NtQueryWnfStateData unavailable: %w", err)
+	}
+
+	stateName := wnfFocusAssistProfile
+	var changeStamp uint32
+	var profile uint32
+	size := uint32(unsafe.Sizeof(profile))
+
+	status, _, _ := procNtQueryWnfStateData.Call(
+		uintptr(unsafe.Pointer(&stateName)),
+		0,
+		0,
+		uintptr(unsafe.Pointer(&changeStamp)),
+		uintptr(unsafe.Pointer(&profile)),
+		uintptr(unsafe.Pointer(&size)),
+	)
+	if status != 0 {
+		return false, fmt.Errorf("NtQueryWnfStateData failed: NTSTATUS 0x%08x", uint32(status))
+	}
+
+	// never written since boot, Focus Assist hasn't been turned on
+	if size == 0 {
+		return false, nil
+	}
+
+	return profile != focusAssistOff, nil
+}
@@ -0,0 +1,132 @@
+package deej
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// How long after connecting deej waits for the device to show its sliders and switches
+	// before checking the config against them. ESPHome sends every entity's state right away
+	hardwareCheckDelay = 10 * time.Second
+)
+
+// noteDeviceSlider records that the device sent a reading for a slider (device id, before slider_remap)
+func (d *Deej) noteDeviceSlider(deviceIdx int) {
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
+
+	if deviceIdx+1 > d.deviceSliders {
+		d.deviceSliders = deviceIdx + 1
+	}
+}
+
+// noteDeviceSwitch records that the device sent a state for a switch (device id, before switch_remap)
+func (d *Deej) noteDeviceSwitch(deviceIdx int) {
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
+
+	if deviceIdx+1 > d.deviceSwitches {
+		d.deviceSwitches = deviceIdx + 1
+	}
+}
+
+// noteDeviceHardware records the slider and switch counts from an info frame, when it has them
+func (d *Deej) noteDeviceHardware(raw map[string]interface{}) {
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
+
+	if sliders, ok := raw["sliders"].(float64); ok && int(sliders) > d.deviceSliders {
+		d.deviceSliders = int(sliders)
+	}
+	if switches, ok := raw["switches"].(float64); ok && int(switches) > d.deviceSwitches {
+		d.deviceSwitches = int(switches)
+	}
+}
+
+// DeviceHardware returns how many sliders and switches the device has, as far as deej can tell:
+// the count from its info frame, or one past the highest id it has sent. 0 means none seen yet
+func (d *Deej) DeviceHardware() (sliders int, switches int) {
+	d.stateMutex.RLock()
+	defer d.stateMutex.RUnlock()
+
+	return d.deviceSliders, d.deviceSwitches
+}
+
+// scheduleHardwareCheck runs checkMappedHardware once the device had time to report its state.
+// Called on every connect and config reload; a pending check is pushed back rather than doubled
+func (d *Deej) scheduleHardwareCheck() {
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
+
+	if d.hardwareCheckTimer != nil {
+		d.hardwareCheckTimer.Reset(hardwareCheckDelay)
+		return
+	}
+
+	d.hardwareCheckTimer = time.AfterFunc(hardwareCheckDelay, d.checkMappedHardware)
+}
+
+// checkMappedHardware warns about slider_mapping and switches_mapping entries the device has no
+// slider or switch for, e.g. slider 5 on a board with 4 pots. Each one is only warned about once per config
+func (d *Deej) checkMappedHardware() {
+	if d.stopped.Load() {
+		return
+	}
+
+	sliders, switches := d.DeviceHardware()
+
+	var missing []string
+
+	if sliders > 0 {
+		available := make(map[int]bool, sliders)
+		for deviceIdx := 0; deviceIdx < sliders; deviceIdx++ {
+			available[d.config.RemapSlider(deviceIdx)] = true
+		}
+
+		d.config.SliderMapping.iterate(func(sliderIdx int, _ []string) {
+			if !available[sliderIdx] {
+				missing = append(missing, fmt.Sprintf("slider %d", sliderIdx))
+			}
+		})
+	}
+
+	if switches > 0 {
+		available := make(map[int]bool, switches)
+		for deviceIdx := 0; deviceIdx < switches; deviceIdx++ {
+			available[d.config.RemapSwitch(deviceIdx)] = true
+		}
+
+		d.config.SwitchesMapping.iterate(func(switchIdx int, _ []string) {
+			if !available[switchIdx] {
+				missing = append(missing, fmt.Sprintf("switch %d", switchIdx))
+			}
+		})
+	}
+
+	sort.Strings(missing)
+
+	d.stateMutex.Lock()
+	unwarned := missing[:0]
+	for _, name := range missing {
+		if !d.hardwareWarned[name] {
+			d.hardwareWarned[name] = true
+			unwarned = append(unwarned, name)
+		}
+	}
+	d.stateMutex.Unlock()
+
+	for _, name := range unwarned {
+		d.logger.Warnw("Config maps a slider or switch the device doesn't report, it will do nothing",
+			"mapping", name, "device_sliders", sliders, "device_switches", switches)
+	}
+}
+
+// resetHardwareWarnings lets checkMappedHardware warn again after the config changed
+func (d *Deej) resetHardwareWarnings() {
+	d.stateMutex.Lock()
+	defer d.stateMutex.Unlock()
+
+	d.hardwareWarned = make(map[string]bool)
+}
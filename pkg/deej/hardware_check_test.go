@@ -0,0 +1,58 @@
+package deej
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCheckMappedHardware(t *testing.T) {
+	pots := func(count int) []string {
+		frames := make([]string, count)
+		for i := range frames {
+			frames[i] = fmt.Sprintf(`{"id":"sensor-pot%d","value":50}`, i)
+		}
+		return frames
+	}
+
+	tests := []struct {
+		name        string
+		yaml        string
+		frames      []string
+		wantWarned  string
+		wantSliders int
+	}{
+		{"nothing seen yet", "slider_mapping:\n  5: master\n", nil, "[]", 0},
+		{"slider past the device's pots", "slider_mapping:\n  1: master\n  5: spotify.exe\n", pots(4), "[slider 5]", 4},
+		{"count from the info frame", "slider_mapping:\n  5: master\n", append(pots(4), `{"id":"info","sliders":6}`), "[]", 6},
+		{"switch past the device's switches",
+			"switches_mapping:\n  1: mic\n  2: master\n",
+			[]string{`{"id":"binary_sensor-sw0","value":true}`, `{"id":"binary_sensor-sw1","value":false}`},
+			"[switch 2]", 0},
+		{"every mapping on the device", "slider_mapping:\n  0: master\n  3: mic\n", pots(4), "[]", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, tt.yaml)
+			logs := observeLogs(d)
+
+			sendStates(d, tt.frames...)
+
+			if sliders, _ := d.DeviceHardware(); sliders != tt.wantSliders {
+				t.Errorf("device sliders: got %d, want %d", sliders, tt.wantSliders)
+			}
+
+			// a second check doesn't warn about the same mapping again
+			d.checkMappedHardware()
+			d.checkMappedHardware()
+
+			warned := []string{}
+			for _, entry := range logs.FilterMessageSnippet("doesn't report").All() {
+				warned = append(warned, fmt.Sprint(entry.ContextMap()["mapping"]))
+			}
+			if got := fmt.Sprint(warned); got != tt.wantWarned {
+				t.Errorf("warned about %s, want %s", got, tt.wantWarned)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package deej
+
+import (
+	"time"
+)
+
+const (
+	// How often the idle dimmer looks at the last activity time
+	idleDimCheckInterval = time.Second
+)
+
+// markActivity records a slider move, switch flip or button press. If the device was dimmed for being
+// idle, it's told to restore its backlight
+func (d *Deej) markActivity() {
+	d.lastActivityAt.Store(time.Now().UnixNano())
+
+	if d.idleDimmed.Swap(false) {
+		d.sendIdleDimCommand(d.config.IdleRestoreCommand, "restore")
+	}
+}
+
+// runIdleDim sends idle_dim_command to the device once nothing has been touched for idle_dim_after.
+// markActivity sends idle_restore_command on the next activity
+func (d *Deej) runIdleDim() {
+	// the idle period starts now, not at the epoch
+	d.lastActivityAt.Store(time.Now().UnixNano())
+
+	ticker := time.NewTicker(idleDimCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if d.stopped.Load() {
+			return
+		}
+
+		d.checkIdleDim(time.Now())
+	}
+}
+
+// checkIdleDim dims the device if it has been idle long enough, and reports whether it did
+func (d *Deej) checkIdleDim(now time.Time) bool {
+	after := d.config.IdleDimAfter
+	if after <= 0 || d.config.IdleDimCommand == "" || d.idleDimmed.Load() {
+		return false
+	}
+
+	idle := now.Sub(time.Unix(0, d.lastActivityAt.Load()))
+	if idle < after {
+		return false
+	}
+
+	if !d.idleDimmed.CompareAndSwap(false, true) {
+		return false
+	}
+
+	d.logger.Debugw("No activity, dimming device", "idle", idle.Round(time.Second))
+	d.sendIdleDimCommand(d.config.IdleDimCommand, "dim")
+
+	return true
+}
+
+// sendIdleDimCommand writes a dim or restore command to the device
+func (d *Deej) sendIdleDimCommand(command string, kind string) {
+	if command == "" {
+		return
+	}
+
+	if err := d.WriteToDevice([]byte(command)); err != nil {
+		d.logger.Debugw("Failed to send idle dim command to device", "command", kind, "error", err)
+	}
+}
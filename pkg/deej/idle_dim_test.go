@@ -0,0 +1,63 @@
+package deej
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdleDim(t *testing.T) {
+	const yaml = "idle_dim_after: 30\nidle_dim_command: DIM\nidle_restore_command: RESTORE\n"
+
+	// each step optionally feeds a frame from the device, then checks after being idle for a while
+	type step struct {
+		frame string
+		idle  time.Duration
+	}
+
+	tests := []struct {
+		name        string
+		yaml        string
+		steps       []step
+		wantWritten string
+	}{
+		{"active device isn't dimmed", yaml, []step{{"", 10 * time.Second}}, ""},
+		{"idle device is dimmed once", yaml, []step{{"", 31 * time.Second}, {"", time.Minute}}, "DIM"},
+		{"slider move restores", yaml, []step{
+			{`{"id":"sensor-pot0","value":20}`, 0},
+			{"", 31 * time.Second},
+			{`{"id":"sensor-pot0","value":30}`, 0},
+		}, "DIM RESTORE"},
+		{"resting slider doesn't restore", yaml, []step{
+			{`{"id":"sensor-pot0","value":50}`, 0},
+			{"", 31 * time.Second},
+			{`{"id":"sensor-pot0","value":50}`, 0},
+		}, "DIM"},
+		{"switch flip restores, then idles again", yaml, []step{
+			{"", 31 * time.Second},
+			{`{"id":"binary_sensor-sw0","value":true}`, 10 * time.Second},
+			{"", 31 * time.Second},
+		}, "DIM RESTORE DIM"},
+		{"disabled", "idle_dim_command: DIM\n", []step{{"", time.Hour}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, tt.yaml)
+			io := &fakeIO{}
+			d.io = io
+			d.lastActivityAt.Store(time.Now().UnixNano())
+
+			for _, s := range tt.steps {
+				if s.frame != "" {
+					sendStates(d, s.frame)
+				}
+				d.checkIdleDim(time.Now().Add(s.idle))
+			}
+
+			if got := strings.Join(io.written, " "); got != tt.wantWritten {
+				t.Errorf("written: got %q, want %q", got, tt.wantWritten)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package deej
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// How often the I/O watchdog looks at the last event time
+	ioWatchdogCheckInterval = 5 * time.Second
+)
+
+// markEventReceived records that the active transport just delivered an event
+func (d *Deej) markEventReceived() {
+	d.lastEventAt.Store(time.Now().UnixNano())
+}
+
+// runIOWatchdog restarts the active I/O interface when it claims to be connected but no event has arrived
+// within io_watchdog_timeout. This catches firmware that hangs while keeping the connection open
+func (d *Deej) runIOWatchdog() {
+	// give the first connection a full window before judging it
+	d.markEventReceived()
+
+	ticker := time.NewTicker(ioWatchdogCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if d.stopped.Load() {
+			return
+		}
+
+		d.checkIOWatchdog(time.Now())
+	}
+}
+
+// checkIOWatchdog restarts the active I/O interface if it went silent, and reports whether it did
+func (d *Deej) checkIOWatchdog(now time.Time) bool {
+	timeout := d.config.IOWatchdogTimeout
+	if timeout <= 0 {
+		return false
+	}
+
+	d.ioMutex.Lock()
+	io := d.io
+	d.ioMutex.Unlock()
+
+	reporter, ok := io.(ConnectionReporter)
+	if !ok || !reporter.IsConnected() {
+		return false
+	}
+
+	silence := now.Sub(time.Unix(0, d.lastEventAt.Load()))
+	if silence < timeout {
+		return false
+	}
+
+	d.logger.Warnw("No events received from connected device, restarting I/O interface",
+		"silence", silence.Round(time.Second), "reason", connReasonIdle)
+	d.notifier.Notify("Device went silent",
+		fmt.Sprintf("No events received for %s, reconnecting.", silence.Round(time.Second)))
+
+	// reset the clock so the reconnect gets a full window too
+	d.markEventReceived()
+
+	io.Stop()
+	if !io.WaitForStop(interfaceStopTimeout) {
+		d.logger.Warn("I/O interface did not stop within timeout, proceeding anyway")
+	}
+	<-time.After(configReloadStopDelay)
+
+	if err := io.Start(); err != nil {
+		d.logger.Warnw("Failed to restart I/O interface after watchdog timeout", "error", err, "reason", connectionErrorReason(err))
+	}
+
+	return true
+}
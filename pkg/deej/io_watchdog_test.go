@@ -0,0 +1,72 @@
+package deej
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeIO is an I/O interface that counts restarts and keeps what was written to the device
+type fakeIO struct {
+	connected bool
+	starts    int
+	stops     int
+	written   []string
+}
+
+func (f *fakeIO) Start() error                                      { f.starts++; return nil }
+func (f *fakeIO) Stop()                                             { f.stops++ }
+func (f *fakeIO) WaitForStop(timeout time.Duration) bool            { return true }
+func (f *fakeIO) SubscribeToSliderMoveEvents() chan SliderMoveEvent { return nil }
+func (f *fakeIO) SubscribeToSwitchEvents() chan SwitchEvent         { return nil }
+func (f *fakeIO) IsConnected() bool                                 { return f.connected }
+func (f *fakeIO) Write(data []byte) error                           { f.written = append(f.written, string(data)); return nil }
+
+func TestCheckIOWatchdog(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		connected   bool
+		silence     time.Duration
+		wantRestart bool
+	}{
+		{"disabled", "", true, time.Hour, false},
+		{"recent event", "io_watchdog_timeout: 10\n", true, 5 * time.Second, false},
+		{"silent past the timeout", "io_watchdog_timeout: 10\n", true, 11 * time.Second, true},
+		{"silent but disconnected", "io_watchdog_timeout: 10\n", false, 11 * time.Second, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, tt.yaml)
+			notifier := &recordingNotifier{}
+			d.notifier = notifier
+
+			io := &fakeIO{connected: tt.connected}
+			d.io = io
+
+			// pretend the last event arrived a while ago
+			now := time.Now()
+			d.lastEventAt.Store(now.Add(-tt.silence).UnixNano())
+
+			if got := d.checkIOWatchdog(now); got != tt.wantRestart {
+				t.Fatalf("restarted: got %v, want %v", got, tt.wantRestart)
+			}
+
+			wantRestarts := 0
+			if tt.wantRestart {
+				wantRestarts = 1
+			}
+			if io.stops != wantRestarts || io.starts != wantRestarts {
+				t.Errorf("got %d stops and %d starts, want %d of each", io.stops, io.starts, wantRestarts)
+			}
+			if len(notifier.Titles()) != wantRestarts {
+				t.Errorf("got notifications %q, want %d", notifier.Titles(), wantRestarts)
+			}
+
+			// a restart resets the clock, so the next check leaves the new connection alone
+			if tt.wantRestart && d.checkIOWatchdog(now.Add(time.Second)) {
+				t.Error("watchdog fired again right after restarting")
+			}
+		})
+	}
+}
@@ -3,11 +3,14 @@ package deej
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stalexteam/deej_esp32/pkg/deej/util"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 const (
@@ -15,21 +18,77 @@ const (
 
 	logDirectory = "logs"
 	logFilename  = "deej-latest-run.log"
+
+	logFormatConsole = "console"
+	logFormatJSON    = "json"
+
+	// log file rotation defaults
+	defaultLogMaxSizeMB  = 10
+	defaultLogMaxBackups = 3
 )
 
-// NewLogger provides a logger instance for the whole program
-func NewLogger(buildType string) (*zap.SugaredLogger, error) {
+// LoggerOptions holds the user's logging preferences. Empty fields keep the build type's defaults
+type LoggerOptions struct {
+	Level  string // debug, info, warn or error
+	Format string // console or json
+
+	MaxSizeMB  int // log file size that triggers rotation
+	MaxBackups int // rotated log files to keep
+}
+
+// LoadLoggerOptions reads the logging keys from the user config file. The logger has to exist before
+// the config is properly loaded, so this peeks at the file on its own - any problem with it just means defaults
+func LoadLoggerOptions() LoggerOptions {
+	v := viper.New()
+	v.SetConfigName(userConfigName)
+	v.SetConfigType(configType)
+	for _, configPath := range userConfigPaths() {
+		v.AddConfigPath(configPath)
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		return LoggerOptions{}
+	}
+
+	return LoggerOptions{
+		Level:      v.GetString(configKey_LogLevel),
+		Format:     v.GetString(configKey_LogFormat),
+		MaxSizeMB:  v.GetInt(configKey_LogMaxSizeMB),
+		MaxBackups: v.GetInt(configKey_LogMaxBackups),
+	}
+}
+
+// parseLogLevel maps a log_level config value to its zap level
+func parseLogLevel(level string) (zapcore.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "warn", "warning":
+		return zapcore.WarnLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	}
+
+	return zapcore.InfoLevel, fmt.Errorf("unknown log level: %q", level)
+}
+
+// NewLogger provides a logger instance for the whole program, along with the level it logs at,
+// which can be changed while running (see Deej.SetLogLevel)
+func NewLogger(buildType string, options LoggerOptions) (*zap.SugaredLogger, zap.AtomicLevel, error) {
 	var loggerConfig zap.Config
 
 	// release: info and above, log to file only (no UI)
 	if buildType == buildTypeRelease {
 		if err := util.EnsureDirExists(logDirectory); err != nil {
-			return nil, fmt.Errorf("ensure log directory exists: %w", err)
+			return nil, zap.AtomicLevel{}, fmt.Errorf("ensure log directory exists: %w", err)
 		}
 
 		loggerConfig = zap.NewProductionConfig()
 
-		loggerConfig.OutputPaths = []string{filepath.Join(logDirectory, logFilename)}
+		// the log file is written through a rotating writer (see below), not by zap itself
+		loggerConfig.OutputPaths = []string{}
 		loggerConfig.Encoding = "console"
 
 		// development: debug and above, log to stderr only, colorful
@@ -53,13 +112,78 @@ func NewLogger(buildType string) (*zap.SugaredLogger, error) {
 	// disable stack traces for cleaner logs
 	loggerConfig.DisableStacktrace = true
 
-	logger, err := loggerConfig.Build()
+	// apply user preferences on top of the build type defaults. problems are reported once the logger exists
+	var optionWarnings []string
+
+	if options.Level != "" {
+		if level, err := parseLogLevel(options.Level); err != nil {
+			optionWarnings = append(optionWarnings, err.Error())
+		} else {
+			loggerConfig.Level = zap.NewAtomicLevelAt(level)
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(options.Format)) {
+	case "", logFormatConsole:
+	case logFormatJSON:
+		loggerConfig.Encoding = logFormatJSON
+
+		// aggregators want plain values, not our column-aligned ones
+		loggerConfig.EncoderConfig.EncodeLevel = zapcore.LowercaseLevelEncoder
+		loggerConfig.EncoderConfig.EncodeName = zapcore.FullNameEncoder
+		loggerConfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	default:
+		optionWarnings = append(optionWarnings, fmt.Sprintf("unknown log format: %q", options.Format))
+	}
+
+	var buildOptions []zap.Option
+
+	// release: write to a size-capped, rotating log file instead of letting it grow forever
+	if buildType == buildTypeRelease {
+		rotator := newLogRotator(options)
+
+		var encoder zapcore.Encoder
+		if loggerConfig.Encoding == logFormatJSON {
+			encoder = zapcore.NewJSONEncoder(loggerConfig.EncoderConfig)
+		} else {
+			encoder = zapcore.NewConsoleEncoder(loggerConfig.EncoderConfig)
+		}
+
+		buildOptions = append(buildOptions, zap.WrapCore(func(zapcore.Core) zapcore.Core {
+			return zapcore.NewCore(encoder, zapcore.AddSync(rotator), loggerConfig.Level)
+		}))
+	}
+
+	logger, err := loggerConfig.Build(buildOptions...)
 	if err != nil {
-		return nil, fmt.Errorf("create zap logger: %w", err)
+		return nil, zap.AtomicLevel{}, fmt.Errorf("create zap logger: %w", err)
 	}
 
 	// no reason not to use the sugared logger - it's fast enough for anything we're gonna do
 	sugar := logger.Sugar()
 
-	return sugar, nil
+	for _, warning := range optionWarnings {
+		sugar.Warnw("Ignoring invalid logging option", "error", warning)
+	}
+
+	return sugar, loggerConfig.Level, nil
+}
+
+// newLogRotator creates the rotating writer behind the release log file
+func newLogRotator(options LoggerOptions) *lumberjack.Logger {
+	maxSize := options.MaxSizeMB
+	if maxSize <= 0 {
+		maxSize = defaultLogMaxSizeMB
+	}
+
+	maxBackups := options.MaxBackups
+	if maxBackups <= 0 {
+		maxBackups = defaultLogMaxBackups
+	}
+
+	return &lumberjack.Logger{
+		Filename:   filepath.Join(logDirectory, logFilename),
+		MaxSize:    maxSize,
+		MaxBackups: maxBackups,
+	}
 }
@@ -0,0 +1,94 @@
+package deej
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		level   string
+		want    zapcore.Level
+		wantErr bool
+	}{
+		{"debug", zapcore.DebugLevel, false},
+		{"info", zapcore.InfoLevel, false},
+		{"warn", zapcore.WarnLevel, false},
+		{"warning", zapcore.WarnLevel, false},
+		{"error", zapcore.ErrorLevel, false},
+		{" DEBUG ", zapcore.DebugLevel, false},
+		{"verbose", zapcore.InfoLevel, true},
+		{"", zapcore.InfoLevel, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			got, err := parseLogLevel(tt.level)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error: got %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLogRotator(t *testing.T) {
+	tests := []struct {
+		name           string
+		options        LoggerOptions
+		wantMaxSize    int
+		wantMaxBackups int
+	}{
+		{"defaults", LoggerOptions{}, defaultLogMaxSizeMB, defaultLogMaxBackups},
+		{"configured", LoggerOptions{MaxSizeMB: 1, MaxBackups: 5}, 1, 5},
+		{"negative values fall back", LoggerOptions{MaxSizeMB: -1, MaxBackups: -2}, defaultLogMaxSizeMB, defaultLogMaxBackups},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rotator := newLogRotator(tt.options)
+			if rotator.MaxSize != tt.wantMaxSize || rotator.MaxBackups != tt.wantMaxBackups {
+				t.Errorf("got size %d backups %d, want size %d backups %d",
+					rotator.MaxSize, rotator.MaxBackups, tt.wantMaxSize, tt.wantMaxBackups)
+			}
+		})
+	}
+}
+
+func TestLogRotatorRotates(t *testing.T) {
+	dir := t.TempDir()
+
+	rotator := newLogRotator(LoggerOptions{MaxSizeMB: 1, MaxBackups: 2})
+	rotator.Filename = filepath.Join(dir, logFilename)
+	defer rotator.Close()
+
+	// 600KB fits under the 1MB threshold once, not twice
+	chunk := bytes.Repeat([]byte("x"), 600*1024)
+	for i := 0; i < 2; i++ {
+		if _, err := rotator.Write(chunk); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read log directory: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d log files after exceeding the threshold, want the current one and a backup", len(entries))
+	}
+
+	current, err := os.Stat(rotator.Filename)
+	if err != nil {
+		t.Fatalf("stat current log: %v", err)
+	}
+	if current.Size() != int64(len(chunk)) {
+		t.Errorf("current log holds %d bytes, want only the last write (%d)", current.Size(), len(chunk))
+	}
+}
@@ -0,0 +1,169 @@
+package deej
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/zap"
+)
+
+const (
+	// Delay between MQTT reconnection attempts
+	mqttRetryDelay = 5 * time.Second
+
+	// How long to wait for the client to flush and disconnect on stop (milliseconds)
+	mqttDisconnectQuiesce = 250
+
+	mqttSliderTopicFormat = "%s/slider/%d"
+	mqttSwitchTopicFormat = "%s/switch/%d"
+)
+
+// MqttPublisher publishes slider and switch state to an MQTT broker.
+// It is a no-op while MQTT_Broker is unset
+type MqttPublisher struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	mu     sync.Mutex // Protects client and broker
+	client mqtt.Client
+	broker string
+}
+
+// NewMqttPublisher creates an MqttPublisher instance
+func NewMqttPublisher(deej *Deej, logger *zap.SugaredLogger) (*MqttPublisher, error) {
+	logger = logger.Named("mqtt")
+
+	mp := &MqttPublisher{
+		deej:   deej,
+		logger: logger,
+	}
+
+	logger.Debug("Created MQTT publisher instance")
+
+	return mp, nil
+}
+
+func (mp *MqttPublisher) initialize() {
+	sliderEventsChannel := mp.deej.SubscribeToSliderMoveEvents()
+	switchEventsChannel := mp.deej.SubscribeToSwitchEvents()
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-sliderEventsChannel:
+				if !ok {
+					mp.logger.Debug("Slider events channel closed, MQTT publisher exiting")
+					return
+				}
+				mp.publish(mqttSliderTopicFormat, event.SliderID, fmt.Sprintf("%.2f", event.PercentValue))
+
+			case event, ok := <-switchEventsChannel:
+				if !ok {
+					mp.logger.Debug("Switch events channel closed, MQTT publisher exiting")
+					return
+				}
+				payload := "OFF"
+				if event.State {
+					payload = "ON"
+				}
+				mp.publish(mqttSwitchTopicFormat, event.SwitchID, payload)
+			}
+		}
+	}()
+}
+
+// Start connects to the configured broker, replacing any existing connection if the broker changed
+func (mp *MqttPublisher) Start() {
+	broker := strings.TrimSpace(mp.deej.config.ConnectionInfo.MQTT_Broker)
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if broker == mp.broker && mp.client != nil {
+		return
+	}
+
+	mp.disconnect()
+
+	if broker == "" {
+		return
+	}
+
+	hostname, _ := os.Hostname()
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(fmt.Sprintf("deej-%s", hostname)).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(mqttRetryDelay).
+		SetMaxReconnectInterval(mqttRetryDelay)
+
+	opts.SetOnConnectHandler(func(mqtt.Client) {
+		mp.logger.Infow("Connected to MQTT broker", "broker", broker)
+	})
+
+	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		mp.logger.Warnw("MQTT connection lost, reconnecting", "broker", broker, "error", err)
+	})
+
+	mp.client = mqtt.NewClient(opts)
+	mp.broker = broker
+
+	// with ConnectRetry set, this keeps retrying in the background so there's nothing to wait for here
+	mp.client.Connect()
+
+	mp.logger.Infow("Publishing state to MQTT", "broker", broker, "topic", mp.topic())
+}
+
+// Stop disconnects from the broker, if connected
+func (mp *MqttPublisher) Stop() {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	mp.disconnect()
+}
+
+// disconnect assumes mu is held
+func (mp *MqttPublisher) disconnect() {
+	if mp.client != nil {
+		mp.client.Disconnect(mqttDisconnectQuiesce)
+		mp.logger.Infow("Disconnected from MQTT broker", "broker", mp.broker)
+	}
+
+	mp.client = nil
+	mp.broker = ""
+}
+
+func (mp *MqttPublisher) topic() string {
+	topic := strings.Trim(strings.TrimSpace(mp.deej.config.ConnectionInfo.MQTT_Topic), "/")
+	if topic == "" {
+		topic = default_MQTT_Topic
+	}
+
+	return topic
+}
+
+func (mp *MqttPublisher) publish(topicFormat string, id int, payload string) {
+	mp.mu.Lock()
+	client := mp.client
+	mp.mu.Unlock()
+
+	if client == nil || !client.IsConnectionOpen() {
+		return
+	}
+
+	topic := fmt.Sprintf(topicFormat, mp.topic(), id)
+
+	// retained so that subscribers joining later still see the current state. we don't wait
+	// on the token - a slow broker must never hold up event dispatch
+	client.Publish(topic, 0, true, payload)
+
+	if mp.deej.Verbose() {
+		mp.logger.Debugw("Published MQTT message", "topic", topic, "payload", payload)
+	}
+}
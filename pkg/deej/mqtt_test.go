@@ -0,0 +1,54 @@
+package deej
+
+import (
+	"fmt"
+	"testing"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/zap"
+)
+
+// fakeMqttClient records publishes. The methods it doesn't override aren't used by MqttPublisher
+type fakeMqttClient struct {
+	mqtt.Client
+	open      bool
+	published []string
+}
+
+func (c *fakeMqttClient) IsConnectionOpen() bool { return c.open }
+
+func (c *fakeMqttClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	c.published = append(c.published, fmt.Sprintf("%s=%v retained=%t qos=%d", topic, payload, retained, qos))
+	return nil
+}
+
+func TestMqttPublish(t *testing.T) {
+	tests := []struct {
+		name        string
+		topic       string
+		open        bool
+		topicFormat string
+		id          int
+		payload     string
+		want        []string
+	}{
+		{"slider on default topic", "", true, mqttSliderTopicFormat, 2, "0.75", []string{default_MQTT_Topic + "/slider/2=0.75 retained=true qos=0"}},
+		{"switch on custom topic", " /home/desk/ ", true, mqttSwitchTopicFormat, 1, "ON", []string{"home/desk/switch/1=ON retained=true qos=0"}},
+		{"nothing while disconnected", "", false, mqttSwitchTopicFormat, 1, "OFF", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := newTestConfig(t, "")
+			cc.ConnectionInfo.MQTT_Topic = tt.topic
+			client := &fakeMqttClient{open: tt.open}
+			mp := &MqttPublisher{deej: &Deej{config: cc}, logger: zap.NewNop().Sugar(), client: client}
+
+			mp.publish(tt.topicFormat, tt.id, tt.payload)
+
+			if fmt.Sprint(client.published) != fmt.Sprint(tt.want) {
+				t.Errorf("published %v, want %v", client.published, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package deej
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const (
+	oscSliderAddressFormat = "/deej/slider/%d"
+	oscSwitchAddressFormat = "/deej/switch/%d"
+)
+
+// OscSender forwards slider and switch events as OSC messages over UDP to a configured target.
+// It is a no-op while OSC_Target is unset
+type OscSender struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	mu     sync.Mutex // Protects conn and target
+	conn   net.Conn
+	target string
+}
+
+// NewOscSender creates an OscSender instance
+func NewOscSender(deej *Deej, logger *zap.SugaredLogger) (*OscSender, error) {
+	logger = logger.Named("osc")
+
+	osc := &OscSender{
+		deej:   deej,
+		logger: logger,
+	}
+
+	logger.Debug("Created OSC sender instance")
+
+	return osc, nil
+}
+
+func (osc *OscSender) initialize() {
+	sliderEventsChannel := osc.deej.SubscribeToSliderMoveEvents()
+	switchEventsChannel := osc.deej.SubscribeToSwitchEvents()
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-sliderEventsChannel:
+				if !ok {
+					osc.logger.Debug("Slider events channel closed, OSC sender exiting")
+					osc.close()
+					return
+				}
+				osc.send(fmt.Sprintf(oscSliderAddressFormat, event.SliderID), event.PercentValue)
+
+			case event, ok := <-switchEventsChannel:
+				if !ok {
+					osc.logger.Debug("Switch events channel closed, OSC sender exiting")
+					osc.close()
+					return
+				}
+				state := int32(0)
+				if event.State {
+					state = 1
+				}
+				osc.send(fmt.Sprintf(oscSwitchAddressFormat, event.SwitchID), state)
+			}
+		}
+	}()
+}
+
+// send encodes and writes a single OSC message, (re)dialing the target if the config changed
+func (osc *OscSender) send(address string, arg interface{}) {
+	target := strings.TrimSpace(osc.deej.config.ConnectionInfo.OSC_Target)
+
+	osc.mu.Lock()
+	defer osc.mu.Unlock()
+
+	if target != osc.target {
+		if osc.conn != nil {
+			osc.conn.Close()
+			osc.conn = nil
+		}
+		osc.target = target
+
+		if target != "" {
+			conn, err := net.Dial("udp", target)
+			if err != nil {
+				osc.logger.Warnw("Failed to set up OSC target", "target", target, "error", err)
+				return
+			}
+			osc.conn = conn
+			osc.logger.Infow("Sending OSC messages", "target", target)
+		}
+	}
+
+	if osc.conn == nil {
+		return
+	}
+
+	packet, err := encodeOscMessage(address, arg)
+	if err != nil {
+		osc.logger.Warnw("Failed to encode OSC message", "address", address, "error", err)
+		return
+	}
+
+	if _, err := osc.conn.Write(packet); err != nil && osc.deej.Verbose() {
+		osc.logger.Debugw("Failed to send OSC message", "address", address, "error", err)
+	}
+}
+
+func (osc *OscSender) close() {
+	osc.mu.Lock()
+	defer osc.mu.Unlock()
+
+	if osc.conn != nil {
+		osc.conn.Close()
+		osc.conn = nil
+	}
+	osc.target = ""
+}
+
+// encodeOscMessage builds an OSC 1.0 message with a single float32 or int32 argument
+func encodeOscMessage(address string, arg interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writeOscString(buf, address)
+
+	switch v := arg.(type) {
+	case float32:
+		writeOscString(buf, ",f")
+		binary.Write(buf, binary.BigEndian, math.Float32bits(v))
+	case int32:
+		writeOscString(buf, ",i")
+		binary.Write(buf, binary.BigEndian, v)
+	default:
+		return nil, fmt.Errorf("unsupported OSC argument type: %T", arg)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeOscString writes a null-terminated string padded to a multiple of 4 bytes
+func writeOscString(buf *bytes.Buffer, s string) {
+	buf.WriteString(s)
+	padding := 4 - len(s)%4
+	buf.Write(make([]byte, padding))
+}
@@ -0,0 +1,91 @@
+package deej
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// decodeOscMessage reads back a single-argument message built by encodeOscMessage
+func decodeOscMessage(t *testing.T, packet []byte) (string, string, interface{}) {
+	t.Helper()
+
+	readString := func() string {
+		end := bytes.IndexByte(packet, 0)
+		if end < 0 {
+			t.Fatalf("unterminated OSC string in %q", packet)
+		}
+		s := string(packet[:end])
+		packet = packet[(end/4+1)*4:]
+		return s
+	}
+
+	address := readString()
+	typeTag := readString()
+	if len(packet) != 4 {
+		t.Fatalf("argument is %d bytes, want 4", len(packet))
+	}
+
+	bits := binary.BigEndian.Uint32(packet)
+	switch typeTag {
+	case ",f":
+		return address, typeTag, math.Float32frombits(bits)
+	case ",i":
+		return address, typeTag, int32(bits)
+	}
+
+	t.Fatalf("unexpected type tag %q", typeTag)
+	return "", "", nil
+}
+
+func TestOscSenderPacket(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+
+	cc := newTestConfig(t, "")
+	cc.ConnectionInfo.OSC_Target = listener.LocalAddr().String()
+	osc := &OscSender{deej: &Deej{config: cc}, logger: zap.NewNop().Sugar()}
+	defer osc.close()
+
+	tests := []struct {
+		address string
+		arg     interface{}
+		wantTag string
+	}{
+		{"/deej/slider/0", float32(0.5), ",f"},
+		{"/deej/slider/12", float32(1), ",f"},
+		{"/deej/switch/3", int32(1), ",i"},
+		{"/deej/switch/4", int32(0), ",i"},
+	}
+
+	for _, tt := range tests {
+		osc.send(tt.address, tt.arg)
+
+		buf := make([]byte, 512)
+		listener.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("%s: read packet: %v", tt.address, err)
+		}
+		if n%4 != 0 {
+			t.Errorf("%s: packet is %d bytes, not 4-byte aligned", tt.address, n)
+		}
+
+		address, tag, arg := decodeOscMessage(t, buf[:n])
+		if address != tt.address || tag != tt.wantTag || arg != tt.arg {
+			t.Errorf("got %s %s %v, want %s %s %v", address, tag, arg, tt.address, tt.wantTag, tt.arg)
+		}
+	}
+
+	if _, err := encodeOscMessage("/deej/slider/0", "loud"); err == nil {
+		t.Error("encodeOscMessage accepted a string argument")
+	}
+}
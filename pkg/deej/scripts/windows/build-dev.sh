@@ -15,13 +15,14 @@ BUILD=$(get_git_build_count)
 VERSION_TAG="v${MAJOR_MINOR}.${BUILD}"
 
 GIT_COMMIT=$(get_git_commit)
+BUILD_DATE=$(date -u +%Y-%m-%dT%H:%M:%SZ)
 
 BUILD_TYPE=dev
-echo "Embedding: gitCommit=$GIT_COMMIT, versionTag=$VERSION_TAG, buildType=$BUILD_TYPE"
+echo "Embedding: gitCommit=$GIT_COMMIT, versionTag=$VERSION_TAG, buildType=$BUILD_TYPE, buildDate=$BUILD_DATE"
 
 mkdir -p build
 
-CGO_ENABLED=0 GOOS=windows GOARCH=amd64 go build -o build/deej-dev.exe -ldflags "-X main.gitCommit=$GIT_COMMIT -X main.versionTag=$VERSION_TAG -X main.buildType=$BUILD_TYPE" ./pkg/deej/cmd
+CGO_ENABLED=0 GOOS=windows GOARCH=amd64 go build -o build/deej-dev.exe -ldflags "-X main.gitCommit=$GIT_COMMIT -X main.versionTag=$VERSION_TAG -X main.buildType=$BUILD_TYPE -X main.buildDate=$BUILD_DATE" ./pkg/deej/cmd
 if [ $? -eq 0 ]; then
     echo 'Done.'
     echo 'Output: build/deej-dev.exe'
@@ -117,11 +117,13 @@ function Invoke-Build {
     }
     
     $fullOutput = Join-Path $buildDir $OutputFile
+    $buildDate = (Get-Date).ToUniversalTime().ToString("yyyy-MM-ddTHH:mm:ssZ")
     
     $baseFlags = @(
         "-X main.gitCommit=$GitCommit",
         "-X main.versionTag=$VersionTag",
-        "-X main.buildType=$BuildType"
+        "-X main.buildType=$BuildType",
+        "-X main.buildDate=$buildDate"
     )
     
     $ldFlags = $baseFlags -join " "
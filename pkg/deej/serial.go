@@ -2,12 +2,15 @@ package deej
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jacobsa/go-serial/serial"
@@ -27,6 +30,17 @@ type SerialIO struct {
 	connected   bool
 	connOptions serial.OpenOptions
 	conn        io.ReadWriteCloser
+
+	// false once the device missed a keep-alive ping, until the next connect
+	healthy atomic.Bool
+
+	// 0 for the regular serial transport, n for the n-th extra board in SERIAL_Port
+	portIndex int
+	retired   atomic.Bool // extra boards only: set once the board is removed, ends its retry loop
+
+	// malformed frame log rate limiting, only touched from the run loop
+	malformedLines   int
+	lastMalformedLog time.Time
 }
 
 const (
@@ -36,6 +50,16 @@ const (
 	// InterCharacterTimeout for serial connection (milliseconds)
 	// This is the timeout between characters before a read operation returns
 	serialInterCharacterTimeout = 50
+
+	// Minimum time between malformed frame log messages
+	serialMalformedLogInterval = 10 * time.Second
+
+	// How long to listen at each candidate baud rate when SERIAL_BaudRate lists several
+	serialBaudProbeDuration = 1500 * time.Millisecond
+
+	// How often to look for an unplugged serial device to come back. This only checks that the port
+	// exists, opening it is left for when it does
+	serialUnpluggedPollInterval = time.Second
 )
 
 var ansiRegexp = regexp.MustCompile(`\x1b\[[0-9;]*m`)
@@ -73,6 +97,26 @@ func (sio *SerialIO) IsConnected() bool {
 	return sio.connected
 }
 
+// Write sends a line to the device, e.g. a command for an indicator LED. A newline is added if missing
+func (sio *SerialIO) Write(data []byte) error {
+	sio.mu.Lock()
+	defer sio.mu.Unlock()
+
+	if !sio.connected || sio.conn == nil {
+		return errors.New("serial port not connected")
+	}
+
+	if len(data) == 0 || data[len(data)-1] != '\n' {
+		data = append(append([]byte{}, data...), '\n')
+	}
+
+	if _, err := sio.conn.Write(data); err != nil {
+		return fmt.Errorf("write to serial port: %w", err)
+	}
+
+	return nil
+}
+
 // Start attempts to connect to our arduino chip
 func (sio *SerialIO) Start() error {
 	sio.mu.Lock()
@@ -85,8 +129,13 @@ func (sio *SerialIO) Start() error {
 	if err := sio.connect(sio.logger); err != nil {
 		return fmt.Errorf("serial initial connect error: %w", err)
 	}
+	sio.deej.notifyConnectionRestored(sio.transportName())
+	sio.deej.runOnConnectAction(sio.transportName())
 
 	go func() {
+		unplugged := false // the device was physically removed, wait for it to reappear before reconnecting
+		announcedUnplug := false
+
 		for {
 			// Only run if we have a valid connection
 			sio.mu.Lock()
@@ -96,30 +145,48 @@ func (sio *SerialIO) Start() error {
 
 			if connected && conn != nil {
 				err := sio.run(sio.logger)
-				if err != nil {
+				if err != nil && sio.deviceUnplugged(err) {
+					unplugged = true
+					announcedUnplug = true
+					sio.logger.Warnw("Serial device unplugged, waiting for it to come back",
+						"port", sio.configuredPort(), "error", err.Error(), "reason", connReasonUnplugged)
+					sio.deej.notifier.Notify("Device unplugged",
+						fmt.Sprintf("%s was disconnected. deej will reconnect once it's plugged back in.", sio.configuredPort()))
+				} else if err != nil {
 					sio.logger.Warnw("Serial connection lost", "error", err.Error())
+					sio.deej.notifyConnectionLost(sio.transportName())
 				}
 			}
 
 			sio.close(sio.logger)
 
-			select {
-			case <-sio.stopChannel:
-				return
-			case <-time.After(serialRetryDelay):
+			if unplugged {
+				if !sio.waitForPort() {
+					return
+				}
+				unplugged = false
+				sio.logger.Infow("Serial device is back, reconnecting", "port", sio.configuredPort())
+			} else {
+				select {
+				case <-sio.stopChannel:
+					return
+				case <-time.After(serialRetryDelay):
+				}
 			}
 
 			// Check if Serial is still the active interface before checking config
 			// If we've switched to another interface, just exit silently
-			sio.deej.ioMutex.Lock()
-			isActive := sio.deej.io == sio
-			sio.deej.ioMutex.Unlock()
-			if !isActive {
+			if !sio.stillActive() {
 				sio.logger.Debug("Serial is no longer the active interface, exiting retry loop")
 				return
 			}
 
-			if sio.deej.config.ConnectionInfo.SERIAL_Port == "" || sio.deej.config.ConnectionInfo.SERIAL_BaudRate == 0 {
+			if sio.portIndex > 0 && sio.configuredPort() == "" {
+				sio.logger.Info("Extra serial board removed from config, exiting retry loop")
+				return
+			}
+
+			if sio.portIndex == 0 && (sio.deej.config.ConnectionInfo.SERIAL_Port == "" || sio.deej.config.ConnectionInfo.SERIAL_BaudRate == 0) {
 				sio.logger.Info("Serial port or baud rate unset in config. Deej will be unable to reconnect. Shutting down.")
 				sio.deej.notifier.Notify("Serial port or baud rate unset in config", "Shutting down.")
 				sio.deej.signalStop()
@@ -128,14 +195,64 @@ func (sio *SerialIO) Start() error {
 
 			if err := sio.connect(sio.logger); err != nil {
 				sio.logger.Warnw("Serial reconnect failed", "error", err.Error())
+				unplugged = sio.deviceUnplugged(err)
 				continue
 			}
+			sio.deej.notifyConnectionRestored(sio.transportName())
+			sio.deej.runOnConnectAction(sio.transportName())
+
+			if announcedUnplug {
+				announcedUnplug = false
+				sio.deej.notifier.Notify("Device reconnected", fmt.Sprintf("%s is plugged back in.", sio.configuredPort()))
+			}
 		}
 	}()
 
 	return nil
 }
 
+// stillActive reports whether this instance should keep reconnecting: it's the active interface, or an
+// extra board that hasn't been removed while serial is active
+func (sio *SerialIO) stillActive() bool {
+	sio.deej.ioMutex.Lock()
+	defer sio.deej.ioMutex.Unlock()
+
+	return sio.deej.io == sio || (sio.portIndex > 0 && sio.deej.io == sio.deej.serial && !sio.retired.Load())
+}
+
+// deviceUnplugged tells a physically removed device apart from a transient read or open error
+func (sio *SerialIO) deviceUnplugged(err error) bool {
+	if serialDeviceRemoved(err) {
+		return true
+	}
+
+	port := sio.configuredPort()
+	return port != "" && !serialPortPresent(port)
+}
+
+// waitForPort polls until the configured port exists again. It returns false if deej stopped
+// or moved on to another interface in the meantime
+func (sio *SerialIO) waitForPort() bool {
+	ticker := time.NewTicker(serialUnpluggedPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sio.stopChannel:
+			return false
+		case <-ticker.C:
+		}
+
+		if !sio.stillActive() {
+			return false
+		}
+
+		if port := sio.configuredPort(); port != "" && serialPortPresent(port) {
+			return true
+		}
+	}
+}
+
 func (sio *SerialIO) connect(logger *zap.SugaredLogger) error {
 	sio.mu.Lock()
 	if sio.connected {
@@ -143,45 +260,157 @@ func (sio *SerialIO) connect(logger *zap.SugaredLogger) error {
 		return errors.New("already connected")
 	}
 
-	sio.connOptions = serial.OpenOptions{
-		PortName:              sio.deej.config.ConnectionInfo.SERIAL_Port,
-		BaudRate:              uint(sio.deej.config.ConnectionInfo.SERIAL_BaudRate),
+	portName := sio.configuredPort()
+	baudRates := sio.candidateBaudRates()
+	sio.mu.Unlock()
+
+	if len(baudRates) == 0 {
+		return errors.New("no serial baud rate configured")
+	}
+
+	var conn io.ReadWriteCloser
+	var options serial.OpenOptions
+	var err error
+
+	if len(baudRates) == 1 {
+		options = serialOpenOptions(portName, baudRates[0])
+		logger.Debugw("Attempting serial connection", "port", portName, "baud", options.BaudRate)
+		if conn, err = openSerialPort(logger, options); err != nil {
+			return err
+		}
+	} else if conn, options, err = sio.negotiateBaudRate(logger, portName, baudRates); err != nil {
+		return err
+	}
+
+	sio.mu.Lock()
+	sio.connOptions = options
+	sio.baudRate = options.BaudRate
+	sio.conn = conn
+	sio.connected = true
+	sio.mu.Unlock()
+
+	logger.Infow("Connected to serial port", "port", portName, "baud", options.BaudRate)
+
+	return nil
+}
+
+// candidateBaudRates returns the configured baud rates, with the last negotiated one first so that
+// reconnects don't have to probe again. Assumes mu is held
+func (sio *SerialIO) candidateBaudRates() []int {
+	configured := sio.deej.config.ConnectionInfo.SERIAL_BaudRates
+	baudRates := make([]int, 0, len(configured))
+
+	if sio.baudRate != 0 && slices.Contains(configured, int(sio.baudRate)) {
+		baudRates = append(baudRates, int(sio.baudRate))
+	}
+
+	for _, baud := range configured {
+		if !slices.Contains(baudRates, baud) {
+			baudRates = append(baudRates, baud)
+		}
+	}
+
+	return baudRates
+}
+
+// negotiateBaudRate opens the port at each candidate baud rate in turn and commits to the first one
+// that yields valid frames. A device that stays silent can't be told apart, so if none of them do
+// we settle for the first candidate
+func (sio *SerialIO) negotiateBaudRate(logger *zap.SugaredLogger, portName string, baudRates []int) (io.ReadWriteCloser, serial.OpenOptions, error) {
+	for _, baud := range baudRates {
+		options := serialOpenOptions(portName, baud)
+		logger.Debugw("Probing serial baud rate", "port", portName, "baud", baud)
+
+		conn, err := openSerialPort(logger, options)
+		if err != nil {
+			// a missing or busy port won't get better at another baud rate
+			return nil, options, err
+		}
+
+		if isValidSerialFraming(probeSerial(conn, serialBaudProbeDuration)) {
+			logger.Infow("Negotiated serial baud rate", "port", portName, "baud", baud)
+			return conn, options, nil
+		}
+
+		if err := conn.Close(); err != nil {
+			logger.Debugw("Failed to close serial port after probing", "port", portName, "baud", baud, "error", err)
+		}
+	}
+
+	logger.Warnw("No baud rate produced valid frames, using the first one", "port", portName, "baudRates", baudRates)
+
+	options := serialOpenOptions(portName, baudRates[0])
+	conn, err := openSerialPort(logger, options)
+
+	return conn, options, err
+}
+
+func serialOpenOptions(portName string, baud int) serial.OpenOptions {
+	return serial.OpenOptions{
+		PortName:              portName,
+		BaudRate:              uint(baud),
 		DataBits:              8,
 		StopBits:              1,
 		MinimumReadSize:       0,
 		InterCharacterTimeout: serialInterCharacterTimeout,
 	}
-	portName := sio.connOptions.PortName
-	sio.mu.Unlock()
+}
 
-	logger.Debugw("Attempting serial connection", "port", portName, "baud", sio.connOptions.BaudRate)
+func openSerialPort(logger *zap.SugaredLogger, options serial.OpenOptions) (io.ReadWriteCloser, error) {
+	portName := options.PortName
 
-	conn, err := serial.Open(sio.connOptions)
+	conn, err := serial.Open(options)
 	if err != nil {
 		// Provide more detailed error messages for common issues
 		errMsg := err.Error()
 		if strings.Contains(errMsg, "access is denied") || strings.Contains(errMsg, "permission denied") {
 			logger.Errorw("Serial port access denied - port may be in use by another application",
 				"port", portName, "error", err)
-			return fmt.Errorf("serial port %s is busy or access denied: %w", portName, err)
+			return nil, fmt.Errorf("serial port %s is busy or access denied: %w", portName, err)
 		}
 		if strings.Contains(errMsg, "no such file") || strings.Contains(errMsg, "cannot find") {
 			logger.Errorw("Serial port does not exist - check port name in configuration",
 				"port", portName, "error", err)
-			return fmt.Errorf("serial port %s does not exist: %w", portName, err)
+			return nil, fmt.Errorf("serial port %s does not exist: %w", portName, err)
 		}
 		logger.Errorw("Failed to open serial port", "port", portName, "error", err)
-		return fmt.Errorf("open serial port %s: %w", portName, err)
+		return nil, fmt.Errorf("open serial port %s: %w", portName, err)
 	}
 
-	sio.mu.Lock()
-	sio.conn = conn
-	sio.connected = true
-	sio.mu.Unlock()
+	return conn, nil
+}
 
-	logger.Infow("Connected to serial port", "port", portName)
+// probeSerial reads whatever arrives on conn for the given duration
+func probeSerial(conn io.Reader, duration time.Duration) []byte {
+	var data []byte
+	buf := make([]byte, 256)
 
-	return nil
+	deadline := time.Now().Add(duration)
+	for time.Now().Before(deadline) {
+		n, err := conn.Read(buf)
+		data = append(data, buf[:n]...)
+		if err != nil && err != io.EOF {
+			break
+		}
+	}
+
+	return data
+}
+
+// isValidSerialFraming reports whether data contains at least one line in a format we understand -
+// either pure JSON or our JSON log tag. At the wrong baud rate the stream is garbage and won't match
+func isValidSerialFraming(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(stripANSI(line))
+		if len(trimmed) > 1 && trimmed[0] == '{' && trimmed[len(trimmed)-1] == '}' && json.Valid([]byte(trimmed)) {
+			return true
+		}
+		if jsonLogRegexp.MatchString(line) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (sio *SerialIO) run(logger *zap.SugaredLogger) error {
@@ -189,7 +418,11 @@ func (sio *SerialIO) run(logger *zap.SugaredLogger) error {
 		return errors.New("cannot run: connection is nil")
 	}
 	connReader := bufio.NewReader(sio.conn)
-	lineChannel := sio.readLine(logger, connReader)
+	lineChannel, readErr := sio.readLine(logger, connReader)
+
+	sio.healthy.Store(true)
+	keepAlive := newSerialKeepAlive(sio.deej.config.SerialPingInterval, sio.deej.config.SerialPingTimeout)
+	defer keepAlive.stop()
 
 	for {
 		select {
@@ -198,13 +431,39 @@ func (sio *SerialIO) run(logger *zap.SugaredLogger) error {
 
 		case line, ok := <-lineChannel:
 			if !ok {
-				return errors.New("serial connection lost")
+				select {
+				case err := <-readErr:
+					return fmt.Errorf("serial connection lost: %w", err)
+				default:
+					return errors.New("serial connection lost")
+				}
 			}
+			keepAlive.lineReceived(time.Now())
 			sio.handleLine(logger, line)
+
+		case now := <-keepAlive.tick():
+			if !keepAlive.shouldPing(now) {
+				continue
+			}
+			if err := sio.Write([]byte(sio.deej.config.SerialPingCommand)); err != nil {
+				return fmt.Errorf("send keep-alive ping: %w", err)
+			}
+			keepAlive.pingSent()
+
+		case <-keepAlive.deadline():
+			sio.healthy.Store(false)
+			logger.Warnw("Device didn't answer keep-alive ping, marking serial link unhealthy",
+				"timeout", sio.deej.config.SerialPingTimeout, "reason", connReasonTimeout)
+			return fmt.Errorf("no reply to keep-alive ping within %s", sio.deej.config.SerialPingTimeout)
 		}
 	}
 }
 
+// Healthy reports whether the serial link is connected and hasn't missed a keep-alive ping
+func (sio *SerialIO) Healthy() bool {
+	return sio.IsConnected() && sio.healthy.Load()
+}
+
 // Stop signals us to shut down our serial connection, if one is active
 func (sio *SerialIO) Stop() {
 	sio.mu.Lock()
@@ -281,14 +540,19 @@ func (sio *SerialIO) close(logger *zap.SugaredLogger) {
 	}
 }
 
-func (sio *SerialIO) readLine(logger *zap.SugaredLogger, reader *bufio.Reader) chan string {
+// readLine delivers lines read from the device until reading fails. The error that ended it, if any,
+// is sent on the second channel before the line channel is closed
+func (sio *SerialIO) readLine(logger *zap.SugaredLogger, reader *bufio.Reader) (chan string, chan error) {
 	ch := make(chan string)
+	errCh := make(chan error, 1)
 
 	go func() {
 		defer close(ch) // Ensure channel is closed when goroutine exits
 		for {
 			line, err := reader.ReadString('\n')
 			if err != nil {
+				errCh <- err
+
 				// Log read errors at info level for connection issues
 				if err != io.EOF {
 					logger.Infow("Serial read error, connection may be lost", "error", err)
@@ -313,7 +577,7 @@ func (sio *SerialIO) readLine(logger *zap.SugaredLogger, reader *bufio.Reader) c
 		}
 	}()
 
-	return ch
+	return ch, errCh
 }
 
 func (sio *SerialIO) handleLine(logger *zap.SugaredLogger, line string) {
@@ -326,10 +590,14 @@ func (sio *SerialIO) handleLine(logger *zap.SugaredLogger, line string) {
 	// Check if line is a pure JSON string (starts with { and ends with })
 	if len(trimmed) > 0 && trimmed[0] == '{' && trimmed[len(trimmed)-1] == '}' {
 		// Try to parse as pure JSON
+		if !json.Valid([]byte(trimmed)) {
+			sio.logMalformedLine(logger, trimmed)
+			return
+		}
 		if sio.deej.Verbose() {
 			logger.Debugw("Pure JSON line detected", "json", trimmed)
 		}
-		sio.deej.handleStateEvent(logger, []byte(trimmed))
+		sio.dispatch(logger, []byte(trimmed))
 		return
 	}
 
@@ -341,10 +609,29 @@ func (sio *SerialIO) handleLine(logger *zap.SugaredLogger, line string) {
 
 	jsonPayload := m[1]
 
+	if !json.Valid([]byte(jsonPayload)) {
+		sio.logMalformedLine(logger, jsonPayload)
+		return
+	}
+
 	if sio.deej.Verbose() {
 		logger.Debugw("JSON payload received from log format", "json", jsonPayload)
 	}
 
 	// Use the common handleStateEvent from deej.go
-	sio.deej.handleStateEvent(logger, []byte(jsonPayload))
+	sio.dispatch(logger, []byte(jsonPayload))
+}
+
+// logMalformedLine reports frames that look like ours but don't parse. A noisy connection can produce
+// lots of them, so they're summarized at most once per serialMalformedLogInterval
+func (sio *SerialIO) logMalformedLine(logger *zap.SugaredLogger, line string) {
+	sio.malformedLines++
+
+	if time.Since(sio.lastMalformedLog) < serialMalformedLogInterval {
+		return
+	}
+
+	logger.Debugw("Dropped malformed serial frames", "count", sio.malformedLines, "last", line)
+	sio.malformedLines = 0
+	sio.lastMalformedLog = time.Now()
 }
@@ -0,0 +1,168 @@
+package deej
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Extra serial boards: SERIAL_Port may list several ports. The first one is the regular serial transport,
+// the others are started and stopped along with it and feed the same event pipeline. Their slider, switch,
+// encoder and button ids are shifted by serial_id_offset per board (the second board's pot 0 becomes
+// slider 100, etc.), which slider_remap/switch_remap can then map anywhere. Write-back only reaches the first board
+
+// newExtraSerialIO creates the SerialIO for the portIndex-th extra board. Unlike NewSerialIO it doesn't
+// subscribe to config changes, since extra boards come and go with them
+func newExtraSerialIO(deej *Deej, logger *zap.SugaredLogger, portIndex int) *SerialIO {
+	return &SerialIO{
+		deej:        deej,
+		logger:      logger.Named(fmt.Sprintf("serial%d", portIndex)),
+		stopChannel: make(chan bool),
+		portIndex:   portIndex,
+	}
+}
+
+// configuredPort returns this instance's port from the config, or "" if it's no longer listed
+func (sio *SerialIO) configuredPort() string {
+	if sio.portIndex == 0 {
+		return sio.deej.config.ConnectionInfo.SERIAL_Port
+	}
+
+	extraPorts := sio.deej.config.ConnectionInfo.SERIAL_ExtraPorts
+	if sio.portIndex > len(extraPorts) {
+		return ""
+	}
+
+	return extraPorts[sio.portIndex-1]
+}
+
+func (sio *SerialIO) transportName() string {
+	if sio.portIndex == 0 {
+		return "serial"
+	}
+
+	return fmt.Sprintf("serial (%s)", sio.configuredPort())
+}
+
+// dispatch hands a state event to deej, shifting its ids first if this is an extra board
+func (sio *SerialIO) dispatch(logger *zap.SugaredLogger, payload []byte) {
+	if sio.portIndex > 0 {
+		payload = sio.deej.offsetStateEventIDs(payload, sio.portIndex*sio.deej.config.ConnectionInfo.SerialIDOffset)
+	}
+
+	sio.deej.handleStateEvent(logger, payload)
+}
+
+// offsetStateEventIDs adds offset to the slider/switch/encoder number in a state event's id, or to the
+// button number in a button event's value. Events it doesn't understand are returned unchanged
+func (d *Deej) offsetStateEventIDs(payload []byte, offset int) []byte {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		return payload
+	}
+
+	id, _ := raw["id"].(string)
+
+	if id == btnStateID {
+		value, _ := raw["value"].(string)
+		parts := strings.Split(value, "_")
+		buttonID, err := strconv.Atoi(parts[0])
+		if len(parts) != 2 || err != nil {
+			return payload
+		}
+		raw["value"] = fmt.Sprintf("%d_%s", buttonID+offset, parts[1])
+	} else {
+		shifted := false
+		for _, h := range d.stateHandlers {
+			loc := h.matcher().FindStringSubmatchIndex(id)
+			if len(loc) < 4 || loc[2] < 0 {
+				continue
+			}
+
+			idx, err := strconv.Atoi(id[loc[2]:loc[3]])
+			if err != nil {
+				return payload
+			}
+
+			raw["id"] = id[:loc[2]] + strconv.Itoa(idx+offset) + id[loc[3]:]
+			shifted = true
+			break
+		}
+
+		if !shifted {
+			return payload
+		}
+	}
+
+	shiftedPayload, err := json.Marshal(raw)
+	if err != nil {
+		return payload
+	}
+
+	return shiftedPayload
+}
+
+// startExtraSerials connects to the extra boards listed in SERIAL_Port. A board that fails to connect
+// now is left out until the next config change. Assumes ioMutex is held
+func (d *Deej) startExtraSerials() {
+	d.extraSerialsMutex.Lock()
+	defer d.extraSerialsMutex.Unlock()
+
+	ports := d.config.ConnectionInfo.SERIAL_ExtraPorts
+	d.extraSerialPorts = slices.Clone(ports)
+
+	for portIdx, port := range ports {
+		sio := newExtraSerialIO(d, d.logger, portIdx+1)
+		if err := sio.Start(); err != nil {
+			d.logger.Warnw("Failed to connect to extra serial board", "port", port, "error", err,
+				"reason", connectionErrorReason(err))
+			d.notifier.Notify(fmt.Sprintf("Can't connect to %s!", port),
+				"This extra serial board is listed in SERIAL_Port but couldn't be opened.")
+			continue
+		}
+
+		d.extraSerials = append(d.extraSerials, sio)
+	}
+}
+
+// stopExtraSerials disconnects all extra boards and waits for them to close
+func (d *Deej) stopExtraSerials() {
+	d.extraSerialsMutex.Lock()
+	extraSerials := d.extraSerials
+	d.extraSerials = nil
+	d.extraSerialPorts = nil
+	d.extraSerialsMutex.Unlock()
+
+	for _, sio := range extraSerials {
+		sio.retired.Store(true)
+		sio.Stop()
+		if !sio.WaitForStop(interfaceStopTimeout) {
+			sio.logger.Warn("Extra serial board did not stop within timeout, proceeding anyway")
+		}
+	}
+}
+
+// syncExtraSerials restarts the extra boards if their list changed in the config
+func (d *Deej) syncExtraSerials() {
+	d.extraSerialsMutex.Lock()
+	unchanged := slices.Equal(d.extraSerialPorts, d.config.ConnectionInfo.SERIAL_ExtraPorts)
+	d.extraSerialsMutex.Unlock()
+
+	if unchanged {
+		return
+	}
+
+	d.logger.Infow("Extra serial boards changed, reconnecting them", "ports", d.config.ConnectionInfo.SERIAL_ExtraPorts)
+	d.stopExtraSerials()
+
+	d.ioMutex.Lock()
+	defer d.ioMutex.Unlock()
+
+	if d.io == d.serial {
+		d.startExtraSerials()
+	}
+}
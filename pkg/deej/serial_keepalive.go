@@ -0,0 +1,71 @@
+package deej
+
+import "time"
+
+// serialKeepAlive is the ping/timeout state machine behind serial_ping_interval. Any line from the
+// device counts as a reply, so a chatty device is never pinged and a quiet one only has to answer
+// with something. It's only touched from the serial run loop
+type serialKeepAlive struct {
+	interval time.Duration
+	timeout  time.Duration
+
+	ticker   *time.Ticker
+	lastLine time.Time
+	pending  *time.Timer // running while a ping waits for its reply
+}
+
+func newSerialKeepAlive(interval time.Duration, timeout time.Duration) *serialKeepAlive {
+	ka := &serialKeepAlive{
+		interval: interval,
+		timeout:  timeout,
+		lastLine: time.Now(),
+	}
+
+	if interval > 0 {
+		ka.ticker = time.NewTicker(interval)
+	}
+
+	return ka
+}
+
+// tick fires every ping interval; it never fires when the keep-alive is disabled
+func (ka *serialKeepAlive) tick() <-chan time.Time {
+	if ka.ticker == nil {
+		return nil
+	}
+	return ka.ticker.C
+}
+
+// deadline fires when the pending ping went unanswered for too long
+func (ka *serialKeepAlive) deadline() <-chan time.Time {
+	if ka.pending == nil {
+		return nil
+	}
+	return ka.pending.C
+}
+
+// shouldPing reports whether the device has been quiet for a full interval with no ping in flight
+func (ka *serialKeepAlive) shouldPing(now time.Time) bool {
+	return ka.pending == nil && now.Sub(ka.lastLine) >= ka.interval
+}
+
+func (ka *serialKeepAlive) pingSent() {
+	ka.pending = time.NewTimer(ka.timeout)
+}
+
+func (ka *serialKeepAlive) lineReceived(now time.Time) {
+	ka.lastLine = now
+	if ka.pending != nil {
+		ka.pending.Stop()
+		ka.pending = nil
+	}
+}
+
+func (ka *serialKeepAlive) stop() {
+	if ka.ticker != nil {
+		ka.ticker.Stop()
+	}
+	if ka.pending != nil {
+		ka.pending.Stop()
+	}
+}
@@ -0,0 +1,76 @@
+package deej
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSerialKeepAlive(t *testing.T) {
+	const (
+		interval = time.Second
+		timeout  = 20 * time.Millisecond
+	)
+
+	// each step happens at an offset from the keep-alive's creation
+	type step struct {
+		at   time.Duration
+		op   string // "line" or "ping" (sent if due)
+		want bool   // for "ping": whether a ping was due
+	}
+
+	tests := []struct {
+		name        string
+		steps       []step
+		wantPending bool // a ping is still waiting for its reply at the end
+	}{
+		{"chatty device is never pinged", []step{{500 * time.Millisecond, "line", false}, {interval, "ping", false}}, false},
+		{"quiet device is pinged", []step{{interval, "ping", true}}, true},
+		{"only one ping in flight", []step{{interval, "ping", true}, {2 * interval, "ping", false}}, true},
+		{"reply clears the ping", []step{{interval, "ping", true}, {interval + 10*time.Millisecond, "line", false}}, false},
+		{"quiet again after a reply", []step{{interval, "ping", true}, {interval, "line", false}, {2 * interval, "ping", true}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ka := newSerialKeepAlive(interval, timeout)
+			defer ka.stop()
+			start := ka.lastLine
+
+			for i, s := range tt.steps {
+				now := start.Add(s.at)
+				switch s.op {
+				case "line":
+					ka.lineReceived(now)
+				case "ping":
+					due := ka.shouldPing(now)
+					if due != s.want {
+						t.Fatalf("step %d: ping due = %v, want %v", i, due, s.want)
+					}
+					if due {
+						ka.pingSent()
+					}
+				}
+			}
+
+			if pending := ka.deadline() != nil; pending != tt.wantPending {
+				t.Fatalf("ping pending = %v, want %v", pending, tt.wantPending)
+			}
+			if !tt.wantPending {
+				return
+			}
+
+			select {
+			case <-ka.deadline():
+			case <-time.After(time.Second):
+				t.Fatal("unanswered ping never timed out")
+			}
+		})
+	}
+
+	// without an interval nothing is ever pinged
+	disabled := newSerialKeepAlive(0, timeout)
+	defer disabled.stop()
+	if disabled.tick() != nil || disabled.deadline() != nil {
+		t.Error("disabled keep-alive has live channels")
+	}
+}
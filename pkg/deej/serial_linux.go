@@ -0,0 +1,21 @@
+//go:build linux
+// +build linux
+
+package deej
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// serialDeviceRemoved reports whether a serial read/open error means the adapter was physically removed
+func serialDeviceRemoved(err error) bool {
+	return errors.Is(err, syscall.ENXIO) || errors.Is(err, syscall.ENODEV)
+}
+
+// serialPortPresent reports whether the serial device node currently exists
+func serialPortPresent(portName string) bool {
+	_, err := os.Stat(portName)
+	return err == nil
+}
@@ -0,0 +1,70 @@
+//go:build linux
+// +build linux
+
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestSerialDeviceRemoved(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantRemoved bool
+		wantReason  string
+	}{
+		{"ENXIO on read", &os.PathError{Op: "read", Path: "/dev/ttyUSB0", Err: syscall.ENXIO}, true, connReasonUnplugged},
+		{"wrapped ENODEV", fmt.Errorf("serial read: %w", syscall.ENODEV), true, connReasonUnplugged},
+		{"EIO is transient", &os.PathError{Op: "read", Path: "/dev/ttyUSB0", Err: syscall.EIO}, false, connReasonIOError},
+		{"busy port", &os.PathError{Op: "open", Path: "/dev/ttyUSB0", Err: syscall.EBUSY}, false, connReasonIOError},
+		{"access denied", &os.PathError{Op: "open", Path: "/dev/ttyUSB0", Err: syscall.EACCES}, false, connReasonBusy},
+		{"missing node", &os.PathError{Op: "open", Path: "/dev/ttyUSB0", Err: syscall.ENOENT}, false, connReasonNotFound},
+		{"plain error", errors.New("unexpected EOF"), false, connReasonIOError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serialDeviceRemoved(tt.err); got != tt.wantRemoved {
+				t.Errorf("serialDeviceRemoved() = %v, want %v", got, tt.wantRemoved)
+			}
+			if got := connectionErrorReason(tt.err); got != tt.wantReason {
+				t.Errorf("connectionErrorReason() = %q, want %q", got, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestSerialDeviceUnplugged(t *testing.T) {
+	present := filepath.Join(t.TempDir(), "ttyUSB0")
+	writeTestFile(t, present, "")
+	missing := filepath.Join(t.TempDir(), "ttyUSB1")
+
+	tests := []struct {
+		name string
+		port string
+		err  error
+		want bool
+	}{
+		{"read error, port still there", present, syscall.EIO, false},
+		{"read error, port gone", missing, syscall.EIO, true},
+		{"removal errno, port still there", present, syscall.ENXIO, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, "")
+			d.config.ConnectionInfo.SERIAL_Port = tt.port
+			sio := &SerialIO{deej: d}
+
+			if got := sio.deviceUnplugged(tt.err); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
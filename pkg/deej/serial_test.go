@@ -0,0 +1,170 @@
+package deej
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestIsValidSerialFraming(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"json frame", `{"id":"sensor-pot0","value":42}` + "\n", true},
+		{"json frame after garbage", "\x8f\xfe\x03junk\n{\"id\":\"binary_sensor-sw1\",\"state\":\"ON\"}\r\n", true},
+		{"esphome json log line", "\x1b[0;36m[D][json:042]: {\"id\":\"sensor-pot1\",\"value\":7}\x1b[0m\n", true},
+		{"json log tag without json", "[D][json:042]: hello\n", false},
+		{"wrong baud garbage", "\xf0\x0f\xaa\x55{\xff\xfe}\x00\n", false},
+		{"unbalanced braces", `{"id":"sensor-pot0","value":4` + "\n", false},
+		{"plain log line", "[I][app:100]: Running through setup()\n", false},
+		{"nothing", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isValidSerialFraming([]byte(tt.data)); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSerialBaudRatesFromConfig(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"115200", "[115200]"},
+		{"[115200, 9600]", "[115200 9600]"},
+		{"[115200, fast, -1, 0, 57600]", "[115200 57600]"},
+		{"0", "[]"},
+	}
+
+	for _, tt := range tests {
+		cc := newTestConfig(t, configKey_SERIAL_BaudRate+": "+tt.value+"\n")
+
+		if got := fmt.Sprint(cc.serialBaudRatesFromConfig()); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCandidateBaudRates(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured []int
+		negotiated uint
+		want       string
+	}{
+		{"configured order", []int{115200, 9600}, 0, "[115200 9600]"},
+		{"last negotiated first", []int{115200, 9600, 57600}, 9600, "[9600 115200 57600]"},
+		{"negotiated rate no longer configured", []int{115200}, 9600, "[115200]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, "")
+			d.config.ConnectionInfo.SERIAL_BaudRates = tt.configured
+			sio := &SerialIO{deej: d, baudRate: tt.negotiated}
+
+			if got := fmt.Sprint(sio.candidateBaudRates()); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleLineSkipsGarbage(t *testing.T) {
+	d := newTestDeej(t, "")
+	events := d.SubscribeToSliderMoveEventsBuffered(8)
+	logs := observeLogs(d)
+	sio := &SerialIO{deej: d}
+
+	lines := []string{
+		`{"id":"sensor-pot0","value":10}`,
+		"\x00\xff\xfe",
+		`{"id":"sensor-pot0","val`,
+		`{"id":"sensor-pot0","value":}`,
+		`[I][json:042]: {"id":"sensor-pot1","value":20}`,
+		`[I][json:042]: {"id":"sensor-pot1" "value":}`,
+		`{{{}`,
+		"\x1b[0;32m[I][json:042]: {\"id\":\"sensor-pot2\",\"value\":30}\x1b[0m",
+		`[D][sensor:093]: 'pot0': Sending state 10.0`,
+	}
+	for _, line := range lines {
+		sio.handleLine(d.logger, line)
+	}
+
+	moves := receiveSliderMoves(events)
+	got := []int{}
+	for _, move := range moves {
+		got = append(got, move.SliderID)
+	}
+	if fmt.Sprint(got) != "[0 1 2]" {
+		t.Errorf("got moves for sliders %v, want [0 1 2]", got)
+	}
+
+	// three malformed frames, but the log is only written once per interval
+	if dropped := logs.FilterMessage("Dropped malformed serial frames").Len(); dropped != 1 {
+		t.Errorf("got %d malformed frame logs, want 1", dropped)
+	}
+	if sio.malformedLines != 2 {
+		t.Errorf("got %d malformed frames waiting to be reported, want 2", sio.malformedLines)
+	}
+}
+
+func TestOffsetStateEventIDs(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    string
+	}{
+		{`{"id":"sensor-pot2","value":42}`, `{"id":"sensor-pot102","value":42}`},
+		{`{"id":"binary_sensor-sw0","state":"ON"}`, `{"id":"binary_sensor-sw100","state":"ON"}`},
+		{`{"id":"sensor-enc1","value":3}`, `{"id":"sensor-enc101","value":3}`},
+		{`{"id":"text_sensor-last_btn_state","value":"3_single"}`, `{"id":"text_sensor-last_btn_state","value":"103_single"}`},
+		{`{"id":"text_sensor-last_btn_state","value":"garbage"}`, `{"id":"text_sensor-last_btn_state","value":"garbage"}`},
+		{`{"id":"sensor-temperature","value":21.5}`, `{"id":"sensor-temperature","value":21.5}`},
+		{`not json`, `not json`},
+	}
+
+	d := newTestDeej(t, "")
+	for _, tt := range tests {
+		if got := string(d.offsetStateEventIDs([]byte(tt.payload), 100)); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.payload, got, tt.want)
+		}
+	}
+}
+
+func TestExtraSerialBoardsFeedEvents(t *testing.T) {
+	d := newTestDeej(t, "SERIAL_Port: [COM3, COM4]\nslider_remap:\n  101: 4\n")
+	sliderEvents := d.SubscribeToSliderMoveEventsBuffered(8)
+	switchEvents := make(chan SwitchEvent, 8)
+	d.switchConsumers = append(d.switchConsumers, switchEvents)
+
+	primary := &SerialIO{deej: d}
+	extra := newExtraSerialIO(d, d.logger, 1)
+
+	primary.handleLine(d.logger, `{"id":"sensor-pot0","value":10}`)
+	extra.handleLine(d.logger, `{"id":"sensor-pot0","value":20}`)
+	extra.handleLine(d.logger, `{"id":"sensor-pot1","value":30}`)
+	primary.handleLine(d.logger, `{"id":"binary_sensor-sw0","value":true}`)
+	extra.handleLine(d.logger, `{"id":"binary_sensor-sw0","value":true}`)
+
+	got := []int{}
+	for _, move := range receiveSliderMoves(sliderEvents) {
+		got = append(got, move.SliderID)
+	}
+	// the extra board's pot 1 is slider 101, remapped to 4
+	if fmt.Sprint(got) != "[0 100 4]" {
+		t.Errorf("got moves for sliders %v, want [0 100 4]", got)
+	}
+
+	switches := []int{}
+	for len(switchEvents) > 0 {
+		switches = append(switches, (<-switchEvents).SwitchID)
+	}
+	if fmt.Sprint(switches) != "[0 100]" {
+		t.Errorf("got events for switches %v, want [0 100]", switches)
+	}
+}
@@ -0,0 +1,40 @@
+//go:build windows
+// +build windows
+
+package deej
+
+import (
+	"errors"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var procQueryDosDevice = modkernel32.NewProc("QueryDosDeviceW")
+
+const (
+	errorGenFailure         syscall.Errno = 31   // ERROR_GEN_FAILURE, what most USB serial drivers return once unplugged
+	errorDeviceNotConnected syscall.Errno = 1167 // ERROR_DEVICE_NOT_CONNECTED
+)
+
+// serialDeviceRemoved reports whether a serial read/open error means the adapter was physically removed
+func serialDeviceRemoved(err error) bool {
+	return errors.Is(err, errorDeviceNotConnected) || errors.Is(err, errorGenFailure)
+}
+
+// serialPortPresent reports whether a COM port currently exists, without opening it
+func serialPortPresent(portName string) bool {
+	name, err := syscall.UTF16PtrFromString(strings.TrimPrefix(portName, `\\.\`))
+	if err != nil {
+		return false
+	}
+
+	var target [512]uint16
+	ret, _, _ := procQueryDosDevice.Call(
+		uintptr(unsafe.Pointer(name)),
+		uintptr(unsafe.Pointer(&target[0])),
+		uintptr(len(target)),
+	)
+
+	return ret != 0
+}
@@ -0,0 +1,39 @@
+//go:build windows
+// +build windows
+
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestSerialDeviceRemoved(t *testing.T) {
+	const errorAccessDenied syscall.Errno = 5
+
+	tests := []struct {
+		name        string
+		err         error
+		wantRemoved bool
+		wantReason  string
+	}{
+		{"device not connected", &os.PathError{Op: "read", Path: "COM3", Err: errorDeviceNotConnected}, true, connReasonUnplugged},
+		{"wrapped gen failure", fmt.Errorf("serial read: %w", errorGenFailure), true, connReasonUnplugged},
+		{"access denied", &os.PathError{Op: "open", Path: "COM3", Err: errorAccessDenied}, false, connReasonBusy},
+		{"plain error", errors.New("unexpected EOF"), false, connReasonIOError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serialDeviceRemoved(tt.err); got != tt.wantRemoved {
+				t.Errorf("serialDeviceRemoved() = %v, want %v", got, tt.wantRemoved)
+			}
+			if got := connectionErrorReason(tt.err); got != tt.wantReason {
+				t.Errorf("connectionErrorReason() = %q, want %q", got, tt.wantReason)
+			}
+		})
+	}
+}
@@ -1,12 +1,18 @@
 package deej
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"sync"
 
 	"go.uber.org/zap"
 )
 
+// errRefreshSessions is returned by a session whose underlying stream is gone (expired on Windows,
+// a sink input that vanished on Linux). The session map refreshes and retries instead of treating it as a failure
+var errRefreshSessions = errors.New("trigger session refresh")
+
 // Session represents a single addressable audio session
 type Session interface {
 	GetVolume() float32
@@ -30,8 +36,9 @@ const (
 	// but it will not call the child GetVolume correctly :/
 	sessionCreationLogMessage = "Created audio session instance"
 
-	// format this with s.humanReadableDesc and whatever the current volume is
-	sessionStringFormat = "<session: %s, vol: %.2f>"
+	// used by describe(), with s.humanReadableDesc, whatever the current volume and mute state are,
+	// and how many switches hold the session muted
+	sessionStringFormat = "<session: %s, vol: %.2f, muted: %t, switch mutes: %d>"
 )
 
 type baseSession struct {
@@ -86,3 +93,9 @@ func (s *baseSession) AdjustSwitchMuteCount(delta int) int {
 	s.switchMuteLock.Unlock()
 	return current
 }
+
+// describe formats the session for String() given its current volume and mute state, which each
+// platform's session reads in its own way
+func (s *baseSession) describe(volume float32, muted bool) string {
+	return fmt.Sprintf(sessionStringFormat, s.humanReadableDesc, volume, muted, s.GetSwitchMuteCount())
+}
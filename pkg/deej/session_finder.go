@@ -14,3 +14,20 @@ type SessionFinder interface {
 
 	Release() error
 }
+
+// DefaultDeviceSetter is implemented by session finders that can change the system's default output device
+type DefaultDeviceSetter interface {
+	SetDefaultOutputDevice(name string) error // name is matched case-insensitively against device names/descriptions
+}
+
+// DefaultCommunicationsDeviceSetter is implemented by session finders whose platform tells apart the default
+// output device used by voice apps from the one used for everything else (Windows' "communications" role)
+type DefaultCommunicationsDeviceSetter interface {
+	SetDefaultCommunicationsDevice(name string) error // only changes the communications role
+}
+
+// FilteringSessionFinder is implemented by session finders that can skip sessions while enumerating,
+// saving the per-session work for processes nothing is mapped to
+type FilteringSessionFinder interface {
+	SetSessionFilter(keep func(processName string) bool) // nil enumerates every session
+}
@@ -4,18 +4,24 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 
 	"github.com/jfreymuth/pulse/proto"
 	"github.com/stalexteam/deej_esp32/pkg/deej/util"
 	"go.uber.org/zap"
 )
 
+// sink inputs with this media.role are event sounds, mapped to the "system" target
+const paEventMediaRole = "event"
+
 type paSessionFinder struct {
 	logger        *zap.SugaredLogger
 	sessionLogger *zap.SugaredLogger
 
 	client *proto.Client
 	conn   net.Conn
+
+	keep func(processName string) bool // optional filter applied while enumerating sink inputs
 }
 
 func newSessionFinder(logger *zap.SugaredLogger) (SessionFinder, error) {
@@ -145,6 +151,40 @@ func (sf *paSessionFinder) GetAllDevices() ([]AudioDeviceInfo, error) {
 	return devices, nil
 }
 
+// SetDefaultOutputDevice makes the sink with the given name or description the default one
+func (sf *paSessionFinder) SetDefaultOutputDevice(name string) error {
+	sinkRequest := proto.GetSinkInfoList{}
+	sinkReply := proto.GetSinkInfoListReply{}
+	if err := sf.client.Request(&sinkRequest, &sinkReply); err != nil {
+		return fmt.Errorf("get sink info list: %w", err)
+	}
+
+	for _, sink := range sinkReply {
+		if sink == nil {
+			continue
+		}
+
+		description := ""
+		if sink.Properties != nil {
+			if descProp, ok := sink.Properties["device.description"]; ok {
+				description = descProp.String()
+			}
+		}
+
+		if !strings.EqualFold(sink.SinkName, name) && !strings.EqualFold(description, name) {
+			continue
+		}
+
+		if err := sf.client.Request(&proto.SetDefaultSink{SinkName: sink.SinkName}, nil); err != nil {
+			return fmt.Errorf("set default sink %s: %w", sink.SinkName, err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("no output device named %q", name)
+}
+
 func (sf *paSessionFinder) Release() error {
 	if err := sf.conn.Close(); err != nil {
 		sf.logger.Warnw("Failed to close PulseAudio connection", "error", err)
@@ -190,6 +230,10 @@ func (sf *paSessionFinder) getMasterSourceSession() (Session, error) {
 	return source, nil
 }
 
+func (sf *paSessionFinder) SetSessionFilter(keep func(processName string) bool) {
+	sf.keep = keep
+}
+
 func (sf *paSessionFinder) enumerateAndAddSessions(sessions *[]Session) error {
 	request := proto.GetSinkInputInfoList{}
 	reply := proto.GetSinkInputInfoListReply{}
@@ -199,9 +243,23 @@ func (sf *paSessionFinder) enumerateAndAddSessions(sessions *[]Session) error {
 		return fmt.Errorf("get sink input list: %w", err)
 	}
 
+	foundSystem := false
+
 	for _, info := range reply {
 		name, ok := info.Properties["application.process.binary"]
 
+		// event sounds (notifications, UI feedback) answer to the "system" target, like Windows' system sounds session
+		if role, hasRole := info.Properties["media.role"]; hasRole && role.String() == paEventMediaRole {
+			processName := "unknown"
+			if ok {
+				processName = name.String()
+			}
+
+			*sessions = append(*sessions, newPASystemSession(sf.sessionLogger, sf.client, info.SinkInputIndex, info.Channels, processName))
+			foundSystem = true
+			continue
+		}
+
 		if !ok {
 			sf.logger.Warnw("Failed to get sink input's process name",
 				"sinkInputIndex", info.SinkInputIndex)
@@ -209,6 +267,10 @@ func (sf *paSessionFinder) enumerateAndAddSessions(sessions *[]Session) error {
 			continue
 		}
 
+		if sf.keep != nil && !sf.keep(name.String()) {
+			continue
+		}
+
 		// Try to get PID from PulseAudio properties
 		var processPath string
 		if pidProp, ok := info.Properties["application.process.id"]; ok {
@@ -231,5 +293,9 @@ func (sf *paSessionFinder) enumerateAndAddSessions(sessions *[]Session) error {
 
 	}
 
+	if !foundSystem {
+		sf.logger.Debug("No event sounds stream is playing, the system target has nothing to control until one appears")
+	}
+
 	return nil
 }
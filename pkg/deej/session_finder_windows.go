@@ -9,6 +9,7 @@ import (
 	"unsafe"
 
 	ole "github.com/go-ole/go-ole"
+	"github.com/mitchellh/go-ps"
 	wca "github.com/moutend/go-wca"
 	"go.uber.org/zap"
 )
@@ -27,6 +28,8 @@ type wcaSessionFinder struct {
 	// our master input and output sessions
 	masterOut *masterSession
 	masterIn  *masterSession
+
+	keep func(processName string) bool // optional filter applied while enumerating sessions
 }
 
 const (
@@ -40,8 +43,56 @@ const (
 
 	// prefix for device sessions in logger
 	deviceSessionFormat = "device.%s"
+
+	// undocumented, but stable since Vista - this is how every "default device switcher" does it
+	clsidPolicyConfigClient = "{870af99c-171d-4f9e-af0d-e63df40c2bc9}"
+	iidPolicyConfig         = "{f8679f50-850a-41cf-9c72-430f290290c8}"
 )
 
+// iPolicyConfig is the minimal binding to the undocumented IPolicyConfig interface we need
+type iPolicyConfig struct {
+	ole.IUnknown
+}
+
+type iPolicyConfigVtbl struct {
+	ole.IUnknownVtbl
+	GetMixFormat          uintptr
+	GetDeviceFormat       uintptr
+	ResetDeviceFormat     uintptr
+	SetDeviceFormat       uintptr
+	GetProcessingPeriod   uintptr
+	SetProcessingPeriod   uintptr
+	GetShareMode          uintptr
+	SetShareMode          uintptr
+	GetPropertyValue      uintptr
+	SetPropertyValue      uintptr
+	SetDefaultEndpoint    uintptr
+	SetEndpointVisibility uintptr
+}
+
+func (v *iPolicyConfig) vtable() *iPolicyConfigVtbl {
+	return (*iPolicyConfigVtbl)(unsafe.Pointer(v.RawVTable))
+}
+
+func (v *iPolicyConfig) setDefaultEndpoint(deviceID string, role uint32) error {
+	deviceIDPtr, err := syscall.UTF16PtrFromString(deviceID)
+	if err != nil {
+		return fmt.Errorf("convert device id: %w", err)
+	}
+
+	hr, _, _ := syscall.Syscall(
+		v.vtable().SetDefaultEndpoint,
+		3,
+		uintptr(unsafe.Pointer(v)),
+		uintptr(unsafe.Pointer(deviceIDPtr)),
+		uintptr(role))
+	if hr != ole.S_OK {
+		return ole.NewError(hr)
+	}
+
+	return nil
+}
+
 func newSessionFinder(logger *zap.SugaredLogger) (SessionFinder, error) {
 	sf := &wcaSessionFinder{
 		logger:        logger.Named("session_finder"),
@@ -233,6 +284,140 @@ func (sf *wcaSessionFinder) GetAllDevices() ([]AudioDeviceInfo, error) {
 	return devices, nil
 }
 
+// SetDefaultOutputDevice makes the active output device with the given friendly name the default
+// one, for all roles (console, multimedia and communications)
+func (sf *wcaSessionFinder) SetDefaultOutputDevice(name string) error {
+	return sf.setDefaultOutputDevice(name, wca.EConsole, wca.EMultimedia, wca.ECommunications)
+}
+
+// SetDefaultCommunicationsDevice makes the active output device with the given friendly name the default
+// communications device (the one voice apps use), leaving the default multimedia device alone
+func (sf *wcaSessionFinder) SetDefaultCommunicationsDevice(name string) error {
+	return sf.setDefaultOutputDevice(name, wca.ECommunications)
+}
+
+func (sf *wcaSessionFinder) setDefaultOutputDevice(name string, roles ...uint32) error {
+
+	// we must call this every time we're about to list devices
+	if err := ole.CoInitializeEx(0, ole.COINIT_APARTMENTTHREADED); err != nil {
+		const eFalse = 1
+		oleError := &ole.OleError{}
+		if errors.As(err, &oleError) && oleError.Code() != eFalse {
+			return fmt.Errorf("call CoInitializeEx: %w", err)
+		}
+	}
+	defer ole.CoUninitialize()
+
+	if err := sf.getDeviceEnumerator(); err != nil {
+		return fmt.Errorf("get device enumerator: %w", err)
+	}
+
+	deviceID, err := sf.findOutputDeviceID(name)
+	if err != nil {
+		return err
+	}
+
+	var policyConfig *iPolicyConfig
+	if err := wca.CoCreateInstance(
+		ole.NewGUID(clsidPolicyConfigClient),
+		0,
+		wca.CLSCTX_ALL,
+		ole.NewGUID(iidPolicyConfig),
+		&policyConfig,
+	); err != nil {
+		return fmt.Errorf("create policy config: %w", err)
+	}
+	defer policyConfig.Release()
+
+	for _, role := range roles {
+		if err := policyConfig.setDefaultEndpoint(deviceID, role); err != nil {
+			return fmt.Errorf("set default endpoint (role %d): %w", role, err)
+		}
+	}
+
+	return nil
+}
+
+// findOutputDeviceID returns the endpoint ID of the active output device with the given friendly name
+func (sf *wcaSessionFinder) findOutputDeviceID(name string) (string, error) {
+	var deviceCollection *wca.IMMDeviceCollection
+	if err := sf.mmDeviceEnumerator.EnumAudioEndpoints(wca.ERender, wca.DEVICE_STATE_ACTIVE, &deviceCollection); err != nil {
+		return "", fmt.Errorf("enumerate active audio endpoints: %w", err)
+	}
+	defer deviceCollection.Release()
+
+	var deviceCount uint32
+	if err := deviceCollection.GetCount(&deviceCount); err != nil {
+		return "", fmt.Errorf("get device count: %w", err)
+	}
+
+	for deviceIdx := uint32(0); deviceIdx < deviceCount; deviceIdx++ {
+		var endpoint *wca.IMMDevice
+		if err := deviceCollection.Item(deviceIdx, &endpoint); err != nil {
+			continue // skip this device
+		}
+
+		friendlyName, err := endpointFriendlyName(endpoint)
+		if err != nil || !strings.EqualFold(friendlyName, name) {
+			endpoint.Release()
+			continue
+		}
+
+		deviceID, err := endpointID(endpoint)
+		endpoint.Release()
+		if err != nil {
+			return "", fmt.Errorf("get id of device %s: %w", friendlyName, err)
+		}
+
+		return deviceID, nil
+	}
+
+	return "", fmt.Errorf("no active output device named %q", name)
+}
+
+func endpointFriendlyName(endpoint *wca.IMMDevice) (string, error) {
+	var propertyStore *wca.IPropertyStore
+	if err := endpoint.OpenPropertyStore(wca.STGM_READ, &propertyStore); err != nil {
+		return "", fmt.Errorf("open property store: %w", err)
+	}
+	defer propertyStore.Release()
+
+	value := &wca.PROPVARIANT{}
+	if err := propertyStore.GetValue(&wca.PKEY_Device_FriendlyName, value); err != nil {
+		return "", fmt.Errorf("get friendly name: %w", err)
+	}
+
+	return value.String(), nil
+}
+
+// endpointID calls IMMDevice::GetId directly - go-wca's wrapper stores the returned pointer
+// in a uint32, which truncates it on 64-bit
+func endpointID(endpoint *wca.IMMDevice) (string, error) {
+	var id *uint16
+	hr, _, _ := syscall.Syscall(
+		endpoint.VTable().GetId,
+		2,
+		uintptr(unsafe.Pointer(endpoint)),
+		uintptr(unsafe.Pointer(&id)),
+		0)
+	if hr != ole.S_OK {
+		return "", ole.NewError(hr)
+	}
+	defer ole.CoTaskMemFree(uintptr(unsafe.Pointer(id)))
+
+	// null-terminated wide string
+	var chars []uint16
+	for i := uintptr(0); ; i++ {
+		char := *(*uint16)(unsafe.Add(unsafe.Pointer(id), i*2))
+		if char == 0 {
+			break
+		}
+		chars = append(chars, char)
+	}
+
+	return syscall.UTF16ToString(chars), nil
+}
+
 func (sf *wcaSessionFinder) Release() error {
 
 	// skip unregistering the mmnotificationclient, as it's not implemented in go-wca
@@ -326,6 +511,10 @@ func (sf *wcaSessionFinder) getMasterSession(mmDevice *wca.IMMDevice, key string
 	return master, nil
 }
 
+func (sf *wcaSessionFinder) SetSessionFilter(keep func(processName string) bool) {
+	sf.keep = keep
+}
+
 func (sf *wcaSessionFinder) enumerateAndAddSessions(sessions *[]Session) error {
 
 	// get list of devices
@@ -558,6 +747,15 @@ func (sf *wcaSessionFinder) enumerateAndAddProcessSessions(
 			// it will successfully update whenever we call GetProcessId for e.g. Video.UI.exe, despite the error being non-nil.
 		}
 
+		// skip sessions of processes nothing is mapped to before doing any more work on them.
+		// the system sounds session (pid 0) is always kept
+		if sf.keep != nil && pid != 0 {
+			if process, err := ps.FindProcess(int(pid)); err == nil && process != nil && !sf.keep(process.Executable()) {
+				audioSessionControl2.Release()
+				continue
+			}
+		}
+
 		// get its ISimpleAudioVolume
 		dispatch, err = audioSessionControl2.QueryInterface(wca.IID_ISimpleAudioVolume)
 		if err != nil {
@@ -63,6 +63,32 @@ func newPASession(
 	return s
 }
 
+// newPASystemSession wraps an event sounds sink input, PulseAudio's closest match to Windows' system sounds session
+func newPASystemSession(
+	logger *zap.SugaredLogger,
+	client *proto.Client,
+	sinkInputIndex uint32,
+	sinkInputChannels byte,
+	processName string,
+) *paSession {
+
+	s := &paSession{
+		client:            client,
+		sinkInputIndex:    sinkInputIndex,
+		sinkInputChannels: sinkInputChannels,
+	}
+
+	s.system = true
+	s.processName = processName
+	s.name = systemSessionName
+	s.humanReadableDesc = fmt.Sprintf("system sounds (%s)", processName)
+
+	s.logger = logger.Named(s.Key())
+	s.logger.Debugw(sessionCreationLogMessage, "session", s)
+
+	return s
+}
+
 func newMasterSession(
 	logger *zap.SugaredLogger,
 	client *proto.Client,
@@ -117,6 +143,10 @@ func (s *paSession) SetVolume(v float32) error {
 	}
 
 	if err := s.client.Request(&request, nil); err != nil {
+		if sinkInputGone(err) {
+			s.logger.Debugw("Sink input is gone, triggering session refresh")
+			return errRefreshSessions
+		}
 		s.logger.Warnw("Failed to set session volume", "error", err)
 		return fmt.Errorf("adjust session volume: %w", err)
 	}
@@ -144,6 +174,10 @@ func (s *paSession) SetMute(v bool, silent bool) error {
 		Mute:           v,
 	}
 	if err := s.client.Request(&request, nil); err != nil {
+		if sinkInputGone(err) {
+			s.logger.Debugw("Sink input is gone, triggering session refresh")
+			return errRefreshSessions
+		}
 		s.logger.Warnw("Failed to set mute state", "error", err)
 		return fmt.Errorf("set mute: %w", err)
 	}
@@ -154,6 +188,12 @@ func (s *paSession) SetMute(v bool, silent bool) error {
 	return nil
 }
 
+// sinkInputGone reports whether a request failed because the sink input no longer exists,
+// i.e. the app's stream ended between enumerating sessions and adjusting this one
+func sinkInputGone(err error) bool {
+	return errors.Is(err, proto.ErrNoSuchEntity)
+}
+
 func (s *paSession) Release() {
 	s.logger.Debug("Releasing audio session")
 }
@@ -163,7 +203,7 @@ func (s *paSession) ProcessPath() string {
 }
 
 func (s *paSession) String() string {
-	return fmt.Sprintf(sessionStringFormat, s.humanReadableDesc, s.GetVolume())
+	return s.describe(s.GetVolume(), s.GetMute())
 }
 
 func (s *masterSession) GetVolume() float32 {
@@ -277,7 +317,7 @@ func (s *masterSession) Release() {
 }
 
 func (s *masterSession) String() string {
-	return fmt.Sprintf(sessionStringFormat, s.humanReadableDesc, s.GetVolume())
+	return s.describe(s.GetVolume(), s.GetMute())
 }
 
 func createChannelVolumes(channels byte, volume float32) []uint32 {
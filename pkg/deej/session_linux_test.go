@@ -0,0 +1,64 @@
+//go:build linux
+// +build linux
+
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jfreymuth/pulse/proto"
+	"go.uber.org/zap"
+)
+
+func TestSystemSessionKey(t *testing.T) {
+	tests := []struct {
+		processName string
+	}{
+		{"gnome-shell"},
+		{"unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.processName, func(t *testing.T) {
+			session := newPASystemSession(zap.NewNop().Sugar(), nil, 7, 2, tt.processName)
+
+			if session.Key() != systemSessionName {
+				t.Errorf("got key %q, want %q", session.Key(), systemSessionName)
+			}
+			if !strings.Contains(session.humanReadableDesc, tt.processName) {
+				t.Errorf("description %q doesn't name the process %q", session.humanReadableDesc, tt.processName)
+			}
+
+			// the session map finds it under the "system" target
+			m, _ := newTestSessionMap(t, fakeSessionFinder{})
+			m.add(session)
+			if sessions, ok := m.get(systemSessionName); !ok || len(sessions) != 1 {
+				t.Errorf("system target resolved to %v", sessions)
+			}
+		})
+	}
+}
+
+func TestSinkInputGone(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"no such entity", proto.ErrNoSuchEntity, true},
+		{"wrapped no such entity", fmt.Errorf("set sink input volume: %w", proto.ErrNoSuchEntity), true},
+		{"access denied", proto.ErrAccessDenied, false},
+		{"connection lost", errors.New("connection closed"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sinkInputGone(tt.err); got != tt.want {
+				t.Errorf("sinkInputGone(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
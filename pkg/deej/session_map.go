@@ -1,7 +1,9 @@
 package deej
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"regexp"
 	"strings"
 	"sync"
@@ -23,6 +25,41 @@ type sessionMap struct {
 
 	lastSessionRefresh time.Time
 	unmappedSessions   []Session
+
+	processGroupLock    sync.Mutex
+	processGroupCache   map[string]processGroupCacheEntry
+	focusedSessionCache processGroupCacheEntry // also protected by processGroupLock
+
+	// push-to-talk release timers, only touched from the switch event goroutine
+	pttTimers   map[int]*time.Timer
+	pttReleases chan int
+
+	// last mute state each push-to-talk switch asked for, kept per switch rather than per session
+	// so sessions that show up later (e.g. an app restarting) get it too
+	pttMutedLock sync.Mutex
+	pttMuted     map[int]bool
+
+	// keys of the sessions each engaged solo switch muted, so disengaging only unmutes those
+	soloLock  sync.Mutex
+	soloMuted map[int]map[string]bool
+
+	// session keys found by the previous refresh, so target_default_volume only hits sessions that just appeared.
+	// nil until the first refresh, whose sessions were already there when deej started
+	knownSessionKeys map[string]bool
+
+	// how far each held slider_jog slider is from its center (-1 to 1), and whether runJog is ticking
+	jogLock          sync.Mutex
+	jogDisplacements map[int]float64
+	jogRunning       bool
+
+	// last positions of slider_fine sliders and their coarse sliders, see slider_fine.go
+	fineLock      sync.Mutex
+	finePositions map[int]float32
+}
+
+type processGroupCacheEntry struct {
+	names     []string
+	updatedAt time.Time
 }
 
 // SliderMoveEvent represents a single slider move captured by deej
@@ -50,35 +87,71 @@ const (
 	// targets the currently active window (Windows-only, experimental)
 	specialTargetCurrentWindow = "current"
 
+	// targets the sessions of the active window's processes and every process they spawned,
+	// which catches helper processes playing audio for the window (Windows-only, experimental)
+	specialTargetFocusedSession = "focused_session"
+
 	// targets all currently unmapped sessions (experimental)
 	specialTargetAllUnmapped = "unmapped"
 
+	// targets an executable and every process it spawned, e.g. "deej.group:discord.exe" (experimental)
+	specialTargetProcessGroupPrefix = "group:"
+
+	// switches the default output device between two devices, e.g. "deej.switch_device:Speakers|Headphones".
+	// only valid as a switch target: off selects the first device, on selects the second
+	specialTargetSwitchDevicePrefix = "switch_device:"
+
+	// same as switch_device, but only changes the default communications device (the one voice apps use).
+	// windows only, e.g. "deej.switch_comm_device:Speakers|Headset"
+	specialTargetSwitchCommDevicePrefix = "switch_comm_device:"
+
+	// process group lookups walk the whole process list, so don't repeat them on every slider tick
+	processGroupCacheDuration = time.Second * 2
+
+	// same for focused_session, but the focus changes all the time so its lookup goes stale much sooner
+	focusedSessionCacheDuration = time.Millisecond * 500
+
 	// this threshold constant assumes that re-acquiring all sessions is a kind of expensive operation,
-	// and needs to be limited in some manner. this value was previously user-configurable through a config
-	// key "process_refresh_frequency", but exposing this type of implementation detail seems wrong now
+	// and needs to be limited in some manner. it's the default for session_refresh_cooldown
 	minTimeBetweenSessionRefreshes = time.Second * 5
 
+	// a forced refresh after a failed volume adjustment can still fail (e.g. the audio engine is restarting),
+	// leaving a failing session behind. this stops every slider tick from forcing yet another refresh.
+	// it's the default for session_refresh_max_rate (refreshes per second)
+	maxFailureRefreshesPerSecond = 1
+
 	// determines whether the map should be refreshed when a slider moves.
 	// this is a bit greedy but allows us to ensure sessions are always re-acquired, which is
 	// especially important for process groups (because you can have one ongoing session
 	// always preventing lookup of other processes bound to its slider, which forces the user
 	// to manually refresh sessions). a cleaner way to do this down the line is by registering to notifications
-	// whenever a new session is added, but that's too hard to justify for how easy this solution is
+	// whenever a new session is added, but that's too hard to justify for how easy this solution is.
+	// it's the default for session_refresh_max_age
 	maxTimeBetweenSessionRefreshes = time.Second * 45
 )
 
 // this matches friendly device names (on Windows), e.g. "Headphones (Realtek Audio)"
 var deviceSessionKeyPattern = regexp.MustCompile(`^.+ \(.+\)$`)
 
+// foregroundProcessTreeNames returns the names in the active window's process tree. It's a variable so
+// the window and process lookups can be replaced
+var foregroundProcessTreeNames = util.GetForegroundProcessTreeNames
+
 func newSessionMap(deej *Deej, logger *zap.SugaredLogger, sessionFinder SessionFinder) (*sessionMap, error) {
 	logger = logger.Named("sessions")
 
 	m := &sessionMap{
-		deej:          deej,
-		logger:        logger,
-		m:             make(map[string][]Session),
-		lock:          &sync.Mutex{},
-		sessionFinder: sessionFinder,
+		deej:             deej,
+		logger:           logger,
+		m:                make(map[string][]Session),
+		lock:             &sync.Mutex{},
+		sessionFinder:    sessionFinder,
+		pttTimers:        make(map[int]*time.Timer),
+		pttReleases:      make(chan int, 8),
+		pttMuted:         make(map[int]bool),
+		soloMuted:        make(map[int]map[string]bool),
+		jogDisplacements: make(map[int]float64),
+		finePositions:    make(map[int]float32),
 	}
 
 	logger.Debug("Created session map instance")
@@ -130,18 +203,36 @@ func (m *sessionMap) getAndAddSessions() error {
 	m.lastSessionRefresh = time.Now()
 	m.unmappedSessions = nil
 
+	if finder, ok := m.sessionFinder.(FilteringSessionFinder); ok {
+		finder.SetSessionFilter(m.sessionFilter())
+	}
+
 	sessions, err := m.sessionFinder.GetAllSessions()
 	if err != nil {
 		m.logger.Warnw("Failed to get sessions from session finder", "error", err)
 		return fmt.Errorf("get sessions from SessionFinder: %w", err)
 	}
 
-	for _, session := range sessions {
+	logLimit := m.deej.config.SessionLogLimit
+	sessionKeys := make(map[string]bool, len(sessions))
+
+	// sessions found at startup keep whatever volume the user left them at
+	startup := m.knownSessionKeys == nil
+
+	for idx, session := range sessions {
 		m.add(session)
+		if !startup && !m.knownSessionKeys[session.Key()] {
+			m.applyDefaultVolume(session)
+		}
+		sessionKeys[session.Key()] = true
 		m.applySwitchMuteState(session)
 
-		// Log all sessions at INFO level so they appear in release build logs
-		m.logger.Infow("Audio session", "key", session.Key(), "session", session)
+		// Log sessions at INFO level so they appear in release build logs, up to session_log_limit
+		if logLimit == 0 || idx < logLimit {
+			m.logger.Infow("Audio session", "key", session.Key(), "session", session)
+		} else {
+			m.logger.Debugw("Audio session", "key", session.Key(), "session", session)
+		}
 
 		if !m.sessionMapped(session) {
 			m.logger.Debugw("Tracking unmapped session", "session", session)
@@ -149,6 +240,8 @@ func (m *sessionMap) getAndAddSessions() error {
 		}
 	}
 
+	m.knownSessionKeys = sessionKeys
+
 	m.logger.Infow("Got all audio sessions successfully", "count", len(sessions))
 	m.logger.Debugw("Session map details", "sessionMap", m)
 
@@ -160,6 +253,47 @@ func (m *sessionMap) getAndAddSessions() error {
 	return nil
 }
 
+// sessionFilter returns the process-name filter for session enumeration when session_filter is on,
+// or nil to enumerate everything. Special targets (current window, unmapped, groups) and path targets
+// can match any process, so any of them turns filtering off
+func (m *sessionMap) sessionFilter() func(processName string) bool {
+	if !m.deej.config.SessionFilter {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	needsAll := false
+
+	collect := func(_ int, targets []string) {
+		for _, target := range targets {
+			if _, isSwitchDevice := parseSwitchDeviceTarget(target); isSwitchDevice {
+				continue
+			}
+
+			if m.targetHasSpecialTransform(strings.ToLower(target)) || util.IsPath(target) {
+				needsAll = true
+				return
+			}
+
+			names[strings.ToLower(target)] = true
+		}
+	}
+
+	m.deej.config.SliderMapping.iterate(collect)
+	m.deej.config.SwitchesMapping.iterate(func(switchID int, targets []string) {
+		collect(switchID, m.deej.config.ExpandMuteGroups(targets))
+	})
+
+	if needsAll {
+		m.logger.Debug("Mapping needs every session, not filtering session enumeration")
+		return nil
+	}
+
+	return func(processName string) bool {
+		return names[strings.ToLower(processName)]
+	}
+}
+
 // evictStaleProcessPathCache removes cached process paths for PIDs that are no longer
 // present in the current set of audio sessions. This prevents the cache from growing
 // unboundedly and ensures stale paths don't linger after a process exits.
@@ -187,7 +321,7 @@ func (m *sessionMap) setupOnConfigReload() {
 		for {
 			<-configReloadedChannel
 			m.logger.Info("Detected config reload, attempting to re-acquire all audio sessions")
-			// Use force=true to ensure sessions are refreshed even if minTimeBetweenSessionRefreshes hasn't passed.
+			// Use force=true to ensure sessions are refreshed even if session_refresh_cooldown hasn't passed.
 			// This is critical when paths are added/removed/changed in the config, as we need to re-evaluate
 			// all sessions against the new mapping immediately.
 			m.refreshSessions(true)
@@ -216,13 +350,18 @@ func (m *sessionMap) setupOnSwitchEvent() {
 
 	go func() {
 		for {
-			event, ok := <-switchEventsChannel
-			if !ok {
-				// channel was closed by closeEventChannels() during shutdown — exit cleanly
-				m.logger.Info("Switch events channel closed, session map handler exiting")
-				return
+			select {
+			case event, ok := <-switchEventsChannel:
+				if !ok {
+					// channel was closed by closeEventChannels() during shutdown — exit cleanly
+					m.logger.Info("Switch events channel closed, session map handler exiting")
+					return
+				}
+				m.handleSwitchEvent(event)
+
+			case switchID := <-m.pttReleases:
+				m.handlePTTRelease(switchID)
 			}
-			m.handleSwitchEvent(event)
 		}
 	}()
 }
@@ -231,7 +370,7 @@ func (m *sessionMap) setupOnSwitchEvent() {
 func (m *sessionMap) refreshSessions(force bool) {
 
 	// make sure enough time passed since the last refresh, unless force is true in which case always clear
-	if !force && m.lastSessionRefresh.Add(minTimeBetweenSessionRefreshes).After(time.Now()) {
+	if !force && m.lastSessionRefresh.Add(m.deej.config.SessionRefreshCooldown).After(time.Now()) {
 		return
 	}
 
@@ -245,18 +384,34 @@ func (m *sessionMap) refreshSessions(force bool) {
 	}
 }
 
+// refreshSessionsAfterFailure forces a refresh because a session failed, but no more than
+// session_refresh_max_rate times per second, so a session that keeps failing can't make refreshes spin.
+// It returns whether the refresh ran
+func (m *sessionMap) refreshSessionsAfterFailure() bool {
+	minInterval := time.Second / time.Duration(m.deej.config.SessionRefreshMaxRate)
+	if m.lastSessionRefresh.Add(minInterval).After(time.Now()) {
+		m.logger.Debug("Session adjustment failed right after a refresh, not refreshing again yet")
+		return false
+	}
+
+	m.refreshSessions(true)
+	return true
+}
+
 // returns true if a session is not currently mapped to any slider, false otherwise
-// special sessions (master, system, mic) and device-specific sessions always count as mapped,
-// even when absent from the config. this makes sense for every current feature that uses "unmapped sessions"
+// special sessions (master, system, mic) and device-specific sessions count as mapped, even when absent
+// from the config, unless unmapped_includes lists their class. this makes sense for every current feature
+// that uses "unmapped sessions"
 func (m *sessionMap) sessionMapped(session Session) bool {
+	includes := m.deej.config.UnmappedIncludes
 
-	// count master/system/mic as mapped
+	// count master/system/mic as mapped (the session names double as their unmapped_includes classes)
 	if funk.ContainsString([]string{masterSessionName, systemSessionName, inputSessionName}, session.Key()) {
-		return true
-	}
-
-	// count device sessions as mapped
-	if deviceSessionKeyPattern.MatchString(session.Key()) {
+		if !includes[session.Key()] {
+			return true
+		}
+	} else if deviceSessionKeyPattern.MatchString(session.Key()) && !includes[UnmappedIncludeDevices] {
+		// count device sessions as mapped
 		return true
 	}
 
@@ -266,8 +421,14 @@ func (m *sessionMap) sessionMapped(session Session) bool {
 	m.deej.config.SliderMapping.iterate(func(sliderIdx int, targets []string) {
 		for _, target := range targets {
 
-			// ignore special transforms
+			// ignore special transforms, except process groups which name concrete processes
 			if m.targetHasSpecialTransform(target) {
+				groupPrefix := specialTargetTransformPrefix + specialTargetProcessGroupPrefix
+				if strings.HasPrefix(strings.ToLower(target), groupPrefix) &&
+					funk.ContainsString(m.resolveTarget(target), session.Key()) {
+					matchFound = true
+					return
+				}
 				continue
 			}
 
@@ -296,11 +457,30 @@ func (m *sessionMap) sessionMapped(session Session) bool {
 func (m *sessionMap) handleSliderMoveEvent(event SliderMoveEvent) {
 
 	// first of all, ensure our session map isn't moldy
-	if m.lastSessionRefresh.Add(maxTimeBetweenSessionRefreshes).Before(time.Now()) {
+	if m.lastSessionRefresh.Add(m.deej.config.SessionRefreshMaxAge).Before(time.Now()) {
 		m.logger.Debug("Stale session map detected on slider move, refreshing")
 		m.refreshSessions(true)
 	}
 
+	// jog sliders don't set a volume, they keep nudging it while held off center
+	if _, ok := m.deej.config.SliderJog[event.SliderID]; ok {
+		m.handleJogSliderMove(event)
+		return
+	}
+
+	// a fine slider moves its coarse slider's targets, a coarse slider takes its fine slider into account
+	event, ok := m.combineFineSlider(event)
+	if !ok {
+		return
+	}
+
+	m.applySliderMove(event, true)
+}
+
+// applySliderMove sets the volume of every session mapped to the slider. If one of them turns out to be
+// gone (errRefreshSessions) and retry is set, the move is applied once more after the failure refresh,
+// if session_refresh_max_rate let it run
+func (m *sessionMap) applySliderMove(event SliderMoveEvent, retry bool) {
 	// get the targets mapped to this slider from the config
 	targets, ok := m.deej.config.SliderMapping.get(event.SliderID)
 
@@ -311,10 +491,14 @@ func (m *sessionMap) handleSliderMoveEvent(event SliderMoveEvent) {
 
 	targetFound := false
 	adjustmentFailed := false
+	sessionGone := false
 
 	// for each possible target for this slider...
 	for _, target := range targets {
 
+		// slider_weights can make this target follow the slider partially, or run opposite to it
+		volume := m.deej.config.WeightedVolume(event.SliderID, target, event.PercentValue)
+
 		// resolve the target name by cleaning it up and applying any special transformations.
 		// depending on the transformation applied, this can result in more than one target name
 		resolvedTargets := m.resolveTarget(target)
@@ -327,8 +511,12 @@ func (m *sessionMap) handleSliderMoveEvent(event SliderMoveEvent) {
 				m.iterateAllSessions(func(session Session) {
 					if util.PathMatches(session.ProcessPath(), resolvedTarget) {
 						targetFound = true
-						if err := session.SetVolume(event.PercentValue); err != nil {
-							m.logger.Warnw("Failed to set target session volume", "error", err)
+						if err := session.SetVolume(volume); err != nil {
+							if errors.Is(err, errRefreshSessions) {
+								sessionGone = true
+							} else {
+								m.logger.Warnw("Failed to set target session volume", "error", err)
+							}
 							adjustmentFailed = true
 						}
 						if session.GetSwitchMuteCount() > 0 {
@@ -352,8 +540,12 @@ func (m *sessionMap) handleSliderMoveEvent(event SliderMoveEvent) {
 
 				// iterate all matching sessions and adjust the volume of each one
 				for _, session := range sessions {
-					if err := session.SetVolume(event.PercentValue); err != nil {
-						m.logger.Warnw("Failed to set target session volume", "error", err)
+					if err := session.SetVolume(volume); err != nil {
+						if errors.Is(err, errRefreshSessions) {
+							sessionGone = true
+						} else {
+							m.logger.Warnw("Failed to set target session volume", "error", err)
+						}
 						adjustmentFailed = true
 					}
 					if session.GetSwitchMuteCount() > 0 {
@@ -377,29 +569,45 @@ func (m *sessionMap) handleSliderMoveEvent(event SliderMoveEvent) {
 		// performance: the reason that forcing a refresh here is okay is that we'll only get here
 		// when a session's SetVolume call errored, such as in the case of a stale master session
 		// (or another, more catastrophic failure happens)
-		m.refreshSessions(true)
+		refreshed := m.refreshSessionsAfterFailure()
+
+		// an app's stream ended after the last refresh - try again on its current sessions, so the move
+		// isn't lost. only when the refresh actually ran, and only once per move
+		if refreshed && sessionGone && retry {
+			m.logger.Debugw("Target session was gone, retrying on the refreshed sessions", "slider", event.SliderID)
+			m.applySliderMove(event, false)
+		}
 	}
 }
 
-func (m *sessionMap) applySwitchStateToSession(session Session, state bool, prevState bool, hasPrev bool) bool {
-	if hasPrev && state == prevState {
-		return false
+// sendMuteIndicator sends the switch's mute_indicator command for its new state to the device, if configured
+func (m *sessionMap) sendMuteIndicator(switchIdx int, muted bool) {
+	indicator, ok := m.deej.config.MuteIndicators[switchIdx]
+	if !ok {
+		return
 	}
 
-	delta := 0
-	if hasPrev {
-		if state {
-			delta = 1
-		} else {
-			delta = -1
-		}
-	} else if state {
-		delta = 1
+	command := indicator.Unmuted
+	if muted {
+		command = indicator.Muted
+	}
+	if command == "" {
+		return
 	}
 
-	if delta != 0 {
-		session.AdjustSwitchMuteCount(delta)
+	if err := m.deej.WriteToDevice([]byte(command)); err != nil {
+		m.logger.Debugw("Failed to send mute indicator to device", "switch", switchIdx, "error", err)
 	}
+}
+
+func (m *sessionMap) applySwitchStateToSession(session Session, state bool, prevState bool, hasPrev bool) bool {
+	if hasPrev && state == prevState {
+		return false
+	}
+
+	// recount instead of adjusting by this switch's delta: a session picked up by a refresh after the
+	// switch changed (e.g. the mic session, once the default input device changed) already counts it
+	session.SetSwitchMuteCount(m.calculateSwitchMuteCount(session))
 
 	if session.GetSwitchMuteCount() > 0 {
 		if !session.GetMute() {
@@ -411,7 +619,7 @@ func (m *sessionMap) applySwitchStateToSession(session Session, state bool, prev
 		return false
 	}
 
-	if session.GetMute() && (!hasPrev || !state) {
+	if session.GetMute() && (!hasPrev || !state) && !m.soloMutesSession(session) {
 		if err := session.SetMute(false, false); err != nil {
 			m.logger.Warnw("Failed to set mute state for target session", "error", err)
 			return true
@@ -421,6 +629,27 @@ func (m *sessionMap) applySwitchStateToSession(session Session, state bool, prev
 	return false
 }
 
+// applyDefaultVolume sets a session that just appeared to its target_default_volume, if it has one.
+// The sliders take over again once they're moved
+func (m *sessionMap) applyDefaultVolume(session Session) {
+	for target, volume := range m.deej.config.TargetDefaultVolume {
+		if util.IsPath(target) {
+			if !util.PathMatches(session.ProcessPath(), target) {
+				continue
+			}
+		} else if target != session.Key() {
+			continue
+		}
+
+		if err := session.SetVolume(volume); err != nil {
+			m.logger.Warnw("Failed to apply default volume to new session", "session", session.Key(), "error", err)
+		} else {
+			m.logger.Debugw("Applied default volume to new session", "session", session.Key(), "volume", volume)
+		}
+		return
+	}
+}
+
 func (m *sessionMap) applySwitchMuteState(session Session) {
 	count := m.calculateSwitchMuteCount(session)
 	session.SetSwitchMuteCount(count)
@@ -429,19 +658,87 @@ func (m *sessionMap) applySwitchMuteState(session Session) {
 		if err := session.SetMute(true, true); err != nil {
 			m.logger.Warnw("Failed to apply initial mute state for session", "error", err)
 		}
+		return
 	}
+
+	if count == 0 && !session.GetMute() && m.pttMutesSession(session) {
+		if err := session.SetMute(true, true); err != nil {
+			m.logger.Warnw("Failed to apply push-to-talk mute state for session", "error", err)
+		}
+		return
+	}
+
+	// a session that shows up while a solo switch is on is muted like the ones that were there already
+	if count == 0 && !session.GetMute() && m.soloMuteNewSession(session) {
+		if err := session.SetMute(true, true); err != nil {
+			m.logger.Warnw("Failed to apply solo mute state for session", "error", err)
+		}
+	}
+}
+
+// pttMutesSession reports whether a released push-to-talk switch targets this session
+func (m *sessionMap) pttMutesSession(session Session) bool {
+	m.pttMutedLock.Lock()
+	defer m.pttMutedLock.Unlock()
+
+	for switchID, muted := range m.pttMuted {
+		if !muted || !m.deej.config.SwitchPTT[switchID] {
+			continue
+		}
+
+		targets, ok := m.switchTargets(switchID)
+		if ok && m.sessionMatchesTargets(session, targets) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// switchTargets returns a switch's targets with mute_groups expanded, so muting one member mutes the whole group
+func (m *sessionMap) switchTargets(switchID int) ([]string, bool) {
+	targets, ok := m.deej.config.SwitchesMapping.get(switchID)
+	if !ok {
+		return nil, false
+	}
+
+	return m.deej.config.ExpandMuteGroups(targets), true
+}
+
+// sessionMatchesTargets reports whether any of the given (unresolved) targets refers to this session
+func (m *sessionMap) sessionMatchesTargets(session Session, targets []string) bool {
+	for _, target := range targets {
+		for _, resolvedTarget := range m.resolveTarget(target) {
+			if util.IsPath(resolvedTarget) {
+				if util.PathMatches(session.ProcessPath(), resolvedTarget) {
+					return true
+				}
+			} else if resolvedTarget == session.Key() {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
 func (m *sessionMap) calculateSwitchMuteCount(session Session) int {
 	count := 0
 
 	m.deej.config.SwitchesMapping.iterate(func(switchID int, targets []string) {
+		targets = m.deej.config.ExpandMuteGroups(targets)
+
+		// push-to-talk and solo switches mute directly, they don't take part in the mute count
+		if m.deej.config.SwitchPTT[switchID] || m.deej.config.SwitchSolo[switchID] {
+			return
+		}
+
 		state, ok := m.deej.GetSwitchState(switchID)
 		if !ok {
 			return
 		}
 
-		if m.deej.config.InvertSwitches {
+		if m.deej.config.SwitchInverted(switchID) {
 			state = !state
 		}
 
@@ -449,19 +746,8 @@ func (m *sessionMap) calculateSwitchMuteCount(session Session) int {
 			return
 		}
 
-		for _, target := range targets {
-			resolvedTargets := m.resolveTarget(target)
-			for _, resolvedTarget := range resolvedTargets {
-				if util.IsPath(resolvedTarget) {
-					if util.PathMatches(session.ProcessPath(), resolvedTarget) {
-						count++
-						return
-					}
-				} else if resolvedTarget == session.Key() {
-					count++
-					return
-				}
-			}
+		if m.sessionMatchesTargets(session, targets) {
+			count++
 		}
 	})
 
@@ -470,7 +756,7 @@ func (m *sessionMap) calculateSwitchMuteCount(session Session) int {
 
 func (m *sessionMap) handleSwitchEvent(event SwitchEvent) {
 
-	if m.lastSessionRefresh.Add(maxTimeBetweenSessionRefreshes).Before(time.Now()) {
+	if m.lastSessionRefresh.Add(m.deej.config.SessionRefreshMaxAge).Before(time.Now()) {
 		m.logger.Debug("Stale session map detected on switch event, refreshing")
 		m.refreshSessions(true)
 	}
@@ -483,11 +769,31 @@ func (m *sessionMap) handleSwitchEvent(event SwitchEvent) {
 	state := event.State
 	prevState := event.PrevState
 
-	if m.deej.config.InvertSwitches {
+	if m.deej.config.SwitchInverted(event.SwitchID) {
 		state = !state
 		prevState = !prevState
 	}
 
+	// solo keeps its targets audible rather than muting them, so mute groups don't apply to it
+	if m.deej.config.SwitchSolo[event.SwitchID] {
+		if !event.HasPrev || state != prevState {
+			m.sendMuteIndicator(event.SwitchID, state)
+			m.handleSoloSwitch(event.SwitchID, targets, state)
+		}
+		return
+	}
+
+	targets = m.deej.config.ExpandMuteGroups(targets)
+
+	if m.deej.config.SwitchPTT[event.SwitchID] {
+		m.handlePTTSwitch(event.SwitchID, targets, state)
+		return
+	}
+
+	if !event.HasPrev || state != prevState {
+		m.sendMuteIndicator(event.SwitchID, state)
+	}
+
 	targetFound := false
 	actionFailed := false
 	appliedSessions := make(map[Session]struct{})
@@ -501,6 +807,16 @@ func (m *sessionMap) handleSwitchEvent(event SwitchEvent) {
 	}
 
 	for _, target := range targets {
+
+		// device switching isn't about sessions at all, handle it on its own
+		if deviceTarget, ok := parseSwitchDeviceTarget(target); ok {
+			targetFound = true
+			if !event.HasPrev || state != prevState {
+				m.switchDefaultDevice(deviceTarget, state)
+			}
+			continue
+		}
+
 		resolvedTargets := m.resolveTarget(target)
 
 		for _, resolvedTarget := range resolvedTargets {
@@ -515,6 +831,9 @@ func (m *sessionMap) handleSwitchEvent(event SwitchEvent) {
 			} else {
 				// Match by process name (?)
 				sessions, ok := m.get(resolvedTarget)
+				if !ok && isDeviceSessionKey(resolvedTarget) {
+					sessions, ok = m.reacquireDeviceSession(resolvedTarget)
+				}
 				if !ok {
 					continue
 				}
@@ -531,8 +850,209 @@ func (m *sessionMap) handleSwitchEvent(event SwitchEvent) {
 	if !targetFound {
 		m.refreshSessions(false)
 	} else if actionFailed {
-		m.refreshSessions(true)
+		m.refreshSessionsAfterFailure()
+	}
+}
+
+// isDeviceSessionKey reports whether a target names a device-level session (master, mic or system)
+// rather than an app's
+func isDeviceSessionKey(key string) bool {
+	return key == masterSessionName || key == inputSessionName || key == systemSessionName
+}
+
+// reacquireDeviceSession is for a switch flipped on master/mic/system while that session is missing,
+// e.g. a mic plugged in after startup. Master and mic exist whenever their device does, so a miss
+// means the session map is outdated
+func (m *sessionMap) reacquireDeviceSession(key string) ([]Session, bool) {
+	// performance: forcing is fine, switches are flipped by hand and this only runs when the session is missing
+	m.refreshSessions(true)
+
+	sessions, ok := m.get(key)
+	if !ok && key != systemSessionName {
+		// system sounds only have a session while one is playing on some setups, so only warn for the others
+		m.logger.Warnw("No audio device found for switch target", "target", key)
+	}
+
+	return sessions, ok
+}
+
+// handlePTTSwitch unmutes a push-to-talk switch's targets while it's held, and mutes them
+// again once it's released, after switch_ptt_release_ms so the end of a word isn't cut off
+func (m *sessionMap) handlePTTSwitch(switchID int, targets []string, held bool) {
+	if timer, ok := m.pttTimers[switchID]; ok {
+		timer.Stop()
+		delete(m.pttTimers, switchID)
+	}
+
+	if held {
+		m.setPTTMute(switchID, targets, false)
+		return
+	}
+
+	delay := m.deej.config.SwitchPTTRelease
+	if delay <= 0 {
+		m.setPTTMute(switchID, targets, true)
+		return
+	}
+
+	// the timer only hands the release back to the switch event goroutine, which does the muting
+	m.pttTimers[switchID] = time.AfterFunc(delay, func() {
+		select {
+		case m.pttReleases <- switchID:
+		default:
+			m.logger.Warnw("Dropped push-to-talk release, mute may be stale", "switch", switchID)
+		}
+	})
+}
+
+// handlePTTRelease mutes a push-to-talk switch's targets once its release delay is over,
+// unless the switch has been pressed again in the meantime
+func (m *sessionMap) handlePTTRelease(switchID int) {
+	delete(m.pttTimers, switchID)
+
+	if !m.deej.config.SwitchPTT[switchID] {
+		return
+	}
+
+	if state, ok := m.deej.GetSwitchState(switchID); ok {
+		if m.deej.config.SwitchInverted(switchID) {
+			state = !state
+		}
+		if state {
+			return
+		}
+	}
+
+	targets, ok := m.switchTargets(switchID)
+	if !ok {
+		return
+	}
+
+	m.setPTTMute(switchID, targets, true)
+}
+
+// setPTTMute mutes or unmutes a push-to-talk switch's targets directly, bypassing the switch mute count
+func (m *sessionMap) setPTTMute(switchID int, targets []string, mute bool) {
+	m.sendMuteIndicator(switchID, mute)
+
+	m.pttMutedLock.Lock()
+	m.pttMuted[switchID] = mute
+	m.pttMutedLock.Unlock()
+
+	targetFound := false
+	actionFailed := false
+
+	apply := func(session Session) {
+		targetFound = true
+		if session.GetMute() == mute {
+			return
+		}
+		if err := session.SetMute(mute, false); err != nil {
+			m.logger.Warnw("Failed to set push-to-talk mute state", "switch", switchID, "mute", mute, "error", err)
+			actionFailed = true
+		}
+	}
+
+	for _, target := range targets {
+		if _, ok := parseSwitchDeviceTarget(target); ok {
+			m.logger.Debugw("Ignoring device switch target on push-to-talk switch", "switch", switchID, "target", target)
+			continue
+		}
+
+		for _, resolvedTarget := range m.resolveTarget(target) {
+			if util.IsPath(resolvedTarget) {
+				m.iterateAllSessions(func(session Session) {
+					if util.PathMatches(session.ProcessPath(), resolvedTarget) {
+						apply(session)
+					}
+				})
+			} else if sessions, ok := m.get(resolvedTarget); ok {
+				for _, session := range sessions {
+					apply(session)
+				}
+			}
+		}
+	}
+
+	if !targetFound {
+		m.refreshSessions(false)
+	} else if actionFailed {
+		m.refreshSessionsAfterFailure()
+	}
+}
+
+// switchDeviceTarget is a parsed "deej.switch_device:<A>|<B>" or "deej.switch_comm_device:<A>|<B>" target
+type switchDeviceTarget struct {
+	devices        [2]string
+	communications bool // only the default communications device is switched
+}
+
+// parseSwitchDeviceTarget splits a device switching target into its two device names
+func parseSwitchDeviceTarget(target string) (switchDeviceTarget, bool) {
+	for _, kind := range []struct {
+		prefix         string
+		communications bool
+	}{
+		{specialTargetSwitchDevicePrefix, false},
+		{specialTargetSwitchCommDevicePrefix, true},
+	} {
+		prefix := specialTargetTransformPrefix + kind.prefix
+		if len(target) < len(prefix) || !strings.EqualFold(target[:len(prefix)], prefix) {
+			continue
+		}
+
+		// device names keep their case here, matching is up to the session finder
+		parts := strings.Split(target[len(prefix):], "|")
+		if len(parts) != 2 {
+			return switchDeviceTarget{}, false
+		}
+
+		devices := [2]string{strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])}
+		if devices[0] == "" || devices[1] == "" {
+			return switchDeviceTarget{}, false
+		}
+
+		return switchDeviceTarget{devices: devices, communications: kind.communications}, true
 	}
+
+	return switchDeviceTarget{}, false
+}
+
+// switchDefaultDevice makes the first device the default output when the switch is off, the second when on
+func (m *sessionMap) switchDefaultDevice(target switchDeviceTarget, state bool) {
+	device := target.devices[0]
+	if state {
+		device = target.devices[1]
+	}
+
+	role := "output"
+	var err error
+
+	if target.communications {
+		role = "communications"
+		setter, ok := m.sessionFinder.(DefaultCommunicationsDeviceSetter)
+		if !ok {
+			m.logger.Warn("Switching the default communications device isn't supported on this platform")
+			return
+		}
+		err = setter.SetDefaultCommunicationsDevice(device)
+	} else {
+		setter, ok := m.sessionFinder.(DefaultDeviceSetter)
+		if !ok {
+			m.logger.Warn("Switching the default output device isn't supported on this platform")
+			return
+		}
+		err = setter.SetDefaultOutputDevice(device)
+	}
+
+	if err != nil {
+		m.logger.Warnw("Failed to switch default device", "role", role, "device", device, "error", err)
+		m.deej.notifier.Notify(fmt.Sprintf("Failed to switch %s device", role),
+			fmt.Sprintf("Couldn't make %s the default %s device. Please check the device name in your config.", device, role))
+		return
+	}
+
+	m.logger.Infow("Switched default device", "role", role, "device", device)
 }
 
 func (m *sessionMap) targetHasSpecialTransform(target string) bool {
@@ -544,6 +1064,11 @@ func (m *sessionMap) resolveTarget(target string) []string {
 	// start by ignoring the case
 	target = strings.ToLower(target)
 
+	// master_device pins "master" to one device, whichever is the default
+	if target == masterSessionName && m.deej.config.MasterDevice != "" {
+		return []string{strings.ToLower(m.deej.config.MasterDevice)}
+	}
+
 	// look for any special targets first, by examining the prefix
 	if m.targetHasSpecialTransform(target) {
 		return m.applyTargetTransform(strings.TrimPrefix(target, specialTargetTransformPrefix))
@@ -554,6 +1079,11 @@ func (m *sessionMap) resolveTarget(target string) []string {
 
 func (m *sessionMap) applyTargetTransform(specialTargetName string) []string {
 
+	// parameterized transformations first
+	if strings.HasPrefix(specialTargetName, specialTargetProcessGroupPrefix) {
+		return m.resolveProcessGroup(strings.TrimPrefix(specialTargetName, specialTargetProcessGroupPrefix))
+	}
+
 	// select the transformation based on its name
 	switch specialTargetName {
 
@@ -574,6 +1104,10 @@ func (m *sessionMap) applyTargetTransform(specialTargetName string) []string {
 		// remove dupes
 		return funk.UniqString(currentWindowProcessNames)
 
+	// get the active window's whole process tree
+	case specialTargetFocusedSession:
+		return m.resolveFocusedSession()
+
 	// get currently unmapped sessions
 	case specialTargetAllUnmapped:
 		targetKeys := make([]string, len(m.unmappedSessions))
@@ -587,6 +1121,56 @@ func (m *sessionMap) applyTargetTransform(specialTargetName string) []string {
 	return nil
 }
 
+// resolveProcessGroup returns the session keys of an executable and all of its descendant processes
+func (m *sessionMap) resolveProcessGroup(rootName string) []string {
+	if rootName == "" {
+		return nil
+	}
+
+	m.processGroupLock.Lock()
+	defer m.processGroupLock.Unlock()
+
+	if entry, ok := m.processGroupCache[rootName]; ok && time.Since(entry.updatedAt) < processGroupCacheDuration {
+		return entry.names
+	}
+
+	names, err := util.GetProcessTreeNames(rootName)
+
+	// same as with the current window - this is the hot path, so just don't match anything
+	if err != nil {
+		m.logger.Debugw("Failed to resolve process group", "root", rootName, "error", err)
+		return nil
+	}
+
+	if m.processGroupCache == nil {
+		m.processGroupCache = make(map[string]processGroupCacheEntry)
+	}
+	m.processGroupCache[rootName] = processGroupCacheEntry{names: names, updatedAt: time.Now()}
+
+	return names
+}
+
+// resolveFocusedSession returns the session keys of the active window's processes and their descendants
+func (m *sessionMap) resolveFocusedSession() []string {
+	m.processGroupLock.Lock()
+	defer m.processGroupLock.Unlock()
+
+	if time.Since(m.focusedSessionCache.updatedAt) < focusedSessionCacheDuration {
+		return m.focusedSessionCache.names
+	}
+
+	names, err := foregroundProcessTreeNames()
+
+	// hot path again, and on linux this always fails - don't match anything
+	if err != nil {
+		return nil
+	}
+
+	m.focusedSessionCache = processGroupCacheEntry{names: names, updatedAt: time.Now()}
+
+	return names
+}
+
 func (m *sessionMap) add(value Session) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -609,6 +1193,35 @@ func (m *sessionMap) get(key string) ([]Session, bool) {
 	return value, ok
 }
 
+// sessionSnapshot is the current volume (percent) and mute state of a mapped session
+type sessionSnapshot struct {
+	key    string
+	volume int
+	muted  bool
+}
+
+// snapshot reads the current volume and mute state of every mapped session.
+// Sessions sharing a key (e.g. several chrome.exe processes) are reported once, from the first of them
+func (m *sessionMap) snapshot() []sessionSnapshot {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	snapshots := make([]sessionSnapshot, 0, len(m.m))
+	for key, sessions := range m.m {
+		if len(sessions) == 0 || !m.sessionMapped(sessions[0]) {
+			continue
+		}
+
+		snapshots = append(snapshots, sessionSnapshot{
+			key:    key,
+			volume: int(math.Round(float64(sessions[0].GetVolume()) * 100)),
+			muted:  sessions[0].GetMute(),
+		})
+	}
+
+	return snapshots
+}
+
 func (m *sessionMap) clear() {
 	m.lock.Lock()
 	defer m.lock.Unlock()
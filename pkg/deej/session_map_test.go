@@ -0,0 +1,864 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/go-ps"
+	"github.com/stalexteam/deej_esp32/pkg/deej/util"
+	"go.uber.org/zap"
+)
+
+// fakeSessionFinder has no sessions and no devices
+type fakeSessionFinder struct{}
+
+func (fakeSessionFinder) GetAllSessions() ([]Session, error)        { return nil, nil }
+func (fakeSessionFinder) GetAllDevices() ([]AudioDeviceInfo, error) { return nil, nil }
+func (fakeSessionFinder) Release() error                            { return nil }
+
+// fakeSession is an audio session that only keeps its state in memory
+type fakeSession struct {
+	key             string
+	volume          float32
+	muted           bool
+	switchMuteCount int
+	err             error // returned by SetVolume and SetMute, if set
+}
+
+func (s *fakeSession) GetVolume() float32                { return s.volume }
+func (s *fakeSession) SetVolume(v float32) error         { s.volume = v; return s.err }
+func (s *fakeSession) GetMute() bool                     { return s.muted }
+func (s *fakeSession) SetMute(v bool, silent bool) error { s.muted = v; return s.err }
+func (s *fakeSession) GetSwitchMuteCount() int           { return s.switchMuteCount }
+func (s *fakeSession) SetSwitchMuteCount(count int)      { s.switchMuteCount = count }
+func (s *fakeSession) AdjustSwitchMuteCount(delta int) int {
+	s.switchMuteCount += delta
+	return s.switchMuteCount
+}
+func (s *fakeSession) Key() string         { return s.key }
+func (s *fakeSession) ProcessPath() string { return "" }
+func (s *fakeSession) Release()            {}
+
+// fakeFilteringFinder creates a session per process, skipping the ones its filter rejects,
+// and counts how many session objects it had to create and how often it was asked for them
+type fakeFilteringFinder struct {
+	fakeSessionFinder
+
+	processes  []string
+	sessionErr error // every session fails with this, if set
+	filter     func(processName string) bool
+	created    int
+	refreshes  int
+}
+
+func (f *fakeFilteringFinder) SetSessionFilter(keep func(processName string) bool) { f.filter = keep }
+
+func (f *fakeFilteringFinder) GetAllSessions() ([]Session, error) {
+	f.refreshes++
+
+	sessions := []Session{}
+	for _, process := range f.processes {
+		if f.filter != nil && !f.filter(process) {
+			continue
+		}
+		f.created++
+		sessions = append(sessions, &fakeSession{key: process, volume: 1, err: f.sessionErr})
+	}
+	return sessions, nil
+}
+
+// fakeDeviceSwitcher records which devices were made the default for each role, failing for unknown ones
+type fakeDeviceSwitcher struct {
+	fakeSessionFinder
+
+	known        []string
+	defaults     []string
+	commDefaults []string
+}
+
+func (f *fakeDeviceSwitcher) SetDefaultOutputDevice(name string) error {
+	return f.setDefault(&f.defaults, name)
+}
+
+func (f *fakeDeviceSwitcher) SetDefaultCommunicationsDevice(name string) error {
+	return f.setDefault(&f.commDefaults, name)
+}
+
+func (f *fakeDeviceSwitcher) setDefault(defaults *[]string, name string) error {
+	for _, device := range f.known {
+		if strings.EqualFold(device, name) {
+			*defaults = append(*defaults, device)
+			return nil
+		}
+	}
+
+	return errors.New("no such device")
+}
+
+func newTestSessionMap(t *testing.T, finder SessionFinder) (*sessionMap, *recordingNotifier) {
+	return newTestSessionMapWithConfig(t, finder, "")
+}
+
+func newTestSessionMapWithConfig(t *testing.T, finder SessionFinder, userYAML string) (*sessionMap, *recordingNotifier) {
+	t.Helper()
+
+	d := newTestDeej(t, userYAML)
+	notifier := &recordingNotifier{}
+	d.notifier = notifier
+
+	m, err := newSessionMap(d, zap.NewNop().Sugar(), finder)
+	if err != nil {
+		t.Fatalf("create session map: %v", err)
+	}
+
+	return m, notifier
+}
+
+func TestParseSwitchDeviceTarget(t *testing.T) {
+	tests := []struct {
+		target   string
+		want     [2]string
+		wantComm bool
+		wantOk   bool
+	}{
+		{"deej.switch_device:Speakers|Headphones", [2]string{"Speakers", "Headphones"}, false, true},
+		{"DEEJ.Switch_Device: Speakers | USB Headset ", [2]string{"Speakers", "USB Headset"}, false, true},
+		{"deej.switch_comm_device:Speakers|Headset", [2]string{"Speakers", "Headset"}, true, true},
+		{"deej.Switch_Comm_Device: Speakers | USB Headset", [2]string{"Speakers", "USB Headset"}, true, true},
+		{"deej.switch_comm_device:Headset", [2]string{}, false, false},
+		{"deej.switch_device:Speakers", [2]string{}, false, false},
+		{"deej.switch_device:Speakers|", [2]string{}, false, false},
+		{"deej.switch_device:A|B|C", [2]string{}, false, false},
+		{"deej.current", [2]string{}, false, false},
+		{"spotify.exe", [2]string{}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got, ok := parseSwitchDeviceTarget(tt.target)
+			if ok != tt.wantOk || got.devices != tt.want || got.communications != tt.wantComm {
+				t.Errorf("got %+v, %v, want %q communications %v, %v", got, ok, tt.want, tt.wantComm, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestSwitchDefaultDevice(t *testing.T) {
+	target := switchDeviceTarget{devices: [2]string{"Speakers", "Headphones"}}
+	commTarget := switchDeviceTarget{devices: target.devices, communications: true}
+
+	tests := []struct {
+		name             string
+		target           switchDeviceTarget
+		known            []string
+		state            bool
+		wantDefaults     []string
+		wantCommDefaults []string
+		wantNotified     bool
+	}{
+		{"off selects the first device", target, []string{"speakers", "headphones"}, false, []string{"speakers"}, nil, false},
+		{"on selects the second device", target, []string{"speakers", "headphones"}, true, []string{"headphones"}, nil, false},
+		{"unknown device notifies", target, []string{"speakers"}, true, nil, nil, true},
+		{"communications off selects the first device", commTarget, []string{"speakers", "headphones"}, false, nil, []string{"speakers"}, false},
+		{"communications on selects the second device", commTarget, []string{"speakers", "headphones"}, true, nil, []string{"headphones"}, false},
+		{"unknown communications device notifies", commTarget, []string{"speakers"}, true, nil, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			switcher := &fakeDeviceSwitcher{known: tt.known}
+			m, notifier := newTestSessionMap(t, switcher)
+
+			m.switchDefaultDevice(tt.target, tt.state)
+
+			if strings.Join(switcher.defaults, ",") != strings.Join(tt.wantDefaults, ",") {
+				t.Errorf("defaults: got %q, want %q", switcher.defaults, tt.wantDefaults)
+			}
+			if strings.Join(switcher.commDefaults, ",") != strings.Join(tt.wantCommDefaults, ",") {
+				t.Errorf("communications defaults: got %q, want %q", switcher.commDefaults, tt.wantCommDefaults)
+			}
+			if notified := len(notifier.Titles()) > 0; notified != tt.wantNotified {
+				t.Errorf("notified: got %v, want %v", notified, tt.wantNotified)
+			}
+		})
+	}
+
+	// a platform that can't switch devices leaves everything alone
+	m, notifier := newTestSessionMap(t, fakeSessionFinder{})
+	m.switchDefaultDevice(target, true)
+	m.switchDefaultDevice(commTarget, true)
+	if len(notifier.Titles()) != 0 {
+		t.Errorf("unsupported platform notified %q", notifier.Titles())
+	}
+}
+
+func TestSessionFilter(t *testing.T) {
+	processes := []string{"spotify.exe", "discord.exe", "chrome.exe", "steam.exe", "explorer.exe"}
+
+	tests := []struct {
+		name        string
+		yaml        string
+		wantCreated int
+	}{
+		{"filter off", "slider_mapping:\n  0: spotify.exe\n", 5},
+		{"filter on", "session_filter: true\nslider_mapping:\n  0: Spotify.exe\n  1: [discord.exe, master]\n", 2},
+		{"switch targets count too", "session_filter: true\nslider_mapping:\n  0: spotify.exe\nswitches_mapping:\n  0: steam.exe\n", 2},
+		{"special target needs every session", "session_filter: true\nslider_mapping:\n  0: spotify.exe\n  1: deej.current\n", 5},
+		{"path target needs every session", "session_filter: true\nslider_mapping:\n  0: 'C:\\Games\\'\n", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeFilteringFinder{processes: processes}
+			m, _ := newTestSessionMapWithConfig(t, finder, tt.yaml)
+
+			if err := m.getAndAddSessions(); err != nil {
+				t.Fatalf("getAndAddSessions: %v", err)
+			}
+
+			if finder.created != tt.wantCreated {
+				t.Errorf("created %d sessions, want %d", finder.created, tt.wantCreated)
+			}
+		})
+	}
+}
+
+func TestFailingSessionRefreshes(t *testing.T) {
+	tests := []struct {
+		name          string
+		sessionErr    error
+		wantRefreshes int
+	}{
+		// the stale map refresh only: a session that stays gone is held to the same rate as any other failure
+		{"session gone", errRefreshSessions, 1},
+		// the stale map refresh only: the rest of the failures come too soon after it
+		{"session failing", errors.New("audio engine restarting"), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeFilteringFinder{processes: []string{"spotify.exe"}, sessionErr: tt.sessionErr}
+			m, _ := newTestSessionMapWithConfig(t, finder, "slider_mapping:\n  0: spotify.exe\n")
+
+			for i := 0; i < 10; i++ {
+				m.handleSliderMoveEvent(SliderMoveEvent{SliderID: 0, PercentValue: float32(i) / 10})
+			}
+
+			if finder.refreshes != tt.wantRefreshes {
+				t.Errorf("got %d refreshes for 10 moves, want %d", finder.refreshes, tt.wantRefreshes)
+			}
+		})
+	}
+}
+
+func TestFailureRefreshRate(t *testing.T) {
+	tests := []struct {
+		name          string
+		yaml          string
+		sinceRefresh  time.Duration
+		wantRefreshes int
+	}{
+		{"default rate throttles", "", 100 * time.Millisecond, 0},
+		{"default rate allows after a second", "", 2 * time.Second, 10},
+		{"higher rate allows", "session_refresh_max_rate: 20\n", 100 * time.Millisecond, 10},
+		{"higher rate still throttles", "session_refresh_max_rate: 20\n", 10 * time.Millisecond, 0},
+	}
+
+	// a session that stays gone is held to the same rate as any other failing one, its retry included
+	sessionErrs := []error{errors.New("audio engine restarting"), errRefreshSessions}
+
+	for _, tt := range tests {
+		for _, sessionErr := range sessionErrs {
+			t.Run(tt.name+"/"+sessionErr.Error(), func(t *testing.T) {
+				finder := &fakeFilteringFinder{processes: []string{"spotify.exe"}, sessionErr: sessionErr}
+				m, _ := newTestSessionMapWithConfig(t, finder, "slider_mapping:\n  0: spotify.exe\n"+tt.yaml)
+
+				m.refreshSessions(true)
+				finder.refreshes = 0
+
+				for i := 0; i < 10; i++ {
+					m.lastSessionRefresh = time.Now().Add(-tt.sinceRefresh)
+					m.handleSliderMoveEvent(SliderMoveEvent{SliderID: 0, PercentValue: float32(i) / 10})
+				}
+
+				if finder.refreshes != tt.wantRefreshes {
+					t.Errorf("got %d forced refreshes for 10 failed moves, want %d", finder.refreshes, tt.wantRefreshes)
+				}
+			})
+		}
+	}
+}
+
+func TestSliderWeightsCrossfade(t *testing.T) {
+	yaml := `slider_mapping:
+  2: [discord.exe, game.exe, music.exe, spotify.exe]
+slider_weights:
+  2:
+    Discord.exe: 1
+    game.exe: -1
+    music.exe: 0.5
+    spotify.exe: 3
+`
+
+	tests := []struct {
+		percent float32
+		want    map[string]float32
+	}{
+		{0, map[string]float32{"discord.exe": 0, "game.exe": 1, "music.exe": 0, "spotify.exe": 0}},
+		{0.25, map[string]float32{"discord.exe": 0.25, "game.exe": 0.75, "music.exe": 0.125, "spotify.exe": 0.25}},
+		{1, map[string]float32{"discord.exe": 1, "game.exe": 0, "music.exe": 0.5, "spotify.exe": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.percent), func(t *testing.T) {
+			finder := &fakeFilteringFinder{processes: []string{"discord.exe", "game.exe", "music.exe", "spotify.exe"}}
+			m, _ := newTestSessionMapWithConfig(t, finder, yaml)
+
+			m.handleSliderMoveEvent(SliderMoveEvent{SliderID: 2, PercentValue: tt.percent})
+
+			// spotify.exe's weight is out of range, so it's ignored and the target follows the slider
+			for key, want := range tt.want {
+				sessions, _ := m.get(key)
+				if got := sessions[0].GetVolume(); math.Abs(float64(got-want)) > 1e-6 {
+					t.Errorf("%s: got volume %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMuteIndicator(t *testing.T) {
+	yaml := `switches_mapping:
+  0: spotify.exe
+  1: discord.exe
+mute_indicator:
+  0:
+    muted: '{"id":"light-rgb","r":255}'
+    unmuted: '{"id":"light-rgb","g":255}'
+`
+
+	finder := &fakeFilteringFinder{processes: []string{"spotify.exe", "discord.exe"}}
+	m, _ := newTestSessionMapWithConfig(t, finder, yaml)
+	device := &fakeIO{connected: true}
+	m.deej.io = device
+
+	events := []SwitchEvent{
+		{SwitchID: 0, State: true},
+		{SwitchID: 0, State: true, PrevState: true, HasPrev: true},
+		{SwitchID: 1, State: true},
+		{SwitchID: 0, State: false, PrevState: true, HasPrev: true},
+	}
+	for _, event := range events {
+		m.handleSwitchEvent(event)
+	}
+
+	want := []string{`{"id":"light-rgb","r":255}`, `{"id":"light-rgb","g":255}`}
+	if strings.Join(device.written, "\n") != strings.Join(want, "\n") {
+		t.Errorf("device got %q, want %q", device.written, want)
+	}
+}
+
+func TestPushToTalk(t *testing.T) {
+	tests := []struct {
+		name        string
+		releaseMs   int
+		states      []bool // switch states sent in order
+		wantRelease bool   // whether a delayed release should come back to the switch goroutine
+		wantMuted   bool   // mic mute state once everything settled
+	}{
+		{"held", 50, []bool{true}, false, false},
+		{"released after delay", 50, []bool{true, false}, true, true},
+		{"pressed again within delay", 50, []bool{true, false, true}, false, false},
+		{"released without delay", 0, []bool{true, false}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yaml := fmt.Sprintf("switches_mapping:\n  0: mic\nswitch_ptt: [0]\nswitch_ptt_release_ms: %d\n", tt.releaseMs)
+			finder := &fakeFilteringFinder{processes: []string{"mic"}}
+			m, _ := newTestSessionMapWithConfig(t, finder, yaml)
+			m.refreshSessions(true)
+
+			mic, _ := m.get(inputSessionName)
+			mic[0].SetMute(true, false)
+
+			var lastEvent time.Time
+			for i, state := range tt.states {
+				event := SwitchEvent{SwitchID: 0, State: state}
+				if i > 0 {
+					event.PrevState, event.HasPrev = tt.states[i-1], true
+				}
+				m.handleSwitchEvent(event)
+				lastEvent = time.Now()
+			}
+
+			if tt.wantRelease {
+				if mic[0].GetMute() {
+					t.Fatal("mic muted before the release delay was over")
+				}
+
+				select {
+				case switchID := <-m.pttReleases:
+					if waited := time.Since(lastEvent); waited < time.Duration(tt.releaseMs)*time.Millisecond {
+						t.Errorf("release came back after %v, want at least %dms", waited, tt.releaseMs)
+					}
+					m.handlePTTRelease(switchID)
+				case <-time.After(time.Second):
+					t.Fatal("release never came back")
+				}
+			} else {
+				select {
+				case switchID := <-m.pttReleases:
+					t.Fatalf("unexpected release for switch %d", switchID)
+				case <-time.After(time.Duration(tt.releaseMs)*time.Millisecond + 50*time.Millisecond):
+				}
+			}
+
+			if got := mic[0].GetMute(); got != tt.wantMuted {
+				t.Errorf("mic muted = %v, want %v", got, tt.wantMuted)
+			}
+			if got := mic[0].GetSwitchMuteCount(); got != 0 {
+				t.Errorf("switch mute count = %d, want push-to-talk to leave it alone", got)
+			}
+		})
+	}
+}
+
+func TestPushToTalkReacquiredSessions(t *testing.T) {
+	yaml := "switches_mapping:\n  0: [mic, discord.exe]\nswitch_ptt: [0]\nswitch_ptt_release_ms: 0\n"
+
+	tests := []struct {
+		name      string
+		states    []bool
+		wantMuted map[string]bool
+	}{
+		{"released switch mutes new sessions", []bool{true, false}, map[string]bool{"mic": true, "discord.exe": true, "spotify.exe": false}},
+		{"held switch leaves new sessions alone", []bool{true}, map[string]bool{"mic": false, "discord.exe": false, "spotify.exe": false}},
+		{"released then held again", []bool{true, false, true}, map[string]bool{"mic": false, "discord.exe": false, "spotify.exe": false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeFilteringFinder{processes: []string{"mic", "discord.exe", "spotify.exe"}}
+			m, _ := newTestSessionMapWithConfig(t, finder, yaml)
+			m.refreshSessions(true)
+
+			for i, state := range tt.states {
+				event := SwitchEvent{SwitchID: 0, State: state}
+				if i > 0 {
+					event.PrevState, event.HasPrev = tt.states[i-1], true
+				}
+				m.handleSwitchEvent(event)
+			}
+
+			// the finder hands out fresh, unmuted session objects, as if the apps had restarted
+			m.refreshSessions(true)
+
+			for key, want := range tt.wantMuted {
+				sessions, ok := m.get(key)
+				if !ok {
+					t.Fatalf("no %s session after the refresh", key)
+				}
+				if got := sessions[0].GetMute(); got != want {
+					t.Errorf("%s muted = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeviceSessionSwitches(t *testing.T) {
+	yaml := "switches_mapping:\n  0: mic\n  1: master\n  2: [mic, system]\n"
+
+	type flip struct {
+		switchID int
+		state    bool
+	}
+
+	tests := []struct {
+		name      string
+		plugged   []string // sessions only there from the first switch event on, e.g. a mic plugged in late
+		flips     []flip
+		wantMuted map[string]bool
+	}{
+		{"mic switch mutes the mic", nil, []flip{{0, true}}, map[string]bool{"mic": true, "master": false}},
+		{"mic switch unmutes again", nil, []flip{{0, true}, {0, false}}, map[string]bool{"mic": false}},
+		{"master switch mutes master", nil, []flip{{1, true}}, map[string]bool{"master": true, "mic": false}},
+		{"mic stays muted while another switch holds it", nil, []flip{{0, true}, {2, true}, {0, false}}, map[string]bool{"mic": true, "system": true}},
+		{"mic plugged in after startup", []string{"mic"}, []flip{{0, true}}, map[string]bool{"mic": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeFilteringFinder{processes: []string{"master", "system", "mic"}}
+			if len(tt.plugged) > 0 {
+				finder.processes = []string{"master", "system"}
+			}
+			m, _ := newTestSessionMapWithConfig(t, finder, yaml)
+			m.refreshSessions(true)
+			finder.processes = append(finder.processes, tt.plugged...)
+
+			previous := map[int]bool{}
+			for _, f := range tt.flips {
+				prev, hasPrev := previous[f.switchID]
+				previous[f.switchID] = f.state
+
+				// handleSwitchState records the state before the event goes out
+				m.deej.switchStateByID[f.switchID] = f.state
+				m.handleSwitchEvent(SwitchEvent{SwitchID: f.switchID, State: f.state, PrevState: prev, HasPrev: hasPrev})
+			}
+
+			for key, want := range tt.wantMuted {
+				sessions, ok := m.get(key)
+				if !ok {
+					t.Fatalf("no %s session", key)
+				}
+				if got := sessions[0].GetMute(); got != want {
+					t.Errorf("%s muted = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSoloSwitch(t *testing.T) {
+	yaml := "switches_mapping:\n  0: discord.exe\nswitch_solo: [0]\n"
+
+	tests := []struct {
+		name      string
+		states    []bool
+		wantMuted map[string]bool
+	}{
+		{"engaged mutes everything but the target", []bool{true},
+			map[string]bool{"discord.exe": false, "spotify.exe": true, "chrome.exe": true, "master": false, "mic": false}},
+		{"disengaged restores the prior state", []bool{true, false},
+			map[string]bool{"discord.exe": false, "spotify.exe": false, "chrome.exe": true, "master": false, "mic": false}},
+		{"engaged again after restoring", []bool{true, false, true},
+			map[string]bool{"discord.exe": false, "spotify.exe": true, "chrome.exe": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeFilteringFinder{processes: []string{"master", "mic", "discord.exe", "spotify.exe", "chrome.exe"}}
+			m, _ := newTestSessionMapWithConfig(t, finder, yaml)
+			m.refreshSessions(true)
+
+			// muted by hand before solo was engaged, so it has to stay muted afterwards
+			chrome, _ := m.get("chrome.exe")
+			chrome[0].SetMute(true, false)
+
+			for i, state := range tt.states {
+				event := SwitchEvent{SwitchID: 0, State: state}
+				if i > 0 {
+					event.PrevState, event.HasPrev = tt.states[i-1], true
+				}
+				m.deej.switchStateByID[0] = state
+				m.handleSwitchEvent(event)
+			}
+
+			for key, want := range tt.wantMuted {
+				sessions, ok := m.get(key)
+				if !ok {
+					t.Fatalf("no %s session", key)
+				}
+				if got := sessions[0].GetMute(); got != want {
+					t.Errorf("%s muted = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+// fakeTreeProcess is a ps.Process with a parent, for building a window's process tree
+type fakeTreeProcess struct {
+	pid, ppid int
+	name      string
+}
+
+func (p fakeTreeProcess) Pid() int           { return p.pid }
+func (p fakeTreeProcess) PPid() int          { return p.ppid }
+func (p fakeTreeProcess) Executable() string { return p.name }
+
+func TestFocusedSession(t *testing.T) {
+	yaml := "slider_mapping:\n  0: deej.focused_session\n"
+
+	// a browser whose audio is played by a helper it spawned, next to unrelated apps
+	processes := []ps.Process{
+		fakeTreeProcess{1, 0, "explorer.exe"},
+		fakeTreeProcess{100, 1, "browser.exe"},
+		fakeTreeProcess{101, 100, "audio_helper.exe"},
+		fakeTreeProcess{200, 1, "spotify.exe"},
+		fakeTreeProcess{300, 1, "game.exe"},
+		fakeTreeProcess{301, 300, "game_launcher.exe"},
+	}
+
+	tests := []struct {
+		name       string
+		windowPIDs []int // processes owning the foreground window
+		lookupErr  error
+		wantMoved  []string // sessions the slider should reach
+	}{
+		{"helper holds the window's session", []int{100}, nil, []string{"audio_helper.exe"}},
+		{"window process holds the session itself", []int{200}, nil, []string{"spotify.exe"}},
+		{"child of the window process", []int{300}, nil, []string{"game.exe"}},
+		{"no foreground window", nil, nil, nil},
+		{"lookup fails", []int{100}, errors.New("not implemented"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := foregroundProcessTreeNames
+			foregroundProcessTreeNames = func() ([]string, error) {
+				if tt.lookupErr != nil {
+					return nil, tt.lookupErr
+				}
+				return util.ProcessTreeNamesByPID(processes, tt.windowPIDs), nil
+			}
+			t.Cleanup(func() { foregroundProcessTreeNames = original })
+
+			finder := &fakeFilteringFinder{processes: []string{"audio_helper.exe", "spotify.exe", "game.exe"}}
+			m, _ := newTestSessionMapWithConfig(t, finder, yaml)
+
+			m.handleSliderMoveEvent(SliderMoveEvent{SliderID: 0, PercentValue: 0.3})
+
+			for _, key := range finder.processes {
+				sessions, _ := m.get(key)
+				want := float32(1)
+				if slices.Contains(tt.wantMoved, key) {
+					want = 0.3
+				}
+				if got := sessions[0].GetVolume(); math.Abs(float64(got-want)) > 1e-6 {
+					t.Errorf("%s: got volume %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUnmappedIncludes(t *testing.T) {
+	sessions := []string{"master", "system", "mic", "Speakers (Realtek Audio)", "discord.exe", "spotify.exe"}
+
+	tests := []struct {
+		name      string
+		includes  string
+		wantMoved []string // sessions deej.unmapped should reach
+	}{
+		{"default skips device sessions", "[]", []string{"spotify.exe"}},
+		{"master included", "[master]", []string{"master", "spotify.exe"}},
+		{"system and mic included", "[system, mic]", []string{"system", "mic", "spotify.exe"}},
+		{"devices included", "[devices]", []string{"Speakers (Realtek Audio)", "spotify.exe"}},
+		{"unknown class is ignored", "[speakers]", []string{"spotify.exe"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yaml := "slider_mapping:\n  0: deej.unmapped\n  1: discord.exe\nunmapped_includes: " + tt.includes + "\n"
+			finder := &fakeFilteringFinder{processes: sessions}
+			m, _ := newTestSessionMapWithConfig(t, finder, yaml)
+			m.refreshSessions(true)
+
+			m.handleSliderMoveEvent(SliderMoveEvent{SliderID: 0, PercentValue: 0.3})
+
+			for _, key := range sessions {
+				found, ok := m.get(key)
+				if !ok {
+					t.Fatalf("no %s session", key)
+				}
+				want := float32(1)
+				if slices.Contains(tt.wantMoved, key) {
+					want = 0.3
+				}
+				if got := found[0].GetVolume(); math.Abs(float64(got-want)) > 1e-6 {
+					t.Errorf("%s: got volume %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTargetDefaultVolume(t *testing.T) {
+	yaml := "target_default_volume:\n  discord.exe: 40\n"
+
+	tests := []struct {
+		name      string
+		refreshes [][]string // the processes with a session on each refresh
+		want      map[string]float32
+	}{
+		// the fake finder hands out sessions at full volume, which stands for the level the user left them at
+		{"running at startup is left alone", [][]string{{"discord.exe", "spotify.exe"}}, map[string]float32{"discord.exe": 1, "spotify.exe": 1}},
+		{"launched later", [][]string{{"spotify.exe"}, {"discord.exe", "spotify.exe"}}, map[string]float32{"discord.exe": 0.4}},
+		{"launched after an empty startup", [][]string{{}, {"discord.exe"}}, map[string]float32{"discord.exe": 0.4}},
+		// the fake finder hands out fresh sessions at full volume, so a re-applied default would show
+		{"not re-applied to a known session", [][]string{{"discord.exe"}, {"discord.exe"}}, map[string]float32{"discord.exe": 1}},
+		{"re-applied after a restart", [][]string{{"discord.exe"}, {}, {"discord.exe"}}, map[string]float32{"discord.exe": 0.4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeFilteringFinder{}
+			m, _ := newTestSessionMapWithConfig(t, finder, yaml)
+
+			for _, processes := range tt.refreshes {
+				finder.processes = processes
+				m.refreshSessions(true)
+			}
+
+			for key, want := range tt.want {
+				sessions, ok := m.get(key)
+				if !ok {
+					t.Fatalf("no %s session", key)
+				}
+				if got := sessions[0].GetVolume(); math.Abs(float64(got-want)) > 1e-6 {
+					t.Errorf("%s: got volume %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMuteGroups(t *testing.T) {
+	yaml := "switches_mapping:\n  0: discord.exe\n  1: teamspeak.exe\n  2: mic\nmute_groups:\n  voice: [discord.exe, teamspeak.exe, mic]\n"
+
+	type flip struct {
+		switchID int
+		state    bool
+	}
+
+	tests := []struct {
+		name      string
+		flips     []flip
+		wantMuted map[string]bool
+	}{
+		{"muting one member mutes the group", []flip{{0, true}},
+			map[string]bool{"discord.exe": true, "teamspeak.exe": true, "mic": true, "spotify.exe": false}},
+		{"group unmutes together", []flip{{0, true}, {0, false}},
+			map[string]bool{"discord.exe": false, "teamspeak.exe": false, "mic": false}},
+		{"group stays muted while another member's switch holds it", []flip{{0, true}, {1, true}, {0, false}},
+			map[string]bool{"discord.exe": true, "teamspeak.exe": true, "mic": true}},
+		{"no double count once both are released", []flip{{0, true}, {2, true}, {0, false}, {2, false}},
+			map[string]bool{"discord.exe": false, "teamspeak.exe": false, "mic": false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeFilteringFinder{processes: []string{"mic", "discord.exe", "teamspeak.exe", "spotify.exe"}}
+			m, _ := newTestSessionMapWithConfig(t, finder, yaml)
+			m.refreshSessions(true)
+
+			previous := map[int]bool{}
+			for _, f := range tt.flips {
+				prev, hasPrev := previous[f.switchID]
+				previous[f.switchID] = f.state
+
+				m.deej.switchStateByID[f.switchID] = f.state
+				m.handleSwitchEvent(SwitchEvent{SwitchID: f.switchID, State: f.state, PrevState: prev, HasPrev: hasPrev})
+			}
+
+			for key, want := range tt.wantMuted {
+				sessions, ok := m.get(key)
+				if !ok {
+					t.Fatalf("no %s session", key)
+				}
+				if got := sessions[0].GetMute(); got != want {
+					t.Errorf("%s muted = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMasterDevice(t *testing.T) {
+	// device sessions are keyed by their lowercased friendly name, as the Windows session finder does
+	sessions := []string{"master", "speakers (realtek audio)", "headphones (usb audio)"}
+
+	tests := []struct {
+		name      string
+		yaml      string
+		wantMoved string
+	}{
+		{"default device without master_device", "", "master"},
+		{"pinned to the named device", "master_device: \"Headphones (USB Audio)\"\n", "headphones (usb audio)"},
+		{"blank name follows the default device", "master_device: \"  \"\n", "master"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			yaml := "slider_mapping:\n  0: master\nswitches_mapping:\n  0: master\n" + tt.yaml
+			finder := &fakeFilteringFinder{processes: sessions}
+			m, _ := newTestSessionMapWithConfig(t, finder, yaml)
+			m.refreshSessions(true)
+
+			m.handleSliderMoveEvent(SliderMoveEvent{SliderID: 0, PercentValue: 0.3})
+			m.deej.switchStateByID[0] = true
+			m.handleSwitchEvent(SwitchEvent{SwitchID: 0, State: true})
+
+			for _, key := range sessions {
+				found, ok := m.get(key)
+				if !ok {
+					t.Fatalf("no %s session", key)
+				}
+
+				wantVolume, wantMuted := float32(1), false
+				if key == tt.wantMoved {
+					wantVolume, wantMuted = 0.3, true
+				}
+				if got := found[0].GetVolume(); math.Abs(float64(got-wantVolume)) > 1e-6 {
+					t.Errorf("%s: got volume %v, want %v", key, got, wantVolume)
+				}
+				if got := found[0].GetMute(); got != wantMuted {
+					t.Errorf("%s: muted = %v, want %v", key, got, wantMuted)
+				}
+			}
+		})
+	}
+}
+
+func TestGoneSessionRetry(t *testing.T) {
+	tests := []struct {
+		name          string
+		sinceRefresh  time.Duration // how long before the move the sessions were last refreshed
+		err           error         // what the sessions in the map fail with
+		freshErr      error         // what the sessions picked up by a refresh fail with
+		wantRefreshes int
+		wantVolume    float32 // of the session in the map afterwards, a fresh one after a refresh
+	}{
+		{"gone, picked up again", 2 * time.Second, errRefreshSessions, nil, 1, 0.3},
+		{"still gone after the refresh", 2 * time.Second, errRefreshSessions, errRefreshSessions, 1, 0.3},
+		{"gone right after a refresh waits", 0, errRefreshSessions, nil, 0, 0.3},
+		{"other failure isn't retried", 2 * time.Second, errors.New("device busy"), nil, 1, 1},
+		{"other failure right after a refresh waits", 0, errors.New("device busy"), nil, 0, 0.3},
+		{"no failure", 0, nil, nil, 0, 0.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeFilteringFinder{processes: []string{"spotify.exe"}, sessionErr: tt.err}
+			m, _ := newTestSessionMapWithConfig(t, finder, "slider_mapping:\n  0: spotify.exe\n")
+			m.refreshSessions(true)
+
+			// the retry waits for the failure refresh, which session_refresh_max_rate holds back right after a refresh
+			m.lastSessionRefresh = time.Now().Add(-tt.sinceRefresh)
+			finder.sessionErr = tt.freshErr
+			refreshes := finder.refreshes
+
+			m.handleSliderMoveEvent(SliderMoveEvent{SliderID: 0, PercentValue: 0.3})
+
+			if got := finder.refreshes - refreshes; got != tt.wantRefreshes {
+				t.Errorf("refreshed %d times, want %d", got, tt.wantRefreshes)
+			}
+
+			sessions, ok := m.get("spotify.exe")
+			if !ok {
+				t.Fatal("no spotify.exe session")
+			}
+			if got := sessions[0].GetVolume(); math.Abs(float64(got-tt.wantVolume)) > 1e-6 {
+				t.Errorf("got volume %v, want %v", got, tt.wantVolume)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package deej
+
+import (
+	"testing"
+)
+
+func TestSessionDescribe(t *testing.T) {
+	tests := []struct {
+		name        string
+		volume      float32
+		muted       bool
+		switchMutes int
+		want        string
+	}{
+		{"playing", 0.5, false, 0, "<session: spotify.exe (pid 42), vol: 0.50, muted: false, switch mutes: 0>"},
+		{"muted by hand", 1, true, 0, "<session: spotify.exe (pid 42), vol: 1.00, muted: true, switch mutes: 0>"},
+		{"held muted by switches", 0.25, true, 2, "<session: spotify.exe (pid 42), vol: 0.25, muted: true, switch mutes: 2>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &baseSession{name: "spotify.exe", humanReadableDesc: "spotify.exe (pid 42)"}
+			s.SetSwitchMuteCount(tt.switchMutes)
+
+			if got := s.describe(tt.volume, tt.muted); got != tt.want {
+				t.Errorf("describe() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 
 	ole "github.com/go-ole/go-ole"
 	ps "github.com/mitchellh/go-ps"
@@ -13,7 +14,6 @@ import (
 )
 
 var errNoSuchProcess = errors.New("no such process")
-var errRefreshSessions = errors.New("trigger session refresh")
 
 type wcaSession struct {
 	baseSession
@@ -35,7 +35,7 @@ type masterSession struct {
 
 	eventCtx *ole.GUID
 
-	stale bool // when set to true, we should refresh sessions on the next call to SetVolume
+	stale atomic.Bool // when set to true, we should refresh sessions on the next call to SetVolume. set from the device notification callback
 }
 
 func newWCASession(
@@ -95,7 +95,8 @@ func newWCASession(
 // which can be slow or blocked by anti-cheat software.
 // On cache miss it calls GetProcessPath and stores the result.
 // Errors are logged at Warn level for access-denied (anti-cheat indicator)
-// and Debug level for all other failures.
+// and Debug level for all other failures. Access denied won't change for the process's
+// lifetime, so it's cached as an empty path and only warned about once per PID.
 func resolveProcessPath(logger *zap.SugaredLogger, pid int) string {
 	if cached, ok := util.GlobalProcessPathCache.GetCached(pid); ok {
 		return cached
@@ -109,6 +110,7 @@ func resolveProcessPath(logger *zap.SugaredLogger, pid int) string {
 			logger.Warnw("Access denied getting process path — process may be protected by anti-cheat or run as admin; path-based matching will not work for this process",
 				"pid", pid,
 				"error", err)
+			util.GlobalProcessPathCache.Set(pid, "")
 		} else {
 			logger.Debugw("Failed to get process path, will use process name only", "pid", pid, "error", err)
 		}
@@ -208,7 +210,7 @@ func (s *wcaSession) ProcessPath() string {
 }
 
 func (s *wcaSession) String() string {
-	return fmt.Sprintf(sessionStringFormat, s.humanReadableDesc, s.GetVolume())
+	return s.describe(s.GetVolume(), s.GetMute())
 }
 
 func (s *masterSession) GetVolume() float32 {
@@ -222,7 +224,7 @@ func (s *masterSession) GetVolume() float32 {
 }
 
 func (s *masterSession) SetVolume(v float32) error {
-	if s.stale {
+	if s.stale.Load() {
 		s.logger.Warnw("Session expired because default device has changed, triggering session refresh")
 		return errRefreshSessions
 	}
@@ -250,7 +252,7 @@ func (s *masterSession) GetMute() bool {
 }
 
 func (s *masterSession) SetMute(v bool, silent bool) error {
-	if s.stale {
+	if s.stale.Load() {
 		s.logger.Warnw("Session expired because default device has changed, triggering session refresh")
 		return errRefreshSessions
 	}
@@ -277,9 +279,9 @@ func (s *masterSession) ProcessPath() string {
 }
 
 func (s *masterSession) String() string {
-	return fmt.Sprintf(sessionStringFormat, s.humanReadableDesc, s.GetVolume())
+	return s.describe(s.GetVolume(), s.GetMute())
 }
 
 func (s *masterSession) markAsStale() {
-	s.stale = true
+	s.stale.Store(true)
 }
@@ -0,0 +1,61 @@
+package deej
+
+// combineFineSlider applies slider_fine to a slider move. A move of a fine slider turns into a move of
+// its coarse slider, at the coarse position plus the fine offset; a move of a coarse slider gets the
+// offset of its fine slider added. Until the fine slider reports, the coarse slider works alone, and
+// until the coarse one reports there's nothing for the fine slider to adjust, which is reported as !ok
+func (m *sessionMap) combineFineSlider(event SliderMoveEvent) (SliderMoveEvent, bool) {
+	fineSliders := m.deej.config.SliderFine
+	if len(fineSliders) == 0 {
+		return event, true
+	}
+
+	if pair, isFine := fineSliders[event.SliderID]; isFine {
+		m.fineLock.Lock()
+		m.finePositions[event.SliderID] = event.PercentValue
+		coarseValue, coarseKnown := m.finePositions[pair.Coarse]
+		m.fineLock.Unlock()
+
+		if !coarseKnown {
+			return event, false
+		}
+
+		return SliderMoveEvent{
+			SliderID:     pair.Coarse,
+			PercentValue: fineSliderVolume(coarseValue, event.PercentValue, pair.Range),
+		}, true
+	}
+
+	for fineIdx, pair := range fineSliders {
+		if pair.Coarse != event.SliderID {
+			continue
+		}
+
+		m.fineLock.Lock()
+		m.finePositions[event.SliderID] = event.PercentValue
+		fineValue, fineKnown := m.finePositions[fineIdx]
+		m.fineLock.Unlock()
+
+		if fineKnown {
+			event.PercentValue = fineSliderVolume(event.PercentValue, fineValue, pair.Range)
+		}
+
+		return event, true
+	}
+
+	return event, true
+}
+
+// fineSliderVolume combines a coarse position with a fine one (both 0-1). The fine slider's center
+// adds nothing and its ends add or take away rangePercent
+func fineSliderVolume(coarse float32, fine float32, rangePercent float64) float32 {
+	volume := coarse + (fine-0.5)*2*float32(rangePercent/100)
+
+	if volume < 0 {
+		return 0
+	} else if volume > 1 {
+		return 1
+	}
+
+	return volume
+}
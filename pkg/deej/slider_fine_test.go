@@ -0,0 +1,70 @@
+package deej
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFineSliderVolume(t *testing.T) {
+	tests := []struct {
+		coarse, fine float32
+		rangePercent float64
+		want         float32
+	}{
+		{0.5, 0.5, 5, 0.5},
+		{0.5, 1, 5, 0.55},
+		{0.5, 0, 5, 0.45},
+		{0.5, 0.75, 10, 0.55},
+		{0.98, 1, 5, 1},
+		{0.02, 0, 5, 0},
+	}
+
+	for _, tt := range tests {
+		if got := fineSliderVolume(tt.coarse, tt.fine, tt.rangePercent); math.Abs(float64(got-tt.want)) > 1e-6 {
+			t.Errorf("fineSliderVolume(%v, %v, %v) = %v, want %v", tt.coarse, tt.fine, tt.rangePercent, got, tt.want)
+		}
+	}
+}
+
+func TestFineSlider(t *testing.T) {
+	const yaml = "slider_mapping:\n  0: spotify.exe\nslider_fine:\n  5:\n    coarse: 0\n    range: 10\n"
+
+	type move struct {
+		slider int
+		value  float32
+	}
+
+	tests := []struct {
+		name  string
+		yaml  string
+		moves []move
+		want  float32 // spotify.exe's volume afterwards, it starts at 1
+	}{
+		{"coarse alone", yaml, []move{{0, 0.6}}, 0.6},
+		{"fine alone has nothing to adjust", yaml, []move{{5, 0.75}}, 1},
+		{"fine after coarse", yaml, []move{{0, 0.6}, {5, 1}}, 0.7},
+		{"coarse after fine keeps the offset", yaml, []move{{5, 0.75}, {0, 0.5}}, 0.55},
+		{"coarse moves again", yaml, []move{{0, 0.6}, {5, 0}, {0, 0.4}}, 0.3},
+		{"fine centered adds nothing", yaml, []move{{0, 0.3}, {5, 0.5}}, 0.3},
+		{"combined level is clamped", yaml, []move{{0, 0.95}, {5, 1}}, 1},
+		{"default range", "slider_mapping:\n  0: spotify.exe\nslider_fine:\n  5:\n    coarse: 0\n",
+			[]move{{0, 0.5}, {5, 1}}, 0.5 + default_SliderFineRange/100.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeFilteringFinder{processes: []string{"spotify.exe"}}
+			m, _ := newTestSessionMapWithConfig(t, finder, tt.yaml)
+			m.refreshSessions(true)
+
+			for _, mv := range tt.moves {
+				m.handleSliderMoveEvent(SliderMoveEvent{SliderID: mv.slider, PercentValue: mv.value})
+			}
+
+			spotify, _ := m.get("spotify.exe")
+			if got := spotify[0].GetVolume(); math.Abs(float64(got-tt.want)) > 1e-6 {
+				t.Errorf("volume = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
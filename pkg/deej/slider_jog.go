@@ -0,0 +1,141 @@
+package deej
+
+import (
+	"math"
+	"time"
+
+	"github.com/stalexteam/deej_esp32/pkg/deej/util"
+)
+
+const (
+	// How often held jog sliders nudge their targets' volume
+	sliderJogTickInterval = 50 * time.Millisecond
+
+	// spring-return sliders rarely come to rest at exactly 50%, so readings this close to the center count as centered
+	sliderJogDeadzone = 0.05
+)
+
+// handleJogSliderMove records how far a slider_jog slider is pushed from its center. The volume itself
+// is changed by runJog, which keeps nudging it for as long as the slider is held off center
+func (m *sessionMap) handleJogSliderMove(event SliderMoveEvent) {
+	displacement := jogDisplacement(event.PercentValue)
+
+	m.jogLock.Lock()
+	defer m.jogLock.Unlock()
+
+	if displacement == 0 {
+		delete(m.jogDisplacements, event.SliderID)
+		return
+	}
+
+	m.jogDisplacements[event.SliderID] = displacement
+
+	if !m.jogRunning {
+		m.jogRunning = true
+		go m.runJog()
+	}
+}
+
+// jogDisplacement turns a 0-1 slider position into how far it's pushed from the center, from -1 to 1,
+// with the deadzone around the center reading as 0
+func jogDisplacement(value float32) float64 {
+	offset := float64(value) - 0.5
+
+	magnitude := math.Abs(offset) - sliderJogDeadzone
+	if magnitude <= 0 {
+		return 0
+	}
+
+	return math.Copysign(math.Min(magnitude/(0.5-sliderJogDeadzone), 1), offset)
+}
+
+// runJog ticks while any jog slider is off center, moving each one's targets at a rate proportional
+// to the displacement. It exits once every jog slider is back at the center
+func (m *sessionMap) runJog() {
+	ticker := time.NewTicker(sliderJogTickInterval)
+	defer ticker.Stop()
+
+	last := time.Now()
+
+	for now := range ticker.C {
+		elapsed := now.Sub(last).Seconds()
+		last = now
+
+		m.jogLock.Lock()
+		if m.deej.stopped.Load() || len(m.jogDisplacements) == 0 {
+			m.jogRunning = false
+			m.jogLock.Unlock()
+			return
+		}
+
+		deltas := make(map[int]float32, len(m.jogDisplacements))
+		for sliderID, displacement := range m.jogDisplacements {
+			rate, ok := m.deej.config.SliderJog[sliderID]
+			if !ok {
+				// no longer a jog slider after a config reload
+				delete(m.jogDisplacements, sliderID)
+				continue
+			}
+			deltas[sliderID] = float32(displacement * rate / 100 * elapsed)
+		}
+		m.jogLock.Unlock()
+
+		for sliderID, delta := range deltas {
+			m.jogSlider(sliderID, delta)
+		}
+	}
+}
+
+// jogSlider changes the volume of every session mapped to a slider by delta (0-1 scale)
+func (m *sessionMap) jogSlider(sliderID int, delta float32) {
+	targets, ok := m.deej.config.SliderMapping.get(sliderID)
+	if !ok {
+		return
+	}
+
+	adjustmentFailed := false
+	adjusted := make(map[Session]struct{})
+
+	adjust := func(session Session) {
+		if _, ok := adjusted[session]; ok {
+			return
+		}
+		adjusted[session] = struct{}{}
+
+		current := session.GetVolume()
+		volume := current + delta
+		if volume < 0 {
+			volume = 0
+		} else if volume > 1 {
+			volume = 1
+		}
+		if volume == current {
+			return
+		}
+
+		if err := session.SetVolume(volume); err != nil {
+			m.logger.Warnw("Failed to jog target session volume", "slider", sliderID, "error", err)
+			adjustmentFailed = true
+		}
+	}
+
+	for _, target := range targets {
+		for _, resolvedTarget := range m.resolveTarget(target) {
+			if util.IsPath(resolvedTarget) {
+				m.iterateAllSessions(func(session Session) {
+					if util.PathMatches(session.ProcessPath(), resolvedTarget) {
+						adjust(session)
+					}
+				})
+			} else if sessions, ok := m.get(resolvedTarget); ok {
+				for _, session := range sessions {
+					adjust(session)
+				}
+			}
+		}
+	}
+
+	if adjustmentFailed {
+		m.refreshSessionsAfterFailure()
+	}
+}
@@ -0,0 +1,83 @@
+package deej
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestJogDisplacement(t *testing.T) {
+	tests := []struct {
+		value float32
+		want  float64
+	}{
+		{0.5, 0},
+		{0.53, 0},
+		{0.47, 0},
+		{1, 1},
+		{0, -1},
+		{0.775, 0.5},
+		{0.225, -0.5},
+	}
+
+	for _, tt := range tests {
+		if got := jogDisplacement(tt.value); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("jogDisplacement(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestJogSlider(t *testing.T) {
+	yaml := "slider_mapping:\n  0: spotify.exe\nslider_jog:\n  0: 100\n"
+
+	tests := []struct {
+		name     string
+		position float32
+		wantUp   bool // whether the volume should have gone up, down otherwise
+		wantSame bool // whether the volume shouldn't have moved at all
+	}{
+		{"held above center raises the volume", 1, true, false},
+		{"held below center lowers the volume", 0, false, false},
+		{"resting at center leaves it alone", 0.52, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeFilteringFinder{processes: []string{"spotify.exe"}}
+			m, _ := newTestSessionMapWithConfig(t, finder, yaml)
+			m.refreshSessions(true)
+
+			spotify, _ := m.get("spotify.exe")
+			spotify[0].SetVolume(0.5)
+
+			m.handleJogSliderMove(SliderMoveEvent{SliderID: 0, PercentValue: tt.position})
+			time.Sleep(4 * sliderJogTickInterval)
+			m.handleJogSliderMove(SliderMoveEvent{SliderID: 0, PercentValue: 0.5})
+
+			// back at center, the adjuster stops on its next tick
+			deadline := time.Now().Add(time.Second)
+			for {
+				m.jogLock.Lock()
+				running := m.jogRunning
+				m.jogLock.Unlock()
+				if !running {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("jog adjuster kept running with the slider centered")
+				}
+				time.Sleep(sliderJogTickInterval / 5)
+			}
+
+			got := spotify[0].GetVolume()
+			switch {
+			case tt.wantSame && got != 0.5:
+				t.Errorf("volume = %v, want it left at 0.5", got)
+			case !tt.wantSame && tt.wantUp && got <= 0.5:
+				t.Errorf("volume = %v, want it raised above 0.5", got)
+			case !tt.wantSame && !tt.wantUp && got >= 0.5:
+				t.Errorf("volume = %v, want it lowered below 0.5", got)
+			}
+		})
+	}
+}
@@ -3,33 +3,63 @@ package deej
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 
+	"github.com/spf13/cast"
 	"github.com/thoas/go-funk"
 )
 
+const (
+	mappingKey_Label   = "label"
+	mappingKey_Targets = "targets"
+)
+
 type sliderMap struct {
-	m    map[int][]string
-	lock sync.Locker
+	m      map[int][]string
+	labels map[int]string
+	lock   sync.Locker
 }
 
 func newSliderMap() *sliderMap {
 	return &sliderMap{
-		m:    make(map[int][]string),
-		lock: &sync.Mutex{},
+		m:      make(map[int][]string),
+		labels: make(map[int]string),
+		lock:   &sync.Mutex{},
+	}
+}
+
+// mappingEntryFromConfig parses a single slider/switch mapping entry. it accepts the legacy form
+// (a target name or a list of targets) as well as the labeled form: {label: "Game", targets: [...]}
+func mappingEntryFromConfig(value interface{}) (string, []string) {
+	var label string
+	var targets []string
+
+	if entryMap, err := cast.ToStringMapE(value); err == nil && len(entryMap) > 0 {
+		label = strings.TrimSpace(cast.ToString(entryMap[mappingKey_Label]))
+		targets = cast.ToStringSlice(entryMap[mappingKey_Targets])
+	} else {
+		targets = cast.ToStringSlice(value)
 	}
+
+	return label, funk.FilterString(targets, func(s string) bool {
+		return s != ""
+	})
 }
 
-func sliderMapFromConfigs(userMapping map[string][]string, internalMapping map[string][]string) *sliderMap {
+func sliderMapFromConfigs(userMapping map[string]interface{}, internalMapping map[string][]string) *sliderMap {
 	resultMap := newSliderMap()
 
-	// copy targets from user config, ignoring empty values
-	for sliderIdxString, targets := range userMapping {
+	// copy targets (and optional labels) from user config, ignoring empty values
+	for sliderIdxString, value := range userMapping {
 		sliderIdx, _ := strconv.Atoi(sliderIdxString)
 
-		resultMap.set(sliderIdx, funk.FilterString(targets, func(s string) bool {
-			return s != ""
-		}))
+		label, targets := mappingEntryFromConfig(value)
+		resultMap.set(sliderIdx, targets)
+
+		if label != "" {
+			resultMap.setLabel(sliderIdx, label)
+		}
 	}
 
 	// add targets from internal configs, ignoring duplicate or empty values
@@ -76,6 +106,22 @@ func (m *sliderMap) set(key int, value []string) {
 	m.m[key] = value
 }
 
+// label returns the user-provided label for a slider, if one was configured
+func (m *sliderMap) label(key int) (string, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	value, ok := m.labels[key]
+	return value, ok
+}
+
+func (m *sliderMap) setLabel(key int, value string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.labels[key] = value
+}
+
 func (m *sliderMap) String() string {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -88,5 +134,9 @@ func (m *sliderMap) String() string {
 		targetCount += len(value)
 	}
 
+	if len(m.labels) > 0 {
+		return fmt.Sprintf("<%d sliders (%d labeled) mapped to %d targets>", sliderCount, len(m.labels), targetCount)
+	}
+
 	return fmt.Sprintf("<%d sliders mapped to %d targets>", sliderCount, targetCount)
 }
@@ -0,0 +1,61 @@
+package deej
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestSliderMapFromConfigs(t *testing.T) {
+	userYAML := `
+slider_mapping:
+  0: master
+  1:
+    - chrome.exe
+    - firefox.exe
+  2:
+    label: Game
+    targets:
+      - game.exe
+  3:
+    label: "  Voice  "
+    targets: discord.exe
+  4:
+    label: Empty
+`
+	internalMapping := map[string][]string{"1": {"firefox.exe", "spotify.exe"}, "5": {"mic"}}
+
+	userConfig := viper.New()
+	userConfig.SetConfigType(configType)
+	if err := userConfig.ReadConfig(strings.NewReader(userYAML)); err != nil {
+		t.Fatalf("read test config: %v", err)
+	}
+
+	m := sliderMapFromConfigs(userConfig.GetStringMap(configKey_SliderMapping), internalMapping)
+
+	tests := []struct {
+		slider      int
+		wantTargets string
+		wantLabel   string
+	}{
+		{0, "master", ""},
+		{1, "chrome.exe,firefox.exe,spotify.exe", ""},
+		{2, "game.exe", "Game"},
+		{3, "discord.exe", "Voice"},
+		{4, "", "Empty"},
+		{5, "mic", ""},
+	}
+
+	for _, tt := range tests {
+		targets, _ := m.get(tt.slider)
+		if got := strings.Join(targets, ","); got != tt.wantTargets {
+			t.Errorf("slider %d targets: got %q, want %q", tt.slider, got, tt.wantTargets)
+		}
+
+		label, ok := m.label(tt.slider)
+		if label != tt.wantLabel || ok != (tt.wantLabel != "") {
+			t.Errorf("slider %d label: got %q (%v), want %q", tt.slider, label, ok, tt.wantLabel)
+		}
+	}
+}
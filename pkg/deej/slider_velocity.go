@@ -0,0 +1,54 @@
+package deej
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// Slowest movements are scaled down to this fraction of the slider's travel
+	sliderFineControlMinGain = 0.25
+
+	// Readings further apart than this aren't one movement, so they don't tell us a speed
+	sliderFineControlMaxGap = time.Second
+)
+
+// sliderVelocityState tracks one slider for slider_fine_control
+type sliderVelocityState struct {
+	lastRaw float64   // last reading from the device
+	lastAt  time.Time // when it arrived
+	output  float64   // the value we made of it
+}
+
+// applySliderVelocity implements slider_fine_control: a slider moved slowly changes the volume by less than
+// it moved (down to sliderFineControlMinGain), one moved at slider_fine_control_speed or faster by as much.
+// Both ends of the slider's travel are exact, which is also where the two get back in sync
+func (d *Deej) applySliderVelocity(idx int, val float64, now time.Time) float64 {
+	fullSpeed := d.config.SliderFineControlSpeed
+	if fullSpeed <= 0 {
+		return val
+	}
+
+	d.sliderVelocityMutex.Lock()
+	defer d.sliderVelocityMutex.Unlock()
+
+	state, seen := d.sliderVelocityStates[idx]
+	output := val
+
+	if seen && val > 0 && val < 100 {
+		delta := val - state.lastRaw
+		gap := now.Sub(state.lastAt)
+
+		gain := 1.0
+		if gap > 0 && gap <= sliderFineControlMaxGap {
+			speed := math.Abs(delta) / gap.Seconds() // percent per second
+			gain = math.Max(sliderFineControlMinGain, math.Min(1, speed/fullSpeed))
+		}
+
+		output = math.Max(0, math.Min(100, state.output+delta*gain))
+	}
+
+	d.sliderVelocityStates[idx] = sliderVelocityState{lastRaw: val, lastAt: now, output: output}
+
+	return output
+}
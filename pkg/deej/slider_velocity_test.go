@@ -0,0 +1,48 @@
+package deej
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestApplySliderVelocity(t *testing.T) {
+	type sample struct {
+		raw float64
+		at  time.Duration
+	}
+
+	tests := []struct {
+		name    string
+		yaml    string
+		samples []sample
+		want    []float64
+	}{
+		{"off", "", []sample{{50, 0}, {52, time.Second}}, []float64{50, 52}},
+		{"slow movement is fine", "slider_fine_control: true\n", []sample{{50, 0}, {52, time.Second}}, []float64{50, 50.5}},
+		{"medium movement is scaled", "slider_fine_control: true\n", []sample{{50, 0}, {60, 400 * time.Millisecond}}, []float64{50, 55}},
+		{"fast movement is coarse", "slider_fine_control: true\n", []sample{{50, 0}, {80, 100 * time.Millisecond}}, []float64{50, 80}},
+		{"custom full speed", "slider_fine_control: true\nslider_fine_control_speed: 10\n", []sample{{50, 0}, {60, 400 * time.Millisecond}}, []float64{50, 60}},
+		{"long pause isn't a speed", "slider_fine_control: true\n", []sample{{50, 0}, {52, 2 * time.Second}}, []float64{50, 52}},
+		{"slow steps accumulate", "slider_fine_control: true\n", []sample{{50, 0}, {52, time.Second}, {54, 2 * time.Second}}, []float64{50, 50.5, 51}},
+		{"ends are exact", "slider_fine_control: true\n", []sample{{50, 0}, {52, time.Second}, {100, 3 * time.Second}, {0, 4 * time.Second}}, []float64{50, 50.5, 100, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, tt.yaml)
+			start := time.Now()
+
+			got := make([]float64, 0, len(tt.samples))
+			for _, s := range tt.samples {
+				got = append(got, d.applySliderVelocity(0, s.raw, start.Add(s.at)))
+			}
+
+			for i := range tt.want {
+				if math.Abs(got[i]-tt.want[i]) > 1e-9 {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -17,7 +17,11 @@ import (
 	"go.uber.org/zap"
 )
 
+// Defaults for SSE_ConnectTimeout, SSE_IdleTimeout and SSE_RetryInterval
 const (
+	// How long establishing the connection may take
+	sseConnectTimeout = 10 * time.Second
+
 	// SSE idle timeout - esphome sends ping every 10 seconds, so 12 seconds timeout is safe
 	sseIdleTimeout = 12 * time.Second
 
@@ -40,6 +44,8 @@ type SseIO struct {
 	ctx        context.Context
 	cancel     context.CancelFunc
 	currentURL string // Stores the URL of the current connection for comparison on config reload
+
+	authNotified atomic.Bool // the user was told the device wants a (different) password, until the next successful connect
 }
 
 // NewSseIO creates an SseIO instance that uses the provided deej instance's connection info
@@ -81,6 +87,8 @@ func (sio *SseIO) Start() error {
 	if err := sio.connect(sio.logger); err != nil {
 		return fmt.Errorf("sse initial connect error: %w", err)
 	}
+	sio.deej.notifyConnectionRestored("SSE")
+	sio.deej.runOnConnectAction("SSE")
 
 	go func() {
 		for {
@@ -95,6 +103,7 @@ func (sio *SseIO) Start() error {
 				err := sio.run(sio.logger)
 				if err != nil {
 					sio.logger.Warnw("SSE connection lost", "error", err.Error())
+					sio.deej.notifyConnectionLost("SSE")
 				}
 			}
 
@@ -103,7 +112,7 @@ func (sio *SseIO) Start() error {
 			select {
 			case <-sio.stopChannel:
 				return
-			case <-time.After(sseRetryDelay):
+			case <-time.After(sio.deej.config.ConnectionInfo.SSE_RetryInterval):
 			}
 
 			// Check stopChannel again before attempting reconnect (avoid deadlock)
@@ -176,6 +185,8 @@ func (sio *SseIO) Start() error {
 					}
 					continue
 				}
+				sio.deej.notifyConnectionRestored("SSE")
+				sio.deej.runOnConnectAction("SSE")
 			}
 		}
 	}()
@@ -237,11 +248,16 @@ func (sio *SseIO) connect(logger *zap.SugaredLogger) error {
 		return fmt.Errorf("create HTTP request: %w", err)
 	}
 
+	if password := sio.deej.config.ConnectionInfo.SSE_Password; password != "" {
+		req.SetBasicAuth(sio.deej.config.ConnectionInfo.SSE_Username, password)
+	}
+
 	// Create eventsource under lock to avoid race conditions
 	sio.mu.Lock()
 	sio.req = req
 	es := eventsource.New(sio.req)
-	es.SetIdleTimeout(sseIdleTimeout)
+	es.ConnectionTimeout = sio.deej.config.ConnectionInfo.SSE_ConnectTimeout
+	es.SetIdleTimeout(sio.deej.config.ConnectionInfo.SSE_IdleTimeout)
 
 	// Callbacks
 	es.OnConnect = func(url string) {
@@ -257,6 +273,10 @@ func (sio *SseIO) connect(logger *zap.SugaredLogger) error {
 	}
 
 	es.OnError = func(url string, err error) {
+		if sseAuthError(err) {
+			sio.notifyAuthRequired(logger, url, err)
+			return
+		}
 		logger.Infow("Device seems offline or not responding", "url", url, "error", err.Error())
 	}
 
@@ -308,11 +328,12 @@ func (sio *SseIO) connect(logger *zap.SugaredLogger) error {
 	}
 
 	if readErr == nil && ev.Type == "state" {
-		sio.deej.handleStateEvent(logger, ev.Data)
+		sio.handleEvent(logger, ev.Data)
 	}
 
 	// Mark as connected atomically and save URL
 	atomic.StoreInt32(&sio.connected, 1)
+	sio.authNotified.Store(false)
 	sio.mu.Lock()
 	sio.currentURL = url
 	sio.mu.Unlock()
@@ -321,6 +342,36 @@ func (sio *SseIO) connect(logger *zap.SugaredLogger) error {
 	return nil
 }
 
+// sseAuthError reports whether the device turned the event stream request down for missing or wrong credentials
+func sseAuthError(err error) bool {
+	// the eventsource library only reports the status text, e.g. "unrecoverable HTTP status: 401 Unauthorized"
+	message := err.Error()
+	return strings.Contains(message, "HTTP status: 401") || strings.Contains(message, "HTTP status: 403")
+}
+
+// notifyAuthRequired tells the user once per failing streak that the device wants a password, since
+// retrying on its own can't fix that
+func (sio *SseIO) notifyAuthRequired(logger *zap.SugaredLogger, url string, err error) {
+	logger.Warnw("Device rejected the SSE connection, it requires a password", "url", url, "error", err.Error())
+
+	if sio.authNotified.Swap(true) {
+		return
+	}
+
+	if sio.deej.config.ConnectionInfo.SSE_Password == "" {
+		sio.deej.notifier.Notify("Device requires a password",
+			"Set SSE_Username and SSE_Password in config.yaml to the device's web server credentials.")
+	} else {
+		sio.deej.notifier.Notify("Device rejected the password",
+			"Check SSE_Username and SSE_Password in config.yaml.")
+	}
+}
+
+// IsConnected returns whether the SSE stream is currently connected
+func (sio *SseIO) IsConnected() bool {
+	return atomic.LoadInt32(&sio.connected) == 1
+}
+
 // WaitForStop waits for the connection to be fully stopped (for use during interface switching)
 func (sio *SseIO) WaitForStop(timeout time.Duration) bool {
 	deadline := time.Now().Add(timeout)
@@ -474,12 +525,24 @@ func (sio *SseIO) run(logger *zap.SugaredLogger) error {
 					continue
 				}
 
-				sio.deej.handleStateEvent(eventLogger, ev.Data)
+				sio.handleEvent(eventLogger, ev.Data)
 			}
 		}
 	}
 }
 
+// handleEvent passes a state event on to deej. A panic while handling it is logged and the event dropped,
+// so a single bad frame can't take the read loop, and with it the whole app, down
+func (sio *SseIO) handleEvent(logger *zap.SugaredLogger, data []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorw("Panic while handling SSE event, dropping it", "panic", r, "data", string(data))
+		}
+	}()
+
+	sio.deej.handleStateEvent(logger, data)
+}
+
 // Stop signals us to shut down our SSE connection, if one is active
 func (sio *SseIO) Stop() {
 	// Send stop signal first (non-blocking to avoid deadlock)
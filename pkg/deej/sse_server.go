@@ -4,7 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -34,11 +39,17 @@ type SseServer struct {
 }
 
 const (
+	// Synthetic session snapshot events are sent as e.g. "session-chrome.exe"
+	sessionSnapshotIDPrefix = "session-"
+
 	// SSE retry timeout in milliseconds (as per ESP32 format)
 	sseRetryTimeout = 30000
 
 	// Ping interval
 	pingInterval = 10 * time.Second
+
+	// Mapping updates are a handful of names, anything bigger isn't one
+	maxMappingBodySize = 1 << 20
 )
 
 // NewSseServer creates a new SSE server instance
@@ -161,15 +172,30 @@ func (srv *SseServer) Start() error {
 	handlerWithManager := eventsource.HandlerWithManager(srv.manager, handler)
 
 	mux := http.NewServeMux()
-	// Handle any URL path - all paths will serve SSE stream
+	mux.HandleFunc("/status", srv.handleStatus)
+	mux.HandleFunc("/sessions/refresh", srv.handleRefreshSessions)
+	mux.HandleFunc("/sessions", srv.handleSessions)
+	mux.HandleFunc("/config/slider_mapping", srv.handleMappingUpdate(configKey_SliderMapping))
+	mux.HandleFunc("/config/switches_mapping", srv.handleMappingUpdate(configKey_SwitchesMapping))
+	// Handle any other URL path - all of them serve the SSE stream
 	mux.HandleFunc("/", handlerWithManager.ServeHTTP)
 
-	addr := fmt.Sprintf(":%d", port)
+	// bind before returning, so a port that's already taken is reported instead of failing in the background
+	listener, err := srv.listen(port)
+	if err != nil {
+		srv.logger.Errorw("Can't start SSE server, port unavailable", "port", port, "error", err)
+		srv.deej.notifier.Notify("SSE relay not started",
+			fmt.Sprintf("Port %d is already in use. Change SSE_RELAY_PORT in config.yaml, or set SSE_RELAY_PortSearch.", port))
+		return fmt.Errorf("listen on SSE relay port %d: %w", port, err)
+	}
+
+	addr := listener.Addr().String()
 	srv.server = &http.Server{
 		Addr:    addr,
 		Handler: mux,
 	}
 
+	// currentPort stays the configured port even when another one was picked, it's what config changes compare against
 	srv.portMutex.Lock()
 	srv.currentPort = port
 	srv.portMutex.Unlock()
@@ -178,7 +204,7 @@ func (srv *SseServer) Start() error {
 
 	go func() {
 		srv.logger.Infow("Starting SSE server", "addr", addr)
-		if err := srv.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := srv.server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			srv.logger.Errorw("SSE server error", "error", err)
 			atomic.StoreInt32(&srv.running, 0)
 		}
@@ -190,6 +216,215 @@ func (srv *SseServer) Start() error {
 	return nil
 }
 
+// listen binds the relay port. When it's taken and SSE_RELAY_PortSearch allows it, the following ports
+// are tried in turn; the error is always the one for the configured port
+func (srv *SseServer) listen(port int) (net.Listener, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err == nil {
+		return listener, nil
+	}
+
+	for offset := 1; offset <= srv.deej.config.ConnectionInfo.SSE_RELAY_PortSearch; offset++ {
+		candidate := port + offset
+		if candidate > 65535 {
+			break
+		}
+
+		if fallback, fallbackErr := net.Listen("tcp", fmt.Sprintf(":%d", candidate)); fallbackErr == nil {
+			srv.logger.Warnw("SSE relay port in use, using the next free one", "port", port, "actual_port", candidate, "error", err)
+			srv.deej.notifier.Notify("SSE relay moved",
+				fmt.Sprintf("Port %d is already in use, the relay is on port %d instead.", port, candidate))
+			return fallback, nil
+		}
+	}
+
+	return nil, err
+}
+
+// handleStatus reports which deej build is running the relay and how its own device link is doing
+func (srv *SseServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := struct {
+		BuildInfo
+		Summary   string `json:"summary"`
+		Transport string `json:"transport"`
+		Connected bool   `json:"connected"`
+		Healthy   bool   `json:"healthy"` // serial also needs to answer keep-alive pings, see serial_ping_interval
+		Sliders   int    `json:"sliders"` // as reported by the device or seen in its events, 0 if unknown
+		Switches  int    `json:"switches"`
+	}{
+		BuildInfo: srv.deej.buildInfo,
+		Summary:   srv.deej.buildInfo.String(),
+	}
+
+	status.Sliders, status.Switches = srv.deej.DeviceHardware()
+
+	srv.deej.ioMutex.Lock()
+	io := srv.deej.io
+	srv.deej.ioMutex.Unlock()
+
+	switch io {
+	case srv.deej.serial:
+		status.Transport = "serial"
+		status.Connected = srv.deej.serial.IsConnected()
+		status.Healthy = srv.deej.serial.Healthy()
+	case srv.deej.sse:
+		status.Transport = "sse"
+		status.Connected = srv.deej.sse.IsConnected()
+		status.Healthy = status.Connected
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		srv.logger.Debugw("Failed to write status response", "error", err)
+	}
+}
+
+// handleSessions lists the audio sessions deej knows about with their volume and mute state, including
+// how many switches hold each one muted, to answer "why is this app muted" without digging through logs
+func (srv *SseServer) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessions := srv.deej.sessions
+	if sessions == nil {
+		http.Error(w, "sessions not initialized yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	type sessionStatus struct {
+		Key             string `json:"key"`
+		Volume          int    `json:"volume"` // percent
+		Muted           bool   `json:"muted"`
+		SwitchMuteCount int    `json:"switch_mute_count"`
+	}
+
+	list := []sessionStatus{}
+	sessions.iterateAllSessions(func(session Session) {
+		list = append(list, sessionStatus{
+			Key:             session.Key(),
+			Volume:          int(math.Round(float64(session.GetVolume()) * 100)),
+			Muted:           session.GetMute(),
+			SwitchMuteCount: session.GetSwitchMuteCount(),
+		})
+	})
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(list); err != nil {
+		srv.logger.Debugw("Failed to write sessions response", "error", err)
+	}
+}
+
+// handleRefreshSessions re-scans audio sessions like the tray item does, so a script that just started
+// an app can have deej pick it up right away. It responds with the new session count
+func (srv *SseServer) handleRefreshSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	sessions := srv.deej.sessions
+	if sessions == nil {
+		http.Error(w, "sessions not initialized yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	srv.logger.Infow("Session refresh requested over HTTP", "remote", r.RemoteAddr)
+
+	// performance: forcing is fine here for the same reason as the tray item, it's a deliberate user action
+	sessions.refreshSessions(true)
+
+	count := 0
+	sessions.iterateAllSessions(func(Session) { count++ })
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]int{"sessions": count}); err != nil {
+		srv.logger.Debugw("Failed to write session refresh response", "error", err)
+	}
+}
+
+// handleMappingUpdate returns a handler that replaces the runtime part of slider_mapping or switches_mapping
+// (key) with the PUT body, e.g. {"0": ["master"], "1": ["chrome.exe", "firefox.exe"]}. It's saved to
+// logs/preferences.yaml and added to the config.yaml mapping, and the response is the resulting active mapping
+func (srv *SseServer) handleMappingUpdate(key string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.Header().Set("Allow", http.MethodPut)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var payload map[string][]string
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMappingBodySize)).Decode(&payload); err != nil {
+			http.Error(w, fmt.Sprintf("invalid mapping: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		mapping, err := mappingFromPayload(payload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid mapping: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		srv.logger.Infow("Mapping update requested over HTTP", "remote", r.RemoteAddr, "key", key, "mapping", mapping)
+
+		if err := srv.deej.config.SaveMapping(key, mapping); err != nil {
+			http.Error(w, fmt.Sprintf("mapping applied but not saved: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		active := make(map[string][]string)
+		collect := func(idx int, targets []string) {
+			active[strconv.Itoa(idx)] = targets
+		}
+		if key == configKey_SliderMapping {
+			srv.deej.config.SliderMapping.iterate(collect)
+		} else {
+			srv.deej.config.SwitchesMapping.iterate(collect)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(active); err != nil {
+			srv.logger.Debugw("Failed to write mapping update response", "error", err)
+		}
+	}
+}
+
+// mappingFromPayload checks a mapping sent over HTTP: indexes are non-negative numbers and targets are
+// non-empty names. Targets are trimmed, and indexes with no targets are dropped
+func mappingFromPayload(payload map[string][]string) (map[int][]string, error) {
+	mapping := make(map[int][]string, len(payload))
+
+	for idxString, targets := range payload {
+		idx, err := strconv.Atoi(strings.TrimSpace(idxString))
+		if err != nil || idx < 0 {
+			return nil, fmt.Errorf("index %q is not a non-negative number", idxString)
+		}
+
+		trimmed := make([]string, 0, len(targets))
+		for _, target := range targets {
+			target = strings.TrimSpace(target)
+			if target == "" {
+				return nil, fmt.Errorf("index %d has an empty target", idx)
+			}
+			trimmed = append(trimmed, target)
+		}
+
+		if len(trimmed) > 0 {
+			mapping[idx] = trimmed
+		}
+	}
+
+	return mapping, nil
+}
+
 // Stop stops the SSE server
 func (srv *SseServer) Stop() {
 	if atomic.LoadInt32(&srv.running) == 0 {
@@ -273,13 +508,23 @@ func (srv *SseServer) sendAllStatesToEncoder(encoder *eventsource.Encoder) {
 	for id, state := range switchStates {
 		srv.sendStateToEncoder(encoder, id, state)
 	}
+
+	// Optionally follow up with the actual audio state, for UI clients
+	if srv.deej.config.ConnectionInfo.SSE_RELAY_Snapshot && srv.deej.sessions != nil {
+		for _, snapshot := range srv.deej.sessions.snapshot() {
+			id := sessionSnapshotIDPrefix + snapshot.key
+			srv.encodeState(encoder, id, map[string]interface{}{
+				"id":    id,
+				"value": snapshot.volume,
+				"muted": snapshot.muted,
+			})
+		}
+	}
 }
 
 // sendStateToEncoder sends a state event to an encoder
 // Uses minimal format: only id and value (as per requirement)
 func (srv *SseServer) sendStateToEncoder(encoder *eventsource.Encoder, id string, state map[string]interface{}) {
-	eventID := atomic.AddInt64(&srv.eventID, 1)
-
 	// Create minimal format with only id and value (no state field needed)
 	minimalState := map[string]interface{}{
 		"id": id,
@@ -289,7 +534,14 @@ func (srv *SseServer) sendStateToEncoder(encoder *eventsource.Encoder, id string
 		minimalState["value"] = value
 	}
 
-	stateJSON, err := json.Marshal(minimalState)
+	srv.encodeState(encoder, id, minimalState)
+}
+
+// encodeState sends an already-shaped state payload to an encoder
+func (srv *SseServer) encodeState(encoder *eventsource.Encoder, id string, payload map[string]interface{}) {
+	eventID := atomic.AddInt64(&srv.eventID, 1)
+
+	stateJSON, err := json.Marshal(payload)
 	if err != nil {
 		srv.logger.Warnw("Failed to marshal state data", "error", err, "id", id)
 		return
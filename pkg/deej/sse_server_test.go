@@ -0,0 +1,264 @@
+package deej
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	eventsource "github.com/stalexteam/eventsource_go"
+	"go.uber.org/zap"
+)
+
+func TestHandleMappingUpdate(t *testing.T) {
+	cc := newTestConfig(t, "switches_mapping:\n  1: mic\n")
+	srv := &SseServer{deej: &Deej{config: cc}, logger: zap.NewNop().Sugar()}
+	handler := srv.handleMappingUpdate(configKey_SwitchesMapping)
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{"wrong method", http.MethodPost, `{"0": ["master"]}`, http.StatusMethodNotAllowed},
+		{"not json", http.MethodPut, `0: master`, http.StatusBadRequest},
+		{"negative index", http.MethodPut, `{"-1": ["master"]}`, http.StatusBadRequest},
+		{"named index", http.MethodPut, `{"mic": ["master"]}`, http.StatusBadRequest},
+		{"empty target", http.MethodPut, `{"0": [" "]}`, http.StatusBadRequest},
+		{"valid", http.MethodPut, `{"0": ["master"], "1": ["discord.exe"]}`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/config/switches_mapping", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status: got %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	// the valid update is active, merged with config.yaml, and echoed back
+	targets, ok := cc.SwitchesMapping.get(0)
+	if !ok || strings.Join(targets, ",") != "master" {
+		t.Errorf("switch 0: got %v", targets)
+	}
+	targets, _ = cc.SwitchesMapping.get(1)
+	if strings.Join(targets, ",") != "mic,discord.exe" {
+		t.Errorf("switch 1: got %v", targets)
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/config/switches_mapping", strings.NewReader(`{"3": ["spotify.exe"]}`))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	var active map[string][]string
+	if err := json.NewDecoder(rec.Body).Decode(&active); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if strings.Join(active["3"], ",") != "spotify.exe" || strings.Join(active["1"], ",") != "mic" {
+		t.Errorf("response: got %v", active)
+	}
+}
+
+func TestInitialBurstSessionSnapshot(t *testing.T) {
+	tests := []struct {
+		name         string
+		yaml         string
+		wantSessions bool
+	}{
+		{"snapshot off", "slider_mapping:\n  0: spotify.exe\n", false},
+		{"snapshot on", "SSE_RELAY_Snapshot: true\nslider_mapping:\n  0: spotify.exe\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeFilteringFinder{processes: []string{"spotify.exe"}}
+			m, _ := newTestSessionMapWithConfig(t, finder, tt.yaml)
+			if err := m.getAndAddSessions(); err != nil {
+				t.Fatalf("getAndAddSessions: %v", err)
+			}
+			spotify, _ := m.get("spotify.exe")
+			spotify[0].SetVolume(0.42)
+			spotify[0].SetMute(true, true)
+
+			d := m.deej
+			d.sessions = m
+			sendStates(d, `{"id":"sensor-pot0","value":42}`)
+
+			var out strings.Builder
+			srv := &SseServer{deej: d, logger: zap.NewNop().Sugar()}
+			srv.sendAllStatesToEncoder(eventsource.NewEncoder(&out))
+
+			burst := out.String()
+			if !strings.Contains(burst, `"id":"sensor-pot0"`) {
+				t.Errorf("initial burst is missing the device state: %q", burst)
+			}
+
+			hasSession := strings.Contains(burst, `{"id":"session-spotify.exe","muted":true,"value":42}`)
+			if hasSession != tt.wantSessions {
+				t.Errorf("session snapshot sent: got %v, want %v, burst %q", hasSession, tt.wantSessions, burst)
+			}
+		})
+	}
+}
+
+func TestHandleRefreshSessions(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		noSessions    bool
+		wantStatus    int
+		wantRefreshes int
+		wantCount     int
+	}{
+		{"refreshes", http.MethodPost, false, http.StatusOK, 1, 3},
+		{"wrong method", http.MethodGet, false, http.StatusMethodNotAllowed, 0, 0},
+		{"before init", http.MethodPost, true, http.StatusServiceUnavailable, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeFilteringFinder{processes: []string{"spotify.exe", "discord.exe"}}
+			m, _ := newTestSessionMap(t, finder)
+			if !tt.noSessions {
+				m.deej.sessions = m
+			}
+			srv := &SseServer{deej: m.deej, logger: zap.NewNop().Sugar()}
+
+			// an app started since the last scan
+			finder.processes = append(finder.processes, "game.exe")
+
+			rec := httptest.NewRecorder()
+			srv.handleRefreshSessions(rec, httptest.NewRequest(tt.method, "/sessions/refresh", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status: got %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if finder.refreshes != tt.wantRefreshes {
+				t.Errorf("finder asked for sessions %d times, want %d", finder.refreshes, tt.wantRefreshes)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response map[string]int
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if response["sessions"] != tt.wantCount {
+				t.Errorf("response: got %v, want %d sessions", response, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestHandleSessions(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		noSessions bool
+		wantStatus int
+		want       string
+	}{
+		{"lists mute state", http.MethodGet, false, http.StatusOK,
+			"[{chrome.exe 100 false 0} {discord.exe 100 true 2} {spotify.exe 40 true 0}]"},
+		{"wrong method", http.MethodPost, false, http.StatusMethodNotAllowed, ""},
+		{"before init", http.MethodGet, true, http.StatusServiceUnavailable, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeFilteringFinder{processes: []string{"spotify.exe", "discord.exe", "chrome.exe"}}
+			m, _ := newTestSessionMap(t, finder)
+			m.refreshSessions(true)
+			if !tt.noSessions {
+				m.deej.sessions = m
+			}
+			srv := &SseServer{deej: m.deej, logger: zap.NewNop().Sugar()}
+
+			// spotify muted by hand, discord held muted by two switches
+			spotify, _ := m.get("spotify.exe")
+			spotify[0].SetVolume(0.4)
+			spotify[0].SetMute(true, true)
+			discord, _ := m.get("discord.exe")
+			discord[0].SetMute(true, true)
+			discord[0].SetSwitchMuteCount(2)
+
+			rec := httptest.NewRecorder()
+			srv.handleSessions(rec, httptest.NewRequest(tt.method, "/sessions", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status: got %d, want %d (%s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var response []struct {
+				Key             string `json:"key"`
+				Volume          int    `json:"volume"`
+				Muted           bool   `json:"muted"`
+				SwitchMuteCount int    `json:"switch_mute_count"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if got := fmt.Sprint(response); got != tt.want {
+				t.Errorf("sessions: got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSseServerBusyPort(t *testing.T) {
+	busy, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("occupy a port: %v", err)
+	}
+	defer busy.Close()
+	port := busy.Addr().(*net.TCPAddr).Port
+
+	tests := []struct {
+		name       string
+		portSearch int
+		wantErr    bool
+		wantTitles string
+	}{
+		{"busy port is reported", 0, true, "[SSE relay not started]"},
+		{"next free port is used", 3, false, "[SSE relay moved]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDeej(t, fmt.Sprintf("SSE_RELAY_PORT: %d\nSSE_RELAY_PortSearch: %d\n", port, tt.portSearch))
+			notifier := &recordingNotifier{}
+			d.notifier = notifier
+
+			srv, err := NewSseServer(d, zap.NewNop().Sugar())
+			if err != nil {
+				t.Fatalf("NewSseServer: %v", err)
+			}
+			t.Cleanup(srv.Stop)
+
+			err = srv.Start()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Start: got %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), strconv.Itoa(port)) {
+				t.Errorf("error %q doesn't name port %d", err, port)
+			}
+			if got := fmt.Sprint(notifier.Titles()); got != tt.wantTitles {
+				t.Errorf("notifications: got %s, want %s", got, tt.wantTitles)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package deej
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newConnectedSseIO returns an SseIO that believes it's connected, without any stream behind it
+func newConnectedSseIO(t *testing.T) (*SseIO, context.Context) {
+	t.Helper()
+
+	sio, err := NewSseIO(newTestDeej(t, ""), zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatalf("create SSE i/o: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sio.ctx, sio.cancel = ctx, cancel
+	atomic.StoreInt32(&sio.connected, 1)
+
+	return sio, ctx
+}
+
+func TestSseWaitForStop(t *testing.T) {
+	sio, ctx := newConnectedSseIO(t)
+
+	if sio.WaitForStop(30 * time.Millisecond) {
+		t.Fatal("WaitForStop returned true while still connected")
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		sio.close(sio.logger)
+		close(closed)
+	}()
+
+	if !sio.WaitForStop(time.Second) {
+		t.Fatal("WaitForStop returned false after close")
+	}
+	<-closed
+
+	if ctx.Err() == nil {
+		t.Error("close didn't cancel the connection context")
+	}
+	if sio.IsConnected() {
+		t.Error("still connected after close")
+	}
+}
+
+// run with -race: the connection state is touched from the read loop, config reloads and shutdown at once
+func TestSseConcurrentStopAndClose(t *testing.T) {
+	sio, _ := newConnectedSseIO(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			sio.Stop()
+		}()
+		go func() {
+			defer wg.Done()
+			sio.close(sio.logger)
+		}()
+		go func() {
+			defer wg.Done()
+			sio.IsConnected()
+			sio.WaitForStop(time.Millisecond)
+		}()
+	}
+	wg.Wait()
+
+	if sio.IsConnected() || !sio.WaitForStop(10*time.Millisecond) {
+		t.Error("still connected after concurrent stops")
+	}
+}
+
+func TestSseAuthentication(t *testing.T) {
+	tests := []struct {
+		name        string
+		credentials string // SSE_Username / SSE_Password config lines
+		wantAuth    bool   // whether the request should have carried the right credentials
+		wantTitles  string
+	}{
+		{"password attached", "SSE_Username: admin\nSSE_Password: secret\n", true, "[]"},
+		{"no password", "", false, "[Device requires a password]"},
+		{"wrong password", "SSE_Username: admin\nSSE_Password: guess\n", false, "[Device rejected the password]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "secret" {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprint(w, "event: ping\ndata: {}\n\n")
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			}))
+			defer server.Close()
+
+			d := newTestDeej(t, "SSE_URL: "+server.URL+"/events\n"+tt.credentials)
+			notifier := &recordingNotifier{}
+			d.notifier = notifier
+
+			sio, err := NewSseIO(d, zap.NewNop().Sugar())
+			if err != nil {
+				t.Fatalf("create SSE i/o: %v", err)
+			}
+			defer sio.close(zap.NewNop().Sugar())
+
+			err = sio.connect(zap.NewNop().Sugar())
+			if (err == nil) != tt.wantAuth {
+				t.Fatalf("connect: got %v, want success %v", err, tt.wantAuth)
+			}
+			if got := fmt.Sprint(notifier.Titles()); got != tt.wantTitles {
+				t.Errorf("notifications: got %s, want %s", got, tt.wantTitles)
+			}
+		})
+	}
+}
+
+func TestSseTimeouts(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeouts    string // SSE_ConnectTimeout / SSE_IdleTimeout / SSE_RetryInterval config lines
+		wantConnect time.Duration
+		wantIdle    time.Duration
+		wantRetry   time.Duration
+	}{
+		{"defaults", "", sseConnectTimeout, sseIdleTimeout, sseRetryDelay},
+		{"slow network", "SSE_ConnectTimeout: 30\nSSE_IdleTimeout: 45\nSSE_RetryInterval: 5\n", 30 * time.Second, 45 * time.Second, 5 * time.Second},
+		{"fractional seconds", "SSE_ConnectTimeout: 2.5\nSSE_IdleTimeout: 0.5\nSSE_RetryInterval: 0.25\n", 2500 * time.Millisecond, 500 * time.Millisecond, 250 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprint(w, "event: ping\ndata: {}\n\n")
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			}))
+			defer server.Close()
+
+			d := newTestDeej(t, "SSE_URL: "+server.URL+"/events\n"+tt.timeouts)
+
+			sio, err := NewSseIO(d, zap.NewNop().Sugar())
+			if err != nil {
+				t.Fatalf("create SSE i/o: %v", err)
+			}
+			defer sio.close(zap.NewNop().Sugar())
+
+			if err := sio.connect(zap.NewNop().Sugar()); err != nil {
+				t.Fatalf("connect: %v", err)
+			}
+
+			sio.mu.Lock()
+			connect, idle := sio.es.ConnectionTimeout, sio.es.IdleTimeout
+			sio.mu.Unlock()
+
+			if connect != tt.wantConnect {
+				t.Errorf("eventsource connection timeout: got %v, want %v", connect, tt.wantConnect)
+			}
+			if idle != tt.wantIdle {
+				t.Errorf("eventsource idle timeout: got %v, want %v", idle, tt.wantIdle)
+			}
+			// Start waits this long between reconnect attempts
+			if got := d.config.ConnectionInfo.SSE_RetryInterval; got != tt.wantRetry {
+				t.Errorf("retry interval: got %v, want %v", got, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestSseReadLoopSurvivesPanics(t *testing.T) {
+	tests := []struct {
+		name   string
+		panics int // state events whose handler panics, sent before a slider reading
+	}{
+		{"no panic", 0},
+		{"one bad event", 1},
+		{"several bad events in a row", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprint(w, "event: ping\ndata: {}\n\n")
+				for i := 0; i < tt.panics; i++ {
+					fmt.Fprintf(w, "event: state\ndata: {\"id\":\"sensor-bad\",\"value\":%d}\n\n", i)
+				}
+				fmt.Fprint(w, "event: state\ndata: {\"id\":\"sensor-pot0\",\"value\":42}\n\n")
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			}))
+			defer server.Close()
+
+			d := newTestDeej(t, "SSE_URL: "+server.URL+"/events\n")
+			logs := observeLogs(d)
+			sliderEvents := d.SubscribeToSliderMoveEventsBuffered(8)
+			if err := d.SubscribeToSensor(`^sensor-bad$`, func(id string, value interface{}) {
+				panic("handler bug")
+			}); err != nil {
+				t.Fatalf("SubscribeToSensor: %v", err)
+			}
+
+			sio, err := NewSseIO(d, d.logger)
+			if err != nil {
+				t.Fatalf("create SSE i/o: %v", err)
+			}
+			if err := sio.connect(d.logger); err != nil {
+				t.Fatalf("connect: %v", err)
+			}
+
+			runDone := make(chan error, 1)
+			go func() { runDone <- sio.run(d.logger) }()
+
+			select {
+			case move := <-sliderEvents:
+				if move.SliderID != 0 || math.Abs(float64(move.PercentValue)-0.42) > 1e-6 {
+					t.Errorf("got slider move %+v, want slider 0 at 0.42", move)
+				}
+			case err := <-runDone:
+				t.Fatalf("read loop ended before the slider reading: %v", err)
+			case <-time.After(2 * time.Second):
+				t.Fatal("slider reading never arrived")
+			}
+
+			if got := logs.FilterMessageSnippet("Panic while handling SSE event").Len(); got != tt.panics {
+				t.Errorf("logged %d panics, want %d", got, tt.panics)
+			}
+
+			sio.Stop()
+			select {
+			case <-runDone:
+			case <-time.After(2 * time.Second):
+				t.Fatal("read loop didn't stop")
+			}
+			sio.close(d.logger)
+		})
+	}
+}
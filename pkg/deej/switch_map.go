@@ -9,26 +9,31 @@ import (
 )
 
 type switchMap struct {
-	m    map[int][]string
-	lock sync.Locker
+	m      map[int][]string
+	labels map[int]string
+	lock   sync.Locker
 }
 
 func newSwitchMap() *switchMap {
 	return &switchMap{
-		m:    make(map[int][]string),
-		lock: &sync.Mutex{},
+		m:      make(map[int][]string),
+		labels: make(map[int]string),
+		lock:   &sync.Mutex{},
 	}
 }
 
-func switchMapFromConfigs(userMapping map[string][]string, internalMapping map[string][]string) *switchMap {
+func switchMapFromConfigs(userMapping map[string]interface{}, internalMapping map[string][]string) *switchMap {
 	resultMap := newSwitchMap()
 
-	for switchIdxString, targets := range userMapping {
+	for switchIdxString, value := range userMapping {
 		switchIdx, _ := strconv.Atoi(switchIdxString)
 
-		resultMap.set(switchIdx, funk.FilterString(targets, func(s string) bool {
-			return s != ""
-		}))
+		label, targets := mappingEntryFromConfig(value)
+		resultMap.set(switchIdx, targets)
+
+		if label != "" {
+			resultMap.setLabel(switchIdx, label)
+		}
 	}
 
 	for switchIdxString, targets := range internalMapping {
@@ -74,6 +79,22 @@ func (m *switchMap) set(key int, value []string) {
 	m.m[key] = value
 }
 
+// label returns the user-provided label for a switch, if one was configured
+func (m *switchMap) label(key int) (string, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	value, ok := m.labels[key]
+	return value, ok
+}
+
+func (m *switchMap) setLabel(key int, value string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.labels[key] = value
+}
+
 func (m *switchMap) String() string {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -86,5 +107,9 @@ func (m *switchMap) String() string {
 		targetCount += len(value)
 	}
 
+	if len(m.labels) > 0 {
+		return fmt.Sprintf("<%d switches (%d labeled) mapped to %d targets>", switchCount, len(m.labels), targetCount)
+	}
+
 	return fmt.Sprintf("<%d switches mapped to %d targets>", switchCount, targetCount)
 }
@@ -0,0 +1,86 @@
+package deej
+
+import (
+	"time"
+)
+
+// How long switch states have to stay put before they're saved. The device reports every switch
+// at once when it connects, which shouldn't turn into a write per switch
+const switchStateSaveDelay = time.Second
+
+// restoreSwitchStates applies the switch states saved at the last exit, for restore_switches.
+// Switches the device already reported are left alone, its state wins
+func (d *Deej) restoreSwitchStates() {
+	if !d.config.RestoreSwitches {
+		return
+	}
+
+	saved := d.config.SavedSwitchStates()
+	restored := 0
+
+	for switchIdx, state := range saved {
+		d.stateMutex.Lock()
+		_, reported := d.switchStateByID[switchIdx]
+		if !reported {
+			d.switchStateByID[switchIdx] = state
+		}
+		d.stateMutex.Unlock()
+
+		if reported {
+			continue
+		}
+
+		d.dispatchSwitchEvent(SwitchEvent{
+			SwitchID: switchIdx,
+			State:    state,
+		})
+		restored++
+	}
+
+	d.logger.Infow("Restored saved switch states", "restored", restored, "saved", len(saved))
+}
+
+// scheduleSwitchStateSave saves the switch states once they stopped changing for switchStateSaveDelay
+func (d *Deej) scheduleSwitchStateSave() {
+	if !d.config.RestoreSwitches {
+		return
+	}
+
+	d.switchSaveMutex.Lock()
+	defer d.switchSaveMutex.Unlock()
+
+	if d.switchSaveTimer != nil {
+		d.switchSaveTimer.Reset(switchStateSaveDelay)
+		return
+	}
+
+	d.switchSaveTimer = time.AfterFunc(switchStateSaveDelay, d.saveSwitchStates)
+}
+
+// flushSwitchStateSave saves right away if a save is pending, for shutdown
+func (d *Deej) flushSwitchStateSave() {
+	d.switchSaveMutex.Lock()
+	pending := d.switchSaveTimer != nil && d.switchSaveTimer.Stop()
+	d.switchSaveMutex.Unlock()
+
+	if pending {
+		d.saveSwitchStates()
+	}
+}
+
+// saveSwitchStates writes the current switch states to preferences.yaml
+func (d *Deej) saveSwitchStates() {
+	d.stateMutex.RLock()
+	states := make(map[int]bool, len(d.switchStateByID))
+	for switchIdx, state := range d.switchStateByID {
+		states[switchIdx] = state
+	}
+	d.stateMutex.RUnlock()
+
+	if err := d.config.SaveSwitchStates(states); err != nil {
+		d.logger.Warnw("Failed to save switch states", "error", err)
+		return
+	}
+
+	d.logger.Debugw("Saved switch states", "switches", len(states))
+}
@@ -0,0 +1,67 @@
+package deej
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestRestoreSwitchStates(t *testing.T) {
+	const restore = "restore_switches: true\n"
+
+	tests := []struct {
+		name         string
+		yaml         string
+		reported     []string // frames the device sends before the saved states are restored
+		wantRestored string   // the switch events restoring dispatches
+		wantStates   string
+	}{
+		{"saved states come back", restore, nil, "[0:true 1:false 2:true]", "map[0:true 1:false 2:true]"},
+		{"device state wins", restore, []string{`{"id":"binary_sensor-sw0","value":false}`}, "[1:false 2:true]", "map[0:false 1:false 2:true]"},
+		{"disabled", "", nil, "[]", "map[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the previous run: the device reported its switches, and deej saved them on the way out
+			before := newTestDeej(t, restore)
+			savedDir := internalConfigPath
+			sendStates(before,
+				`{"id":"binary_sensor-sw0","value":true}`,
+				`{"id":"binary_sensor-sw1","value":false}`,
+				`{"id":"binary_sensor-sw2","value":true}`)
+			before.flushSwitchStateSave()
+
+			// this run reads the same preferences.yaml from scratch
+			d := newTestDeej(t, tt.yaml)
+			d.config.internalConfig.SetConfigFile(filepath.Join(savedDir, internalConfigName+"."+configType))
+			if err := d.config.internalConfig.ReadInConfig(); err != nil {
+				t.Fatalf("read saved internal config: %v", err)
+			}
+
+			sendStates(d, tt.reported...)
+			// a reported switch schedules a save, make it before the temporary preferences.yaml goes away
+			defer d.flushSwitchStateSave()
+
+			events := make(chan SwitchEvent, 8)
+			d.switchConsumers = append(d.switchConsumers, events)
+
+			d.restoreSwitchStates()
+			close(events)
+
+			restored := []string{}
+			for event := range events {
+				restored = append(restored, fmt.Sprintf("%d:%v", event.SwitchID, event.State))
+			}
+			sort.Strings(restored)
+			if got := fmt.Sprint(restored); got != tt.wantRestored {
+				t.Errorf("restored %s, want %s", got, tt.wantRestored)
+			}
+
+			if got := fmt.Sprint(d.switchStateByID); got != tt.wantStates {
+				t.Errorf("switch states: got %s, want %s", got, tt.wantStates)
+			}
+		})
+	}
+}
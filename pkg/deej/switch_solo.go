@@ -0,0 +1,156 @@
+package deej
+
+// handleSoloSwitch mutes every app session except the switch's targets when a solo switch turns on,
+// and unmutes the sessions it muted once it turns off. Sessions that were already muted stay muted
+func (m *sessionMap) handleSoloSwitch(switchID int, targets []string, on bool) {
+	if on {
+		m.engageSolo(switchID, targets)
+	} else {
+		m.disengageSolo(switchID)
+	}
+}
+
+// engageSolo mutes everything but the targets, remembering which sessions it muted by key,
+// so an app that restarts while solo is on is still restored afterwards
+func (m *sessionMap) engageSolo(switchID int, targets []string) {
+	m.soloLock.Lock()
+	if _, engaged := m.soloMuted[switchID]; engaged {
+		m.soloLock.Unlock()
+		return
+	}
+	m.soloMuted[switchID] = make(map[string]bool)
+	m.soloLock.Unlock()
+
+	mutedCount := 0
+	actionFailed := false
+
+	for _, session := range m.allSessions() {
+		if soloExempt(session) || m.sessionMatchesTargets(session, targets) {
+			continue
+		}
+
+		// muted by hand or by another switch - not ours to restore, unless another solo switch did it
+		if session.GetMute() && !m.soloMutesSession(session) {
+			continue
+		}
+
+		if !session.GetMute() {
+			if err := session.SetMute(true, false); err != nil {
+				m.logger.Warnw("Failed to mute session for solo switch", "switch", switchID, "session", session.Key(), "error", err)
+				actionFailed = true
+				continue
+			}
+		}
+
+		m.soloLock.Lock()
+		m.soloMuted[switchID][session.Key()] = true
+		m.soloLock.Unlock()
+		mutedCount++
+	}
+
+	m.logger.Infow("Solo switch engaged", "switch", switchID, "muted", mutedCount)
+
+	if actionFailed {
+		m.refreshSessionsAfterFailure()
+	}
+}
+
+// disengageSolo unmutes the sessions a solo switch muted, unless something else still wants them muted
+func (m *sessionMap) disengageSolo(switchID int) {
+	m.soloLock.Lock()
+	mutedKeys, engaged := m.soloMuted[switchID]
+	delete(m.soloMuted, switchID)
+	m.soloLock.Unlock()
+
+	if !engaged {
+		return
+	}
+
+	actionFailed := false
+
+	for _, session := range m.allSessions() {
+		if !mutedKeys[session.Key()] || !session.GetMute() {
+			continue
+		}
+
+		if session.GetSwitchMuteCount() > 0 || m.pttMutesSession(session) || m.soloMutesSession(session) {
+			continue
+		}
+
+		if err := session.SetMute(false, false); err != nil {
+			m.logger.Warnw("Failed to restore session after solo switch", "switch", switchID, "session", session.Key(), "error", err)
+			actionFailed = true
+		}
+	}
+
+	m.logger.Infow("Solo switch disengaged", "switch", switchID, "restored", len(mutedKeys))
+
+	if actionFailed {
+		m.refreshSessionsAfterFailure()
+	}
+}
+
+// soloMutesSession reports whether an engaged solo switch muted this session
+func (m *sessionMap) soloMutesSession(session Session) bool {
+	m.soloLock.Lock()
+	defer m.soloLock.Unlock()
+
+	for switchID, mutedKeys := range m.soloMuted {
+		if m.deej.config.SwitchSolo[switchID] && mutedKeys[session.Key()] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// soloMuteNewSession reports whether a session that just showed up should be muted by an engaged
+// solo switch, and records it so it's restored along with the rest when the switch turns off
+func (m *sessionMap) soloMuteNewSession(session Session) bool {
+	if soloExempt(session) {
+		return false
+	}
+
+	m.soloLock.Lock()
+	engaged := make([]int, 0, len(m.soloMuted))
+	for switchID := range m.soloMuted {
+		if m.deej.config.SwitchSolo[switchID] {
+			engaged = append(engaged, switchID)
+		}
+	}
+	m.soloLock.Unlock()
+
+	for _, switchID := range engaged {
+		targets, ok := m.deej.config.SwitchesMapping.get(switchID)
+		if ok && m.sessionMatchesTargets(session, targets) {
+			continue
+		}
+
+		m.soloLock.Lock()
+		if mutedKeys, ok := m.soloMuted[switchID]; ok {
+			mutedKeys[session.Key()] = true
+		}
+		m.soloLock.Unlock()
+
+		return true
+	}
+
+	return false
+}
+
+// allSessions returns a snapshot of the session map, for work that can't run under its lock
+func (m *sessionMap) allSessions() []Session {
+	var sessions []Session
+	m.iterateAllSessions(func(session Session) {
+		sessions = append(sessions, session)
+	})
+
+	return sessions
+}
+
+// soloExempt reports whether solo leaves a session alone: device-level sessions (master, mic,
+// system, devices by name) would silence the solo target along with everything else
+func soloExempt(session Session) bool {
+	key := session.Key()
+	return isDeviceSessionKey(key) || deviceSessionKeyPattern.MatchString(key)
+}
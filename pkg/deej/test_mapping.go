@@ -0,0 +1,138 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TestMapping loads the config and the audio sessions, applies a single simulated slider or switch
+// event (as given to --test-mapping, e.g. "slider:2=0.5" or "switch:1=on") through the normal mapping
+// code, and writes which sessions it targeted along with their resulting volume and mute state to out.
+// The event really changes those sessions, just like the device would
+func (d *Deej) TestMapping(spec string, out io.Writer) error {
+	if err := d.config.Load(); err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	if err := d.sessions.getAndAddSessions(); err != nil {
+		return fmt.Errorf("get sessions: %w", err)
+	}
+	defer func() {
+		if err := d.sessions.release(); err != nil {
+			d.logger.Warnw("Failed to release session map after mapping test", "error", err)
+		}
+	}()
+
+	event, err := parseTestEvent(spec)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := d.sessions.applyTestEvent(event)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "%s\n", spec)
+	if len(sessions) == 0 {
+		fmt.Fprintln(out, "  no sessions matched its targets")
+		return nil
+	}
+
+	for _, session := range sessions {
+		fmt.Fprintf(out, "  %-40s volume %3.0f%%  muted %t\n", session.Key(), session.GetVolume()*100, session.GetMute())
+	}
+
+	return nil
+}
+
+// parseTestEvent parses a --test-mapping spec into a SliderMoveEvent ("slider:<id>=<0-1>")
+// or a SwitchEvent ("switch:<id>=on|off")
+func parseTestEvent(spec string) (interface{}, error) {
+	kind, rest, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid test event %q, expected slider:<id>=<value> or switch:<id>=<on|off>", spec)
+	}
+
+	idString, value, ok := strings.Cut(rest, "=")
+	if !ok {
+		return nil, fmt.Errorf("invalid test event %q, missing =<value>", spec)
+	}
+
+	id, err := strconv.Atoi(strings.TrimSpace(idString))
+	if err != nil {
+		return nil, fmt.Errorf("invalid id in test event %q: %w", spec, err)
+	}
+
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	switch strings.ToLower(kind) {
+	case "slider":
+		volume, err := strconv.ParseFloat(value, 32)
+		if err != nil || volume < 0 || volume > 1 {
+			return nil, fmt.Errorf("invalid slider value in test event %q, expected 0 to 1", spec)
+		}
+		return SliderMoveEvent{SliderID: id, PercentValue: float32(volume)}, nil
+
+	case "switch":
+		var state bool
+		switch value {
+		case "on", "true", "1":
+			state = true
+		case "off", "false", "0":
+			state = false
+		default:
+			return nil, fmt.Errorf("invalid switch value in test event %q, expected on or off", spec)
+		}
+		return SwitchEvent{SwitchID: id, State: state}, nil
+	}
+
+	return nil, fmt.Errorf("invalid test event %q, expected slider or switch", spec)
+}
+
+// applyTestEvent runs one event through the slider or switch handler and returns the sessions its
+// targets matched, sorted by key
+func (m *sessionMap) applyTestEvent(event interface{}) ([]Session, error) {
+	var targets []string
+	var ok bool
+
+	switch e := event.(type) {
+	case SliderMoveEvent:
+		if targets, ok = m.deej.config.SliderMapping.get(e.SliderID); !ok {
+			return nil, fmt.Errorf("slider %d isn't mapped", e.SliderID)
+		}
+		m.handleSliderMoveEvent(e)
+
+	case SwitchEvent:
+		if targets, ok = m.switchTargets(e.SwitchID); !ok {
+			return nil, fmt.Errorf("switch %d isn't mapped", e.SwitchID)
+		}
+
+		// mute counts are worked out from the stored switch states, like for a real switch
+		m.deej.stateMutex.Lock()
+		m.deej.switchStateByID[e.SwitchID] = e.State
+		m.deej.stateMutex.Unlock()
+
+		m.handleSwitchEvent(e)
+
+	default:
+		return nil, errors.New("unknown test event")
+	}
+
+	var matched []Session
+	for _, session := range m.allSessions() {
+		if m.sessionMatchesTargets(session, targets) {
+			matched = append(matched, session)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].Key() < matched[j].Key()
+	})
+
+	return matched, nil
+}
@@ -0,0 +1,92 @@
+package deej
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseTestEvent(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    interface{}
+		wantErr bool
+	}{
+		{"slider:2=0.5", SliderMoveEvent{SliderID: 2, PercentValue: 0.5}, false},
+		{" Slider: 0 = 1 ", SliderMoveEvent{SliderID: 0, PercentValue: 1}, false},
+		{"switch:1=on", SwitchEvent{SwitchID: 1, State: true}, false},
+		{"switch:3=OFF", SwitchEvent{SwitchID: 3, State: false}, false},
+		{"switch:0=1", SwitchEvent{SwitchID: 0, State: true}, false},
+		{"slider:2=1.5", nil, true},
+		{"slider:2=loud", nil, true},
+		{"switch:1=maybe", nil, true},
+		{"slider:x=0.5", nil, true},
+		{"slider:2", nil, true},
+		{"knob:1=0.5", nil, true},
+		{"slider2=0.5", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTestEvent(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTestEvent(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseTestEvent(%q) = %#v, want %#v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestApplyTestEvent(t *testing.T) {
+	const yaml = `slider_mapping:
+  0: spotify.exe
+  1:
+    - chrome.exe
+    - firefox.exe
+switches_mapping:
+  0: chrome.exe
+`
+
+	// sessions prints what --test-mapping reports for each affected session
+	sessions := func(matched []Session) string {
+		report := []string{}
+		for _, session := range matched {
+			report = append(report, fmt.Sprintf("%s:%.2f:%t", session.Key(), session.GetVolume(), session.GetMute()))
+		}
+		return fmt.Sprint(report)
+	}
+
+	tests := []struct {
+		name    string
+		event   interface{}
+		want    string
+		wantErr bool
+	}{
+		{"slider with one target", SliderMoveEvent{SliderID: 0, PercentValue: 0.25}, "[spotify.exe:0.25:false]", false},
+		{"slider with several targets, sorted", SliderMoveEvent{SliderID: 1, PercentValue: 0.5}, "[chrome.exe:0.50:false firefox.exe:0.50:false]", false},
+		{"switch mutes", SwitchEvent{SwitchID: 0, State: true}, "[chrome.exe:1.00:true]", false},
+		{"unmapped slider", SliderMoveEvent{SliderID: 5, PercentValue: 0.5}, "[]", true},
+		{"unmapped switch", SwitchEvent{SwitchID: 5, State: true}, "[]", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := &fakeFilteringFinder{processes: []string{"spotify.exe", "chrome.exe", "firefox.exe", "discord.exe"}}
+			m, _ := newTestSessionMapWithConfig(t, finder, yaml)
+			m.refreshSessions(true)
+
+			matched, err := m.applyTestEvent(tt.event)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyTestEvent error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := sessions(matched); got != tt.want {
+				t.Errorf("affected sessions: got %s, want %s", got, tt.want)
+			}
+
+			// sessions the event didn't target are left alone
+			if discord, _ := m.get("discord.exe"); discord[0].GetVolume() != 1 || discord[0].GetMute() {
+				t.Errorf("untargeted session changed: %s", sessions(discord))
+			}
+		})
+	}
+}
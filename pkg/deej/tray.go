@@ -19,7 +19,7 @@ func (d *Deej) initializeTray(onDone func()) {
 		systray.SetTitle("Deej ESP32")
 		systray.SetTooltip("Deej ESP32")
 
-		editConfig := systray.AddMenuItem("Edit configuration", "Open config file with notepad")
+		editConfig := systray.AddMenuItem("Edit configuration", "Open config file in a text editor")
 		editConfig.SetIcon(icon.EditConfig)
 
 		refreshSessions := systray.AddMenuItem("Re-scan audio sessions", "Manually refresh audio sessions if something's stuck")
@@ -27,11 +27,16 @@ func (d *Deej) initializeTray(onDone func()) {
 
 		// Only enable stack trace dump in verbose/debug mode
 		var dumpStack *systray.MenuItem
-		if d.verbose {
+		if d.Verbose() {
 			dumpStack = systray.AddMenuItem("Dump stack trace", "Output all goroutines stack trace to log (for debugging deadlocks)")
 			dumpStack.SetIcon(icon.RefreshSessions) // Reuse icon, or we can add a new one later
 		}
 
+		verboseLogging := systray.AddMenuItem("Verbose logging", "Log everything, including debug messages (until deej restarts)")
+		if d.Verbose() {
+			verboseLogging.Check()
+		}
+
 		if d.version != "" {
 			systray.AddSeparator()
 			versionInfo := systray.AddMenuItem(d.version, "")
@@ -56,22 +61,25 @@ func (d *Deej) initializeTray(onDone func()) {
 				case <-editConfig.ClickedCh:
 					logger.Info("Edit config menu item clicked, opening config for editing")
 
-					editor := "notepad.exe"
-					if util.Linux() {
-						// Try $EDITOR first, then fallback to common editors
-						if editorEnv := os.Getenv("EDITOR"); editorEnv != "" {
-							editor = editorEnv
-						} else {
-							// Try common Linux editors in order of preference
-							editor = "xdg-open"
-							// xdg-open will open with default text editor
-						}
-					}
+					editor := resolveEditor(d.config.Editor, util.Linux(), os.Getenv)
 
-					if err := util.OpenExternal(logger, editor, userConfigFilepath); err != nil {
+					if err := util.OpenExternal(logger, editor, d.config.UserConfigFile()); err != nil {
 						logger.Warnw("Failed to open config file for editing", "error", err)
 					}
 
+				// toggle verbose logging
+				case <-verboseLogging.ClickedCh:
+					verbose := !d.Verbose()
+					d.SetVerbose(verbose)
+
+					if verbose {
+						verboseLogging.Check()
+					} else {
+						verboseLogging.Uncheck()
+					}
+
+					logger.Infow("Verbose logging menu item clicked", "verbose", verbose)
+
 				// refresh sessions
 				case <-refreshSessions.ClickedCh:
 					logger.Info("Refresh sessions menu item clicked, triggering session map refresh")
@@ -84,7 +92,7 @@ func (d *Deej) initializeTray(onDone func()) {
 		}()
 
 		// dump stack trace handler (only in verbose/debug mode)
-		if d.verbose && dumpStack != nil {
+		if dumpStack != nil {
 			go func() {
 				for {
 					<-dumpStack.ClickedCh
@@ -111,3 +119,22 @@ func (d *Deej) stopTray() {
 	d.logger.Debug("Quitting tray")
 	systray.Quit()
 }
+
+// resolveEditor picks the command used to open the config file: the configured editor if set,
+// otherwise notepad on Windows and $EDITOR (falling back to xdg-open) on Linux
+func resolveEditor(configured string, linux bool, getenv func(string) string) string {
+	if configured != "" {
+		return configured
+	}
+
+	if !linux {
+		return "notepad.exe"
+	}
+
+	if editorEnv := getenv("EDITOR"); editorEnv != "" {
+		return editorEnv
+	}
+
+	// xdg-open will open with default text editor
+	return "xdg-open"
+}
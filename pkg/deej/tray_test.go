@@ -0,0 +1,35 @@
+package deej
+
+import "testing"
+
+func TestResolveEditor(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		linux      bool
+		envEditor  string
+		want       string
+	}{
+		{"windows default", "", false, "", "notepad.exe"},
+		{"windows ignores $EDITOR", "", false, "vim", "notepad.exe"},
+		{"windows configured", `C:\Program Files\Notepad++\notepad++.exe`, false, "", `C:\Program Files\Notepad++\notepad++.exe`},
+		{"linux default", "", true, "", "xdg-open"},
+		{"linux $EDITOR", "", true, "vim", "vim"},
+		{"linux configured beats $EDITOR", "code", true, "vim", "code"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string {
+				if key == "EDITOR" {
+					return tt.envEditor
+				}
+				return ""
+			}
+
+			if got := resolveEditor(tt.configured, tt.linux, getenv); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
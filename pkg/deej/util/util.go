@@ -12,6 +12,7 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/mitchellh/go-ps"
 	"go.uber.org/zap"
 )
 
@@ -62,6 +63,11 @@ func GetCurrentWindowProcessNames() ([]string, error) {
 	return getCurrentWindowProcessNames()
 }
 
+// GetForegroundWindowTitle returns the title of the window that currently has focus
+func GetForegroundWindowTitle() (string, error) {
+	return getForegroundWindowTitle()
+}
+
 // OpenExternal spawns a detached window with the provided command and argument
 func OpenExternal(logger *zap.SugaredLogger, cmd string, arg string) error {
 
@@ -91,6 +97,26 @@ func NormalizeScalar(v float32) float32 {
 	return float32(math.Floor(float64(v)*100) / 100.0)
 }
 
+// Expo applies an RC transmitter style expo curve to a 0-1 value: the distance from the center is blended
+// between linear and cubic by expo (0 is linear, 1 fully cubic). Movement near the center changes
+// the result less and movement near the ends more, while 0, 0.5 and 1 stay where they are
+func Expo(v float64, expo float64) float64 {
+	x := 2*v - 1
+	y := (1-expo)*x + expo*x*x*x
+
+	return (y + 1) / 2
+}
+
+// Wrap brings a 0-1 value that went out of range back into it from the other end (1.25 -> 0.25, -0.25 -> 0.75).
+// Values already in range, including 1, are returned unchanged
+func Wrap(v float64) float64 {
+	if v >= 0 && v <= 1 {
+		return v
+	}
+
+	return v - math.Floor(v)
+}
+
 // a helper to make sure volume snaps correctly to 0 and 100, where appropriate
 func almostEquals(a float32, b float32) bool {
 	return math.Abs(float64(a-b)) < 0.000001
@@ -149,12 +175,16 @@ func NormalizePath(path string) (string, error) {
 	return normalized, nil
 }
 
-// checks if a process path matches a target dir
+// checks if a process path matches a target dir, or a target glob pattern if it has wildcards
 func PathMatches(processPath string, targetPath string) bool {
 	if processPath == "" || targetPath == "" {
 		return false
 	}
 
+	if HasGlob(targetPath) {
+		return PathGlobMatches(processPath, targetPath)
+	}
+
 	normalizedProcessPath, err := NormalizePath(processPath)
 	if err != nil {
 		return false
@@ -171,3 +201,134 @@ func PathMatches(processPath string, targetPath string) bool {
 
 	return strings.HasPrefix(normalizedProcessPath, normalizedTargetPath)
 }
+
+// HasGlob reports whether a target path contains glob wildcards (*, ? or [)
+func HasGlob(targetPath string) bool {
+	return strings.ContainsAny(targetPath, "*?[")
+}
+
+// PathGlobMatches checks if a process path matches a glob pattern such as C:\Games\*\bin\*.exe.
+// Like a plain target dir, a pattern matching one of the process' parent directories matches too.
+// Wildcards don't cross path separators
+func PathGlobMatches(processPath string, pattern string) bool {
+	if processPath == "" || pattern == "" {
+		return false
+	}
+
+	normalizedProcessPath, err := NormalizePath(processPath)
+	if err != nil {
+		return false
+	}
+
+	normalizedPattern, err := NormalizePath(pattern)
+	if err != nil {
+		return false
+	}
+
+	for candidate := normalizedProcessPath; ; {
+		matched, err := filepath.Match(normalizedPattern, candidate)
+		if err != nil {
+			// malformed pattern, it'll never match anything
+			return false
+		}
+		if matched {
+			return true
+		}
+
+		parent := filepath.Dir(candidate)
+		if parent == candidate {
+			return false
+		}
+		candidate = parent
+	}
+}
+
+// GetProcessTreeNames returns the lowercase executable names of every process named rootName
+// and all of their descendants, found by walking parent PIDs
+func GetProcessTreeNames(rootName string) ([]string, error) {
+	processes, err := ps.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("list processes: %w", err)
+	}
+
+	return ProcessTreeNames(processes, rootName), nil
+}
+
+// GetForegroundProcessTreeNames returns the lowercase executable names of the foreground window's
+// processes (as in GetCurrentWindowProcessNames) and all of their descendants, which catches helper
+// processes that hold the audio session on the window's behalf. This is currently only implemented for Windows
+func GetForegroundProcessTreeNames() ([]string, error) {
+	rootPIDs, err := getForegroundWindowPIDs()
+	if err != nil {
+		return nil, fmt.Errorf("get foreground window processes: %w", err)
+	}
+
+	if len(rootPIDs) == 0 {
+		return nil, nil
+	}
+
+	processes, err := ps.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("list processes: %w", err)
+	}
+
+	return ProcessTreeNamesByPID(processes, rootPIDs), nil
+}
+
+// ProcessTreeNames is the lookup behind GetProcessTreeNames, operating on an already taken process list
+func ProcessTreeNames(processes []ps.Process, rootName string) []string {
+	rootName = strings.ToLower(rootName)
+
+	return processTreeNames(processes, func(process ps.Process) bool {
+		return strings.ToLower(process.Executable()) == rootName
+	})
+}
+
+// ProcessTreeNamesByPID is the lookup behind GetForegroundProcessTreeNames: like ProcessTreeNames,
+// but the tree starts at the given PIDs
+func ProcessTreeNamesByPID(processes []ps.Process, rootPIDs []int) []string {
+	roots := make(map[int]bool, len(rootPIDs))
+	for _, pid := range rootPIDs {
+		roots[pid] = true
+	}
+
+	return processTreeNames(processes, func(process ps.Process) bool {
+		return roots[process.Pid()]
+	})
+}
+
+func processTreeNames(processes []ps.Process, isRoot func(ps.Process) bool) []string {
+	children := make(map[int][]ps.Process)
+	var queue []ps.Process
+	for _, process := range processes {
+		children[process.PPid()] = append(children[process.PPid()], process)
+		if isRoot(process) {
+			queue = append(queue, process)
+		}
+	}
+
+	seenPIDs := make(map[int]bool)
+	seenNames := make(map[string]bool)
+	names := []string{}
+
+	// breadth-first over the tree; seenPIDs also protects us from PID reuse creating cycles
+	for len(queue) > 0 {
+		process := queue[0]
+		queue = queue[1:]
+
+		if seenPIDs[process.Pid()] {
+			continue
+		}
+		seenPIDs[process.Pid()] = true
+
+		name := strings.ToLower(process.Executable())
+		if !seenNames[name] {
+			seenNames[name] = true
+			names = append(names, name)
+		}
+
+		queue = append(queue, children[process.Pid()]...)
+	}
+
+	return names
+}
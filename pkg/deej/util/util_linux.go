@@ -4,13 +4,28 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 )
 
 func getCurrentWindowProcessNames() ([]string, error) {
 	return nil, errors.New("Not implemented")
 }
 
+func getForegroundWindowPIDs() ([]int, error) {
+	return nil, errors.New("Not implemented")
+}
+
+func getForegroundWindowTitle() (string, error) {
+	output, err := exec.Command("xdotool", "getactivewindow", "getwindowname").Output()
+	if err != nil {
+		return "", fmt.Errorf("get active window name: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
 // GetProcessPath returns the full path to the executable for the given process ID
 // On Linux, this reads /proc/PID/exe which is a symlink to the executable
 func GetProcessPath(pid int) (string, error) {
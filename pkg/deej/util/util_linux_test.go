@@ -0,0 +1,30 @@
+//go:build linux
+// +build linux
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetProcessPath(t *testing.T) {
+	want, err := os.Executable()
+	if err != nil {
+		t.Fatalf("os.Executable: %v", err)
+	}
+
+	got, err := GetProcessPath(os.Getpid())
+	if err != nil {
+		t.Fatalf("GetProcessPath: %v", err)
+	}
+
+	if !filepath.IsAbs(got) || filepath.Clean(got) != filepath.Clean(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if path, err := GetProcessPath(-1); err == nil {
+		t.Errorf("pid -1: got %q, want an error", path)
+	}
+}
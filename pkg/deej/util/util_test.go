@@ -0,0 +1,140 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/mitchellh/go-ps"
+)
+
+// fakeProcess is a ps.Process for building process trees by hand
+type fakeProcess struct {
+	pid, ppid int
+	name      string
+}
+
+func (p fakeProcess) Pid() int           { return p.pid }
+func (p fakeProcess) PPid() int          { return p.ppid }
+func (p fakeProcess) Executable() string { return p.name }
+
+// testProcessTree is a browser with helpers (one of them nested), plus unrelated processes
+var testProcessTree = []ps.Process{
+	fakeProcess{1, 0, "explorer.exe"},
+	fakeProcess{100, 1, "Chrome.exe"},
+	fakeProcess{101, 100, "chrome.exe"},
+	fakeProcess{102, 100, "chrome_crashpad_handler.exe"},
+	fakeProcess{103, 101, "audiodg.exe"},
+	fakeProcess{200, 1, "discord.exe"},
+	fakeProcess{300, 1, "game.exe"},
+	fakeProcess{301, 301, "game.exe"}, // its own parent after PID reuse
+}
+
+func TestProcessTreeNames(t *testing.T) {
+	tests := []struct {
+		root string
+		want string
+	}{
+		{"chrome.exe", "[chrome.exe chrome_crashpad_handler.exe audiodg.exe]"},
+		{"CHROME.EXE", "[chrome.exe chrome_crashpad_handler.exe audiodg.exe]"},
+		{"discord.exe", "[discord.exe]"},
+		{"game.exe", "[game.exe]"},
+		{"spotify.exe", "[]"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprint(ProcessTreeNames(testProcessTree, tt.root)); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.root, got, tt.want)
+		}
+	}
+}
+
+func TestProcessTreeNamesByPID(t *testing.T) {
+	tests := []struct {
+		name string
+		pids []int
+		want string
+	}{
+		{"window owned by a helper", []int{101}, "[chrome.exe audiodg.exe]"},
+		{"window owned by the main process", []int{100}, "[chrome.exe chrome_crashpad_handler.exe audiodg.exe]"},
+		{"several window processes", []int{102, 200}, "[chrome_crashpad_handler.exe discord.exe]"},
+		{"own parent", []int{301}, "[game.exe]"},
+		{"gone", []int{999}, "[]"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprint(ProcessTreeNamesByPID(testProcessTree, tt.pids)); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExpo(t *testing.T) {
+	tests := []struct {
+		v, expo float64
+		want    float64
+	}{
+		{0.75, 0, 0.75},
+		{0.3, 0, 0.3},
+		{0.75, 1, 0.5625},
+		{0.25, 1, 0.4375},
+		{0.75, 0.5, 0.65625},
+		{0, 0.7, 0},
+		{0.5, 0.7, 0.5},
+		{1, 0.7, 1},
+	}
+
+	for _, tt := range tests {
+		if got := Expo(tt.v, tt.expo); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Expo(%v, %v) = %v, want %v", tt.v, tt.expo, got, tt.want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		v, want float64
+	}{
+		{0, 0},
+		{0.4, 0.4},
+		{1, 1},
+		{1.25, 0.25},
+		{2.5, 0.5},
+		{-0.25, 0.75},
+		{-1.5, 0.5},
+	}
+
+	for _, tt := range tests {
+		if got := Wrap(tt.v); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Wrap(%v) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestPathMatches(t *testing.T) {
+	tests := []struct {
+		process string
+		target  string
+		want    bool
+	}{
+		{"/games/doom/bin/doom.exe", "/games/*/bin/*.exe", true},
+		{"/games/quake/bin/quake.exe", "/games/*/bin/*.exe", true},
+		{"/games/doom/doom.exe", "/games/*/bin/*.exe", false},
+		{"/games/doom/bin/x64/doom.exe", "/games/*/bin/*.exe", false},
+		{"/games/doom/bin/doom.sh", "/games/*/bin/*.exe", false},
+		{"/games/doom/bin/x64/doom.exe", "/games/*/bin", true},
+		{"/games/doom/bin/doom.exe", "/games/d??m/bin/doom.exe", true},
+		{"/games/dooom/bin/doom.exe", "/games/d??m/bin/doom.exe", false},
+		{"/games/doom2/doom.exe", "/games/doom[0-9]/doom.exe", true},
+		{"/games/doom/bin/doom.exe", "/games/[/doom.exe", false},
+		{"/games/doom/bin/doom.exe", "/games/doom", true},
+		{"/games/doom/bin/doom.exe", "/apps", false},
+		{"", "/games/*", false},
+	}
+
+	for _, tt := range tests {
+		if got := PathMatches(tt.process, tt.target); got != tt.want {
+			t.Errorf("PathMatches(%q, %q) = %v, want %v", tt.process, tt.target, got, tt.want)
+		}
+	}
+}
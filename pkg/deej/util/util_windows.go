@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"slices"
 	"sync"
 	"syscall"
 	"time"
@@ -14,6 +15,10 @@ import (
 var (
 	modkernel32                    = syscall.NewLazyDLL("kernel32.dll")
 	procQueryFullProcessImageNameW = modkernel32.NewProc("QueryFullProcessImageNameW")
+
+	moduser32                = syscall.NewLazyDLL("user32.dll")
+	procGetWindowTextLengthW = moduser32.NewProc("GetWindowTextLengthW")
+	procGetWindowTextW       = moduser32.NewProc("GetWindowTextW")
 )
 
 const (
@@ -164,6 +169,61 @@ func getCurrentWindowProcessNames() ([]string, error) {
 	return result, nil
 }
 
+// enumChildWindowPIDsCallback collects the PIDs of a window's children into the *[]int passed as lParam.
+// It's created once since every syscall.NewCallback takes up one of a limited number of slots
+var enumChildWindowPIDsCallback = syscall.NewCallback(func(childHWND *uintptr, lParam *uintptr) uintptr {
+	pids := (*[]int)(unsafe.Pointer(lParam))
+
+	var childPID uint32
+	win.GetWindowThreadProcessId((win.HWND)(unsafe.Pointer(childHWND)), &childPID)
+
+	if childPID != 0 && !slices.Contains(*pids, int(childPID)) {
+		*pids = append(*pids, int(childPID))
+	}
+
+	// keep iterating
+	return 1
+})
+
+// getForegroundWindowPIDs returns the PID of the foreground window's process, followed by those of its
+// child windows. Like in getCurrentWindowProcessNames, the children matter for container processes
+// (such as ApplicationFrameHost.exe) that host other processes' windows
+func getForegroundWindowPIDs() ([]int, error) {
+	hwnd := win.GetForegroundWindow()
+	if hwnd == 0 {
+		return nil, nil
+	}
+
+	var ownerPID uint32
+	win.GetWindowThreadProcessId(hwnd, &ownerPID)
+
+	// check for system PID (0)
+	if ownerPID == 0 {
+		return nil, nil
+	}
+
+	pids := []int{int(ownerPID)}
+	win.EnumChildWindows(hwnd, enumChildWindowPIDsCallback, (uintptr)(unsafe.Pointer(&pids)))
+
+	return pids, nil
+}
+
+func getForegroundWindowTitle() (string, error) {
+	hwnd := win.GetForegroundWindow()
+	if hwnd == 0 {
+		return "", nil
+	}
+
+	length, _, _ := procGetWindowTextLengthW.Call(uintptr(hwnd))
+	if length == 0 {
+		return "", nil
+	}
+
+	buf := make([]uint16, length+1)
+	procGetWindowTextW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return syscall.UTF16ToString(buf), nil
+}
+
 // IsAccessDeniedError returns true if the error is a Windows ERROR_ACCESS_DENIED (code 5).
 // This typically means a process is protected by anti-cheat software or elevated privileges.
 func IsAccessDeniedError(err error) bool {
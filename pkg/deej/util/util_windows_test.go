@@ -0,0 +1,32 @@
+//go:build windows
+// +build windows
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetProcessPath(t *testing.T) {
+	want, err := os.Executable()
+	if err != nil {
+		t.Fatalf("os.Executable: %v", err)
+	}
+
+	got, err := GetProcessPath(os.Getpid())
+	if err != nil {
+		t.Fatalf("GetProcessPath: %v", err)
+	}
+
+	if !filepath.IsAbs(got) || !strings.EqualFold(filepath.Clean(got), filepath.Clean(want)) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// pid 0 is the idle process, it can't be opened
+	if path, err := GetProcessPath(0); err == nil {
+		t.Errorf("pid 0: got %q, want an error", path)
+	}
+}